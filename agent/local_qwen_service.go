@@ -211,8 +211,8 @@ func (s *LocalQwenService) ReviewContent(ctx context.Context, content string) (b
 	}
 	
 	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		// 如果无法解析 JSON，则默认通过
-		return true, "无法解析审核结果，默认通过", nil
+		// 无法解析审核结果时拒绝通过，不能放行未经审核的内容
+		return false, "无法解析审核结果，拒绝通过", fmt.Errorf("解析审核结果失败: %w", err)
 	}
 	
 	return result.Approved, result.Reason, nil
@@ -12,11 +12,17 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
+
+	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/media"
+	"github.com/richard9219/3kstory/internal/storage"
+	"github.com/richard9219/3kstory/internal/subtitle"
 )
 
 type generateRequest struct {
@@ -26,55 +32,87 @@ type generateRequest struct {
 	AspectRatio string `json:"aspect_ratio"`
 	SceneID     uint   `json:"scene_id"`
 	ProjectID   uint   `json:"project_id"`
+	// Motion is a Ken Burns preset for image scenes; when empty it is chosen
+	// from ShotType
+	Motion   string `json:"motion"`
+	ShotType string `json:"shot_type"`
+	// Dialogue is shown as subtitles, timed across the clip; speakers are the
+	// scene's characters. Subtitles is burn (default), soft or none.
+	Dialogue  string   `json:"dialogue"`
+	Speakers  []string `json:"speakers"`
+	Subtitles string   `json:"subtitles"`
 }
 
 type generateResponse struct {
-	VideoID  string `json:"video_id"`
-	Status   string `json:"status"`
-	VideoURL string `json:"video_url"`
-	Message  string `json:"message,omitempty"`
-}
-
-type videoJob struct {
-	ID       string
-	Status   string
-	VideoURL string
-	Error    string
+	VideoID  string  `json:"video_id"`
+	Status   string  `json:"status"`
+	VideoURL string  `json:"video_url"`
+	Progress float64 `json:"progress,omitempty"`
+	Queued   bool    `json:"queued,omitempty"`
+	Message  string  `json:"message,omitempty"`
 }
 
 type server struct {
-	publicURL string
-	outputDir string
-
-	mu   sync.RWMutex
-	jobs map[string]*videoJob
+	store    storage.Store
+	jobs     *jobManager
+	font     string
+	fontsDir string
 }
 
 func main() {
 	port := getenv("LOCAL_VIDEO_PORT", "8003")
 	addr := ":" + port
-	public := getenv("LOCAL_VIDEO_PUBLIC_BASE", "http://localhost:"+port)
-	outputDir := getenv("LOCAL_VIDEO_OUTPUT_DIR", filepath.Join(".local", "videos"))
 
 	if _, err := exec.LookPath("ffmpeg"); err != nil {
 		log.Fatalf("ffmpeg not found. Install it first (macOS): brew install ffmpeg")
 	}
-	if err := os.MkdirAll(outputDir, 0o755); err != nil {
-		log.Fatalf("failed to create output dir: %v", err)
+
+	// Clips go to the same media storage as the API server (STORAGE_DRIVER,
+	// MEDIA_ROOT, MEDIA_BASE_URL, OSS_*), which then adopts them as assets
+	cfg := config.Load()
+	store, err := storage.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("invalid storage configuration: %v", err)
+	}
+
+	s := &server{store: store, font: cfg.Render.SubtitleFont, fontsDir: cfg.Render.SubtitleFontsDir}
+	s.jobs, err = newJobManager(jobManagerConfig{
+		JournalPath:   getenv("LOCAL_VIDEO_JOURNAL", filepath.Join(".local", "video-jobs.json")),
+		QueueSize:     getenvInt("LOCAL_VIDEO_QUEUE_SIZE", 100),
+		RenderTimeout: time.Duration(getenvInt("LOCAL_VIDEO_RENDER_TIMEOUT", 600)) * time.Second,
+		TTL:           time.Duration(getenvInt("LOCAL_VIDEO_JOB_TTL", 86400)) * time.Second,
+	}, s.render)
+	if err != nil {
+		log.Fatalf("failed to load jobs: %v", err)
 	}
 
-	s := &server{publicURL: strings.TrimRight(public, "/"), outputDir: outputDir, jobs: map[string]*videoJob{}}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	workers := getenvInt("LOCAL_VIDEO_WORKERS", 2)
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Run(ctx, workers)
+		close(done)
+	}()
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
 	mux.HandleFunc("/v1/generate", s.handleGenerate)
 	mux.HandleFunc("/v1/generate/", s.handleGetStatus)
-	mux.Handle("/files/", http.StripPrefix("/files/", http.FileServer(http.Dir(outputDir))))
 
-	log.Printf("local-video-service listening on %s", addr)
-	if err := http.ListenAndServe(addr, withCORS(mux)); err != nil {
+	srv := &http.Server{Addr: addr, Handler: withCORS(mux)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("local-video-service listening on %s with %d render workers", addr, workers)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
 	}
+	<-done
 }
 
 func (s *server) handleGenerate(w http.ResponseWriter, r *http.Request) {
@@ -99,35 +137,94 @@ func (s *server) handleGenerate(w http.ResponseWriter, r *http.Request) {
 		dur = 60
 	}
 	wpx, hpx := aspectToSize(req.AspectRatio)
+	motion, err := media.Resolve(req.Motion, req.ShotType)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	subs := subtitle.ModeBurn
+	if req.Subtitles != "" {
+		if subs, err = subtitle.ParseMode(req.Subtitles); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
 
 	id, err := randomID(12)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create id"})
 		return
 	}
-	outFile := filepath.Join(s.outputDir, id+".mp4")
-	publicURL := fmt.Sprintf("%s/files/%s.mp4", s.publicURL, id)
-
-	job := &videoJob{ID: id, Status: "processing", VideoURL: publicURL}
-	s.mu.Lock()
-	s.jobs[id] = job
-	s.mu.Unlock()
-
-	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Minute)
-	defer cancel()
-	if err := renderVideo(ctx, renderParams{Prompt: req.Prompt, ImageURL: strings.TrimSpace(req.ImageURL), W: wpx, H: hpx, Seconds: dur, OutPath: outFile}); err != nil {
-		s.mu.Lock()
-		job.Status = "failed"
-		job.Error = err.Error()
-		s.mu.Unlock()
-		writeJSON(w, http.StatusInternalServerError, generateResponse{VideoID: id, Status: "failed", Message: err.Error()})
+	// Rendering happens on the worker pool; the backend polls for the result
+	job, err := s.jobs.Submit(id, renderParams{
+		Prompt:    req.Prompt,
+		ImageURL:  strings.TrimSpace(req.ImageURL),
+		Motion:    motion,
+		Dialogue:  req.Dialogue,
+		Speakers:  req.Speakers,
+		Subtitles: subs,
+		W:         wpx,
+		H:         hpx,
+		Seconds:   dur,
+	})
+	if errors.Is(err, errQueueFull) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
 		return
 	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to queue job"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, generateResponse{VideoID: job.ID, Status: job.Status})
+}
+
+// render encodes the clip into a temp dir and uploads it to media storage
+func (s *server) render(ctx context.Context, job *videoJob, onProgress func(float64)) (string, error) {
+	id, p := job.ID, job.Params
+	dir, err := os.MkdirTemp("", "3kstory-video-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	if p.ImageURL != "" {
+		imgPath, err := s.fetchImage(ctx, p.ImageURL, dir)
+		if err != nil {
+			return "", err
+		}
+		p.ImagePath = imgPath
+	}
+	p.OutPath = filepath.Join(dir, id+".mp4")
+	p.Font, p.FontsDir = s.font, s.fontsDir
+	if err := renderVideo(ctx, p, onProgress); err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(p.OutPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	videoURL, err := s.store.Put(ctx, "videos/"+id+".mp4", f, "video/mp4")
+	if err != nil {
+		return "", fmt.Errorf("failed to store video: %w", err)
+	}
+	return videoURL, nil
+}
 
-	s.mu.Lock()
-	job.Status = "completed"
-	s.mu.Unlock()
-	writeJSON(w, http.StatusOK, generateResponse{VideoID: id, Status: "completed", VideoURL: publicURL})
+// fetchImage reads keyframes from media storage directly and downloads
+// anything else
+func (s *server) fetchImage(ctx context.Context, imageURL, dir string) (string, error) {
+	key, ok := storage.KeyForURL(s.store, imageURL)
+	if !ok {
+		return downloadToTemp(ctx, imageURL, dir)
+	}
+	body, _, err := s.store.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image %s: %w", key, err)
+	}
+	defer body.Close()
+	return writeTemp(dir, body)
 }
 
 func (s *server) handleGetStatus(w http.ResponseWriter, r *http.Request) {
@@ -140,14 +237,18 @@ func (s *server) handleGetStatus(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "video_id is required"})
 		return
 	}
-	s.mu.RLock()
-	job := s.jobs[id]
-	s.mu.RUnlock()
-	if job == nil {
+	job, ok := s.jobs.Get(id)
+	if !ok {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
 		return
 	}
-	resp := generateResponse{VideoID: job.ID, Status: job.Status, VideoURL: job.VideoURL}
+	resp := generateResponse{
+		VideoID:  job.ID,
+		Status:   job.Status,
+		VideoURL: job.VideoURL,
+		Progress: job.Progress,
+		Queued:   job.Status == jobProcessing && !job.Running,
+	}
 	if job.Error != "" {
 		resp.Message = job.Error
 	}
@@ -155,44 +256,64 @@ func (s *server) handleGetStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 type renderParams struct {
-	Prompt   string
-	ImageURL string
-	W        int
-	H        int
-	Seconds  int
-	OutPath  string
+	Prompt    string        `json:"prompt"`
+	ImageURL  string        `json:"image_url,omitempty"`
+	ImagePath string        `json:"-"` // ImageURL fetched to disk
+	Motion    media.Motion  `json:"motion,omitempty"`
+	Dialogue  string        `json:"dialogue,omitempty"`
+	Speakers  []string      `json:"speakers,omitempty"`
+	Subtitles subtitle.Mode `json:"subtitles,omitempty"`
+	W         int           `json:"w"`
+	H         int           `json:"h"`
+	Seconds   int           `json:"seconds"`
+	OutPath   string        `json:"-"`
+	Font      string        `json:"-"`
+	FontsDir  string        `json:"-"`
 }
 
-func renderVideo(ctx context.Context, p renderParams) error {
+func renderVideo(ctx context.Context, p renderParams, onProgress func(float64)) error {
 	if p.Seconds <= 0 {
 		return errors.New("invalid duration")
 	}
-	textPath := p.OutPath + ".txt"
-	if err := os.WriteFile(textPath, []byte(p.Prompt), 0o644); err != nil {
-		return fmt.Errorf("failed to write text: %w", err)
-	}
-	defer os.Remove(textPath)
 
 	args := []string{"-y"}
-	if p.ImageURL != "" {
-		imgPath, err := downloadToTemp(ctx, p.ImageURL)
-		if err != nil {
-			return err
-		}
-		defer os.Remove(imgPath)
-		args = append(args, "-loop", "1", "-i", imgPath, "-t", strconv.Itoa(p.Seconds))
-		vf := fmt.Sprintf("scale=%d:%d,format=yuv420p,drawtext=%s", p.W, p.H, drawTextFilter(textPath))
-		args = append(args, "-vf", vf, "-r", "30", p.OutPath)
+	var vf string
+	if p.ImagePath != "" {
+		// zoompan turns the single image frame into the whole clip
+		args = append(args, "-i", p.ImagePath)
+		vf = media.KenBurns(p.Motion, p.W, p.H, 30, float64(p.Seconds)) + ",format=yuv420p"
 	} else {
 		args = append(args, "-f", "lavfi", "-i", fmt.Sprintf("color=c=black:s=%dx%d:d=%d", p.W, p.H, p.Seconds))
-		vf := fmt.Sprintf("drawtext=%s,format=yuv420p", drawTextFilter(textPath))
-		args = append(args, "-vf", vf, "-r", "30", p.OutPath)
+		vf = "format=yuv420p"
 	}
 
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(string(out)))
+	var subArgs []string
+	cues := subtitle.FromDialogue(p.Dialogue, p.Speakers, float64(p.Seconds))
+	if len(cues) > 0 && p.Subtitles != subtitle.ModeNone {
+		subPath := p.OutPath + ".srt"
+		data := subtitle.SRT(cues)
+		if p.Subtitles == subtitle.ModeBurn {
+			subPath = p.OutPath + ".ass"
+			data = subtitle.ASS(cues, subtitle.Style{Font: p.Font, Width: p.W, Height: p.H})
+		}
+		if err := os.WriteFile(subPath, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write subtitles: %w", err)
+		}
+		defer os.Remove(subPath)
+
+		if p.Subtitles == subtitle.ModeBurn {
+			vf += "," + media.SubtitlesFilter(subPath, p.FontsDir)
+		} else {
+			args = append(args, "-i", subPath)
+			subArgs = []string{"-map", "0:v", "-map", "1:0", "-c:s", "mov_text", "-metadata:s:s:0", "language=chi"}
+		}
+	}
+
+	args = append(args, "-vf", vf)
+	args = append(args, subArgs...)
+	args = append(args, "-t", strconv.Itoa(p.Seconds), "-r", "30", p.OutPath)
+	if err := media.Run(ctx, args, float64(p.Seconds), onProgress); err != nil {
+		return err
 	}
 	if _, err := os.Stat(p.OutPath); err != nil {
 		return fmt.Errorf("output not created: %w", err)
@@ -200,7 +321,7 @@ func renderVideo(ctx context.Context, p renderParams) error {
 	return nil
 }
 
-func downloadToTemp(ctx context.Context, url string) (string, error) {
+func downloadToTemp(ctx context.Context, url, dir string) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return "", err
@@ -214,16 +335,16 @@ func downloadToTemp(ctx context.Context, url string) (string, error) {
 		b, _ := io.ReadAll(resp.Body)
 		return "", fmt.Errorf("failed to download image (status %d): %s", resp.StatusCode, string(b))
 	}
-	f, err := os.CreateTemp("", "3kstory-img-*.bin")
+	return writeTemp(dir, resp.Body)
+}
+
+// writeTemp copies r to a new file in dir, which the caller cleans up
+func writeTemp(dir string, r io.Reader) (string, error) {
+	f, err := os.CreateTemp(dir, "img-*.bin")
 	if err != nil {
 		return "", err
 	}
-	defer func() {
-		if err != nil {
-			_ = os.Remove(f.Name())
-		}
-	}()
-	if _, err := io.Copy(f, resp.Body); err != nil {
+	if _, err := io.Copy(f, r); err != nil {
 		_ = f.Close()
 		return "", err
 	}
@@ -233,33 +354,6 @@ func downloadToTemp(ctx context.Context, url string) (string, error) {
 	return f.Name(), nil
 }
 
-func drawTextFilter(textPath string) string {
-	fontOpt := ""
-	if font := findFontFile(); font != "" {
-		fontOpt = "fontfile=" + font + ":"
-	}
-	return fmt.Sprintf(
-		"%stextfile=%s:fontcolor=white:fontsize=36:box=1:boxcolor=black@0.55:boxborderw=18:x=40:y=40:line_spacing=10",
-		fontOpt,
-		textPath,
-	)
-}
-
-func findFontFile() string {
-	for _, p := range []string{
-		"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
-		"/System/Library/Fonts/Supplemental/Arial.ttf",
-		"/System/Library/Fonts/Supplemental/Helvetica.ttf",
-		"/Library/Fonts/Arial Unicode.ttf",
-		"/Library/Fonts/Arial.ttf",
-	} {
-		if _, err := os.Stat(p); err == nil {
-			return p
-		}
-	}
-	return ""
-}
-
 func aspectToSize(ar string) (int, int) {
 	if strings.TrimSpace(ar) == "9:16" {
 		return 720, 1280
@@ -294,6 +388,14 @@ func withCORS(next http.Handler) http.Handler {
 	})
 }
 
+func getenvInt(key string, def int) int {
+	v, err := strconv.Atoi(getenv(key, ""))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
 func getenv(key, def string) string {
 	v := strings.TrimSpace(os.Getenv(key))
 	if v == "" {
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job statuses reported to the backend. Queued jobs are already
+// "processing" from the backend's point of view.
+const (
+	jobProcessing = "processing"
+	jobCompleted  = "completed"
+	jobFailed     = "failed"
+)
+
+var errQueueFull = errors.New("render queue is full")
+
+type videoJob struct {
+	ID        string       `json:"id"`
+	Status    string       `json:"status"`
+	Params    renderParams `json:"params"`
+	VideoURL  string       `json:"video_url,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Running   bool         `json:"-"`
+	Progress  float64      `json:"-"` // 0-1, only known while ffmpeg runs
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+func (j *videoJob) terminal() bool {
+	return j.Status == jobCompleted || j.Status == jobFailed
+}
+
+// renderFunc produces the clip for a job and returns its URL
+type renderFunc func(ctx context.Context, job *videoJob, onProgress func(float64)) (string, error)
+
+type jobManagerConfig struct {
+	JournalPath   string
+	QueueSize     int
+	RenderTimeout time.Duration
+	// TTL is how long finished jobs stay queryable
+	TTL time.Duration
+}
+
+// jobManager queues render jobs for a bounded pool of ffmpeg workers. Every
+// state change is written to a JSON journal, so unfinished jobs are queued
+// again after a restart and finished ones can still be polled.
+type jobManager struct {
+	cfg    jobManagerConfig
+	render renderFunc
+
+	mu    sync.Mutex
+	jobs  map[string]*videoJob
+	queue chan string
+}
+
+func newJobManager(cfg jobManagerConfig, render renderFunc) (*jobManager, error) {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.RenderTimeout <= 0 {
+		cfg.RenderTimeout = 10 * time.Minute
+	}
+	m := &jobManager{cfg: cfg, render: render, jobs: map[string]*videoJob{}}
+
+	restored, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	m.prune(time.Now())
+
+	var pending []*videoJob
+	for _, job := range m.jobs {
+		if !job.terminal() {
+			pending = append(pending, job)
+		}
+	}
+	sort.Slice(pending, func(i, k int) bool { return pending[i].CreatedAt.Before(pending[k].CreatedAt) })
+
+	size := cfg.QueueSize
+	if len(pending) > size {
+		size = len(pending)
+	}
+	m.queue = make(chan string, size)
+	for _, job := range pending {
+		m.queue <- job.ID
+	}
+	if restored > 0 {
+		log.Printf("restored %d jobs from %s, %d to resume", restored, cfg.JournalPath, len(pending))
+	}
+	return m, nil
+}
+
+// Submit records a job and queues it
+func (m *jobManager) Submit(id string, p renderParams) (*videoJob, error) {
+	now := time.Now()
+	job := &videoJob{ID: id, Status: jobProcessing, Params: p, CreatedAt: now, UpdatedAt: now}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.queue) == cap(m.queue) {
+		return nil, errQueueFull
+	}
+	m.jobs[id] = job
+	if err := m.saveLocked(); err != nil {
+		delete(m.jobs, id)
+		return nil, err
+	}
+	m.queue <- id
+	out := *job
+	return &out, nil
+}
+
+// Get returns a snapshot of a job
+func (m *jobManager) Get(id string) (videoJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return videoJob{}, false
+	}
+	return *job, true
+}
+
+// Run starts the workers and the pruner and blocks until ctx is cancelled and
+// the workers have stopped. Renders interrupted by shutdown stay unfinished
+// in the journal and resume on the next start.
+func (m *jobManager) Run(ctx context.Context, workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.work(ctx)
+		}()
+	}
+
+	interval := m.cfg.TTL / 10
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			if m.prune(now) > 0 {
+				if err := m.saveLocked(); err != nil {
+					log.Printf("failed to write job journal: %v", err)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (m *jobManager) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-m.queue:
+			m.runJob(ctx, id)
+		}
+	}
+}
+
+func (m *jobManager) runJob(ctx context.Context, id string) {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	if !ok || job.terminal() {
+		m.mu.Unlock()
+		return
+	}
+	job.Running = true
+	snapshot := *job
+	m.mu.Unlock()
+
+	renderCtx, cancel := context.WithTimeout(ctx, m.cfg.RenderTimeout)
+	defer cancel()
+	videoURL, err := m.render(renderCtx, &snapshot, func(p float64) {
+		m.mu.Lock()
+		job.Progress = p
+		m.mu.Unlock()
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.Running = false
+	job.Progress = 0
+	if ctx.Err() != nil {
+		// Shutting down: keep the job unfinished so it resumes after restart
+		return
+	}
+	if err != nil {
+		if errors.Is(renderCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("render timed out after %s: %w", m.cfg.RenderTimeout, err)
+		}
+		job.Status = jobFailed
+		job.Error = err.Error()
+		log.Printf("job %s failed: %v", id, err)
+	} else {
+		job.Status = jobCompleted
+		job.VideoURL = videoURL
+	}
+	job.UpdatedAt = time.Now()
+	if err := m.saveLocked(); err != nil {
+		log.Printf("failed to write job journal: %v", err)
+	}
+}
+
+// prune drops finished jobs older than the TTL
+func (m *jobManager) prune(now time.Time) int {
+	removed := 0
+	for id, job := range m.jobs {
+		if job.terminal() && now.Sub(job.UpdatedAt) > m.cfg.TTL {
+			delete(m.jobs, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (m *jobManager) load() (int, error) {
+	data, err := os.ReadFile(m.cfg.JournalPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read job journal: %w", err)
+	}
+	var jobs []*videoJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return 0, fmt.Errorf("corrupt job journal %s: %w", m.cfg.JournalPath, err)
+	}
+	for _, job := range jobs {
+		m.jobs[job.ID] = job
+	}
+	return len(jobs), nil
+}
+
+// saveLocked rewrites the journal atomically; callers hold m.mu
+func (m *jobManager) saveLocked() error {
+	jobs := make([]*videoJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt.Before(jobs[k].CreatedAt) })
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(m.cfg.JournalPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".journal-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.cfg.JournalPath)
+}
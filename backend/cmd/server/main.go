@@ -1,8 +1,16 @@
 package main
 
 import (
+"context"
+"errors"
 "log"
+"net"
+"net/http"
 "os"
+"os/signal"
+"sync"
+"syscall"
+"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -10,6 +18,8 @@ import (
 	"github.com/richard9219/3kstory/internal/database"
 	"github.com/richard9219/3kstory/internal/middleware"
 	"github.com/richard9219/3kstory/internal/router"
+	"github.com/richard9219/3kstory/internal/services"
+	"github.com/richard9219/3kstory/internal/worker"
 )
 
 func main() {
@@ -25,12 +35,35 @@ log.Fatalf("Failed to connect database: %v", err)
 }
 
 rdb := database.InitRedis(cfg)
+svc := services.New(db, rdb, cfg)
+
+ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+defer stop()
+
+// Background loops stop with ctx; workers hand their jobs back to the queue
+var background sync.WaitGroup
+runBackground := func(run func(context.Context)) {
+background.Add(1)
+go func() {
+defer background.Done()
+run(ctx)
+}()
+}
+runBackground(svc.Health.Run)
+
+// Workers can also run as a separate process via cmd/worker
+if cfg.Worker.Enabled {
+runBackground(worker.New(svc, cfg.Worker.Concurrency).Run)
+}
+if cfg.Poller.Enabled {
+runBackground(worker.NewVideoPoller(svc.Video, time.Duration(cfg.Poller.TickInterval)*time.Second).Run)
+}
 
 if cfg.Env == "production" {
 gin.SetMode(gin.ReleaseMode)
 }
 
-r := gin.Default()
+r := gin.New()
 
 r.Use(middleware.CORS())
 r.Use(middleware.Logger())
@@ -40,15 +73,35 @@ r.GET("/health", func(c *gin.Context) {
 c.JSON(200, gin.H{"status": "ok"})
 })
 
-router.SetupRoutes(r, db, rdb, cfg)
+router.SetupRoutes(r, db, svc, cfg)
 
 port := os.Getenv("PORT")
 if port == "" {
 port = "8080"
 }
 
+// Requests share ctx, so open event streams end when shutdown starts
+srv := &http.Server{
+Addr:        ":" + port,
+Handler:     r,
+BaseContext: func(net.Listener) context.Context { return ctx },
+}
+go func() {
 log.Printf("Server starting on port %s...", port)
-if err := r.Run(":" + port); err != nil {
+if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 log.Fatalf("Failed to start server: %v", err)
 }
+}()
+
+<-ctx.Done()
+stop()
+log.Println("Shutting down...")
+
+shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+defer cancel()
+if err := srv.Shutdown(shutdownCtx); err != nil {
+log.Printf("Server shutdown failed: %v", err)
+}
+background.Wait()
+log.Println("Server stopped")
 }
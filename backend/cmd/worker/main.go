@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/database"
+	"github.com/richard9219/3kstory/internal/services"
+	"github.com/richard9219/3kstory/internal/worker"
+)
+
+// worker runs the AI job pool without the HTTP API. Start the API server with
+// WORKER_ENABLED=false when running workers this way.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := config.Load()
+
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+
+	rdb := database.InitRedis(cfg)
+	svc := services.New(db, rdb, cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go svc.Health.Run(ctx)
+	if cfg.Poller.Enabled {
+		go worker.NewVideoPoller(svc.Video, time.Duration(cfg.Poller.TickInterval)*time.Second).Run(ctx)
+	}
+	worker.New(svc, cfg.Worker.Concurrency).Run(ctx)
+}
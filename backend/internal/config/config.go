@@ -13,6 +13,13 @@ type Config struct {
 	JWT      JWTConfig
 	OSS      OSSConfig
 	AI       AIConfig
+	Worker   WorkerConfig
+	Poller   PollerConfig
+	Health   HealthConfig
+	Storage  StorageConfig
+	Render   RenderConfig
+	Review   ReviewConfig
+	Admin    AdminConfig
 }
 
 type DatabaseConfig struct {
@@ -41,12 +48,15 @@ type OSSConfig struct {
 	AccessKeySecret string
 	BucketName      string
 	BaseURL         string
+	Region          string
+	PathStyle       bool
 }
 
 type AIConfig struct {
 	AIProvider       string
 	QwenAPIKey       string
 	QwenAPIBase      string
+	QwenModelName    string
 	VLLMBaseURL      string
 	VLLMModelName    string
 	VLLMMaxTokens    int
@@ -61,6 +71,101 @@ type AIConfig struct {
 	ReviewServiceURL string
 	RunwayAPIKey     string
 	PikaAPIKey       string
+
+	OpenAICompatBaseURL   string
+	OpenAICompatModelName string
+	OpenAICompatAPIKey    string
+
+	LLMProviders       string
+	LLMRoutingStrategy string
+	LLMProviderWeights string
+	LLMProviderCosts   string
+
+	ScriptValidationRetries int
+
+	ImageProvider       string
+	SDWebUIURL          string
+	ComfyUIURL          string
+	ComfyUIWorkflow     string
+	ComfyUICheckpoint   string
+	ImageWidth          int
+	ImageHeight         int
+	ImageSteps          int
+	ImageNegativePrompt string
+	ImageTimeout        int
+	ImageStubFont       string
+	ImageIPAdapterModel string
+
+	TTSProvider   string
+	TTSServiceURL string
+	TTSVoices     string
+	TTSSpeed      float64
+	TTSTimeout    int
+}
+
+type WorkerConfig struct {
+	Enabled           bool
+	Concurrency       int
+	QueueName         string
+	VisibilityTimeout int
+	MaxAttempts       int
+	RetryBackoff      int
+}
+
+type PollerConfig struct {
+	Enabled        bool
+	TickInterval   int
+	RunwayInterval int
+	PikaInterval   int
+	LocalInterval  int
+	JitterPercent  int
+	Deadline       int
+}
+
+type StorageConfig struct {
+	Driver        string
+	LocalRoot     string
+	BaseURL       string
+	PresignExpiry int
+}
+
+type RenderConfig struct {
+	Transition   string
+	TransitionMS int
+	FPS          int
+	Timeout      int
+	// Subtitles is burn, soft or none
+	Subtitles        string
+	SubtitleFont     string
+	SubtitleFontsDir string
+	// MusicVolume is the level of background music in dB, before ducking
+	MusicVolume float64
+	// Loudness is the integrated loudness target of episodes in LUFS
+	Loudness float64
+}
+
+type ReviewConfig struct {
+	Enabled bool
+	// HighRisk is what happens to high-risk content: queue it for a human
+	// reviewer or block it outright
+	HighRisk    string
+	VideoFrames int
+	Timeout     int
+}
+
+type AdminConfig struct {
+	// Emails are comma-separated; existing accounts with these addresses are
+	// promoted to admin on startup
+	Emails string
+}
+
+type HealthConfig struct {
+	ProbeInterval    int
+	FailureThreshold int
+	ErrorRatePercent int
+	Window           int
+	MinSamples       int
+	OpenTimeout      int
 }
 
 func Load() *Config {
@@ -69,6 +174,41 @@ func Load() *Config {
 	vllmTimeout, _ := strconv.Atoi(getEnv("VLLM_TIMEOUT", "60"))
 	ollamaMaxTokens, _ := strconv.Atoi(getEnv("OLLAMA_MAX_TOKENS", "2048"))
 	ollamaTimeout, _ := strconv.Atoi(getEnv("OLLAMA_TIMEOUT", "60"))
+	workerEnabled, _ := strconv.ParseBool(getEnv("WORKER_ENABLED", "true"))
+	workerConcurrency, _ := strconv.Atoi(getEnv("WORKER_CONCURRENCY", "4"))
+	visibilityTimeout, _ := strconv.Atoi(getEnv("QUEUE_VISIBILITY_TIMEOUT", "300"))
+	maxAttempts, _ := strconv.Atoi(getEnv("QUEUE_MAX_ATTEMPTS", "3"))
+	retryBackoff, _ := strconv.Atoi(getEnv("QUEUE_RETRY_BACKOFF", "10"))
+	pollerEnabled, _ := strconv.ParseBool(getEnv("VIDEO_POLLER_ENABLED", "true"))
+	pollTick, _ := strconv.Atoi(getEnv("VIDEO_POLL_TICK", "2"))
+	pollRunway, _ := strconv.Atoi(getEnv("VIDEO_POLL_INTERVAL_RUNWAY", "10"))
+	pollPika, _ := strconv.Atoi(getEnv("VIDEO_POLL_INTERVAL_PIKA", "10"))
+	pollLocal, _ := strconv.Atoi(getEnv("VIDEO_POLL_INTERVAL_LOCAL", "3"))
+	pollJitter, _ := strconv.Atoi(getEnv("VIDEO_POLL_JITTER_PERCENT", "20"))
+	pollDeadline, _ := strconv.Atoi(getEnv("VIDEO_POLL_DEADLINE", "1800"))
+	scriptRetries, _ := strconv.Atoi(getEnv("SCRIPT_VALIDATION_RETRIES", "2"))
+	probeInterval, _ := strconv.Atoi(getEnv("HEALTH_PROBE_INTERVAL", "30"))
+	breakerThreshold, _ := strconv.Atoi(getEnv("BREAKER_FAILURE_THRESHOLD", "3"))
+	breakerErrorRate, _ := strconv.Atoi(getEnv("BREAKER_ERROR_RATE_PERCENT", "50"))
+	breakerWindow, _ := strconv.Atoi(getEnv("BREAKER_WINDOW", "20"))
+	breakerMinSamples, _ := strconv.Atoi(getEnv("BREAKER_MIN_SAMPLES", "5"))
+	breakerOpenTimeout, _ := strconv.Atoi(getEnv("BREAKER_OPEN_TIMEOUT", "30"))
+	imageWidth, _ := strconv.Atoi(getEnv("IMAGE_WIDTH", "1024"))
+	imageHeight, _ := strconv.Atoi(getEnv("IMAGE_HEIGHT", "576"))
+	imageSteps, _ := strconv.Atoi(getEnv("IMAGE_STEPS", "25"))
+	imageTimeout, _ := strconv.Atoi(getEnv("IMAGE_TIMEOUT", "300"))
+	ttsSpeed, _ := strconv.ParseFloat(getEnv("TTS_SPEED", "1"), 64)
+	ttsTimeout, _ := strconv.Atoi(getEnv("TTS_TIMEOUT", "120"))
+	ossPathStyle, _ := strconv.ParseBool(getEnv("OSS_PATH_STYLE", "false"))
+	presignExpiry, _ := strconv.Atoi(getEnv("STORAGE_PRESIGN_EXPIRY", "3600"))
+	renderTransitionMS, _ := strconv.Atoi(getEnv("RENDER_TRANSITION_MS", "500"))
+	renderFPS, _ := strconv.Atoi(getEnv("RENDER_FPS", "30"))
+	renderTimeout, _ := strconv.Atoi(getEnv("RENDER_TIMEOUT", "1800"))
+	renderMusicVolume, _ := strconv.ParseFloat(getEnv("RENDER_MUSIC_VOLUME", "-10"), 64)
+	renderLoudness, _ := strconv.ParseFloat(getEnv("RENDER_LOUDNESS", "-16"), 64)
+	reviewEnabled, _ := strconv.ParseBool(getEnv("REVIEW_ENABLED", "true"))
+	reviewFrames, _ := strconv.Atoi(getEnv("REVIEW_VIDEO_FRAMES", "4"))
+	reviewTimeout, _ := strconv.Atoi(getEnv("REVIEW_TIMEOUT", "60"))
 
 	return &Config{
 		Env:  getEnv("ENV", "development"),
@@ -96,11 +236,14 @@ func Load() *Config {
 			AccessKeySecret: getEnv("OSS_ACCESS_KEY_SECRET", ""),
 			BucketName:      getEnv("OSS_BUCKET_NAME", ""),
 			BaseURL:         getEnv("OSS_BASE_URL", ""),
+			Region:          getEnv("OSS_REGION", "us-east-1"),
+			PathStyle:       ossPathStyle,
 		},
 		AI: AIConfig{
 			AIProvider:       getEnv("AI_PROVIDER", "cloud_qwen"),
 			QwenAPIKey:       getEnv("QWEN_API_KEY", ""),
 			QwenAPIBase:      getEnv("QWEN_API_BASE", ""),
+			QwenModelName:    getEnv("QWEN_MODEL_NAME", "qwen-plus"),
 			VLLMBaseURL:      getEnv("VLLM_BASE_URL", "http://localhost:8000"),
 			VLLMModelName:    getEnv("VLLM_MODEL_NAME", "qwen2.5-7b"),
 			VLLMMaxTokens:    vllmMaxTokens,
@@ -115,6 +258,87 @@ func Load() *Config {
 			ReviewServiceURL: getEnv("AI_REVIEW_SERVICE_URL", ""),
 			RunwayAPIKey:     getEnv("RUNWAY_API_KEY", ""),
 			PikaAPIKey:       getEnv("PIKA_API_KEY", ""),
+
+			OpenAICompatBaseURL:   getEnv("OPENAI_COMPAT_BASE_URL", ""),
+			OpenAICompatModelName: getEnv("OPENAI_COMPAT_MODEL_NAME", ""),
+			OpenAICompatAPIKey:    getEnv("OPENAI_COMPAT_API_KEY", ""),
+
+			LLMProviders:       getEnv("LLM_PROVIDERS", ""),
+			LLMRoutingStrategy: getEnv("LLM_ROUTING_STRATEGY", "priority"),
+			LLMProviderWeights: getEnv("LLM_PROVIDER_WEIGHTS", ""),
+			LLMProviderCosts:   getEnv("LLM_PROVIDER_COSTS", ""),
+
+			ScriptValidationRetries: scriptRetries,
+
+			ImageProvider:       getEnv("AI_IMAGE_PROVIDER", ""),
+			SDWebUIURL:          getEnv("SD_WEBUI_URL", ""),
+			ComfyUIURL:          getEnv("COMFYUI_URL", ""),
+			ComfyUIWorkflow:     getEnv("COMFYUI_WORKFLOW", ""),
+			ComfyUICheckpoint:   getEnv("COMFYUI_CHECKPOINT", "v1-5-pruned-emaonly.safetensors"),
+			ImageWidth:          imageWidth,
+			ImageHeight:         imageHeight,
+			ImageSteps:          imageSteps,
+			ImageNegativePrompt: getEnv("IMAGE_NEGATIVE_PROMPT", "lowres, blurry, watermark, text, deformed"),
+			ImageTimeout:        imageTimeout,
+			ImageStubFont:       getEnv("IMAGE_STUB_FONT", ""),
+			ImageIPAdapterModel: getEnv("IMAGE_IP_ADAPTER_MODEL", ""),
+
+			TTSProvider:   getEnv("AI_TTS_PROVIDER", ""),
+			TTSServiceURL: getEnv("AI_TTS_SERVICE_URL", ""),
+			TTSVoices:     getEnv("TTS_VOICES", ""),
+			TTSSpeed:      ttsSpeed,
+			TTSTimeout:    ttsTimeout,
+		},
+		Worker: WorkerConfig{
+			Enabled:           workerEnabled,
+			Concurrency:       workerConcurrency,
+			QueueName:         getEnv("QUEUE_NAME", "ai"),
+			VisibilityTimeout: visibilityTimeout,
+			MaxAttempts:       maxAttempts,
+			RetryBackoff:      retryBackoff,
+		},
+		Poller: PollerConfig{
+			Enabled:        pollerEnabled,
+			TickInterval:   pollTick,
+			RunwayInterval: pollRunway,
+			PikaInterval:   pollPika,
+			LocalInterval:  pollLocal,
+			JitterPercent:  pollJitter,
+			Deadline:       pollDeadline,
+		},
+		Health: HealthConfig{
+			ProbeInterval:    probeInterval,
+			FailureThreshold: breakerThreshold,
+			ErrorRatePercent: breakerErrorRate,
+			Window:           breakerWindow,
+			MinSamples:       breakerMinSamples,
+			OpenTimeout:      breakerOpenTimeout,
+		},
+		Storage: StorageConfig{
+			Driver:        getEnv("STORAGE_DRIVER", "local"),
+			LocalRoot:     getEnv("MEDIA_ROOT", ".local/media"),
+			BaseURL:       getEnv("MEDIA_BASE_URL", "http://localhost:8080/media"),
+			PresignExpiry: presignExpiry,
+		},
+		Render: RenderConfig{
+			Transition:       getEnv("RENDER_TRANSITION", "crossfade"),
+			TransitionMS:     renderTransitionMS,
+			FPS:              renderFPS,
+			Timeout:          renderTimeout,
+			Subtitles:        getEnv("RENDER_SUBTITLES", "burn"),
+			SubtitleFont:     getEnv("SUBTITLE_FONT", "Noto Sans CJK SC"),
+			SubtitleFontsDir: getEnv("SUBTITLE_FONTS_DIR", ""),
+			MusicVolume:      renderMusicVolume,
+			Loudness:         renderLoudness,
+		},
+		Review: ReviewConfig{
+			Enabled:     reviewEnabled,
+			HighRisk:    getEnv("REVIEW_HIGH_RISK", "queue"),
+			VideoFrames: reviewFrames,
+			Timeout:     reviewTimeout,
+		},
+		Admin: AdminConfig{
+			Emails: getEnv("ADMIN_EMAILS", ""),
 		},
 	}
 }
@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/richard9219/3kstory/internal/config"
 	"github.com/richard9219/3kstory/internal/models"
@@ -38,6 +39,10 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if err := promoteAdmins(db, cfg.Admin.Emails); err != nil {
+		return nil, fmt.Errorf("failed to promote admins: %w", err)
+	}
+
 	log.Println("Database connected successfully")
 	return db, nil
 }
@@ -48,5 +53,44 @@ func autoMigrate(db *gorm.DB) error {
 		&models.Project{},
 		&models.Scene{},
 		&models.AITask{},
+		&models.VideoTask{},
+		&models.PromptTemplate{},
+		&models.ProjectPromptPin{},
+		&models.Asset{},
+		&models.VoiceMapping{},
+		&models.DialogueLine{},
+		&models.MusicTrack{},
+		&models.ReviewResult{},
+		&models.ReviewDecision{},
+		&models.ProjectCharacter{},
+		&models.SceneRevision{},
+		&models.ProjectVersion{},
+		&models.Series{},
 	)
 }
+
+// promoteAdmins grants the admin role to existing accounts listed in
+// ADMIN_EMAILS. Addresses without an account are skipped, so registering
+// one later does not make it an admin.
+func promoteAdmins(db *gorm.DB, emails string) error {
+	var list []string
+	for _, email := range strings.Split(emails, ",") {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			list = append(list, email)
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	result := db.Model(&models.User{}).
+		Where("LOWER(email) IN ? AND role <> ?", list, models.RoleAdmin).
+		Update("role", models.RoleAdmin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Promoted %d account(s) from ADMIN_EMAILS to admin", result.RowsAffected)
+	}
+	return nil
+}
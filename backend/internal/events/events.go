@@ -0,0 +1,220 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Event types streamed to clients watching a project
+const (
+	ScriptStarted    = "script.started"
+	ScriptCompleted  = "script.completed"
+	SceneCreated     = "scene.created"
+	ScenesEdited     = "scenes.edited"
+	ImageReady       = "image.ready"
+	VideoProgress    = "video.progress"
+	VideoReady       = "video.ready"
+	AudioReady       = "audio.ready"
+	ReviewVerdict    = "review.verdict"
+	ReviewDecided    = "review.decided"
+	RenderProgress   = "render.progress"
+	RenderReady      = "render.ready"
+	RenderFailed     = "render.failed"
+	ProjectCompleted = "project.completed"
+	ProjectFailed    = "project.failed"
+	ProjectHeld      = "project.held"
+	ProjectPublished = "project.published"
+)
+
+// Event is one progress notification for a project. ID is the Redis stream
+// entry ID, which clients send back as Last-Event-ID to resume.
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	ProjectID uint                   `json:"project_id"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Broker fans project events out through Redis so any backend instance can
+// serve a stream. Each event is appended to a capped stream (for replay) and
+// published on a pub/sub channel (for live delivery).
+type Broker struct {
+	rdb       *redis.Client
+	maxLength int64
+}
+
+func NewBroker(rdb *redis.Client) *Broker {
+	return &Broker{rdb: rdb, maxLength: 1000}
+}
+
+func streamKey(projectID uint) string {
+	return fmt.Sprintf("events:project:%d", projectID)
+}
+
+func channelName(projectID uint) string {
+	return fmt.Sprintf("events:project:%d:live", projectID)
+}
+
+// Publish records and broadcasts an event. A nil broker discards events.
+func (b *Broker) Publish(ctx context.Context, projectID uint, eventType string, data map[string]interface{}) error {
+	if b == nil {
+		return nil
+	}
+	ev := Event{Type: eventType, ProjectID: projectID, Data: data, CreatedAt: time.Now()}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	id, err := b.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream:       streamKey(projectID),
+		MaxLenApprox: b.maxLength,
+		Values:       map[string]interface{}{"event": payload},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to append event: %w", err)
+	}
+
+	ev.ID = id
+	payload, err = json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return b.rdb.Publish(ctx, channelName(projectID), payload).Err()
+}
+
+// Emit publishes an event and only logs failures; progress events must never
+// break the work that produces them
+func (b *Broker) Emit(ctx context.Context, projectID uint, eventType string, data map[string]interface{}) {
+	if err := b.Publish(ctx, projectID, eventType, data); err != nil {
+		log.Printf("events: failed to publish %s for project %d: %v", eventType, projectID, err)
+	}
+}
+
+// Replay returns the stored events after afterID ("0" for all of them)
+func (b *Broker) Replay(ctx context.Context, projectID uint, afterID string) ([]Event, error) {
+	msgs, err := b.rdb.XRange(ctx, streamKey(projectID), afterID, "+").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Event, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.ID == afterID {
+			continue
+		}
+		raw, ok := msg.Values["event"].(string)
+		if !ok {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+			continue
+		}
+		ev.ID = msg.ID
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+// Subscribe streams a project's events until ctx is cancelled. When
+// lastEventID is set, stored events after it are delivered first; live events
+// already covered by the replay are skipped, so none are lost or duplicated.
+func (b *Broker) Subscribe(ctx context.Context, projectID uint, lastEventID string) (<-chan Event, error) {
+	sub := b.rdb.Subscribe(ctx, channelName(projectID))
+	// Wait for the subscription so nothing published during replay is missed
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	var backlog []Event
+	if lastEventID != "" {
+		var err error
+		backlog, err = b.Replay(ctx, projectID, lastEventID)
+		if err != nil {
+			sub.Close()
+			return nil, err
+		}
+	}
+
+	out := make(chan Event, 64)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+
+		last := lastEventID
+		for _, ev := range backlog {
+			select {
+			case out <- ev:
+				last = ev.ID
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		live := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-live:
+				if !ok {
+					return
+				}
+				var ev Event
+				if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+					continue
+				}
+				if last != "" && !streamIDAfter(ev.ID, last) {
+					continue
+				}
+				select {
+				case out <- ev:
+					last = ev.ID
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// streamIDAfter compares Redis stream IDs of the form "<ms>-<seq>"
+func streamIDAfter(a, b string) bool {
+	am, as, errA := splitStreamID(a)
+	bm, bs, errB := splitStreamID(b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	if am != bm {
+		return am > bm
+	}
+	return as > bs
+}
+
+func splitStreamID(id string) (uint64, uint64, error) {
+	ms, seq, found := strings.Cut(id, "-")
+	msVal, err := strconv.ParseUint(ms, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !found {
+		return msVal, 0, nil
+	}
+	seqVal, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid stream id")
+	}
+	return msVal, seqVal, nil
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/health"
+	"github.com/richard9219/3kstory/internal/services"
+)
+
+type AdminHandler struct {
+	health *health.Monitor
+	ai     *services.AIService
+}
+
+func NewAdminHandler(monitor *health.Monitor, ai *services.AIService) *AdminHandler {
+	return &AdminHandler{health: monitor, ai: ai}
+}
+
+// ListProviders reports breaker state and recent errors for every AI backend
+// GET /api/v1/admin/providers
+func (h *AdminHandler) ListProviders(c *gin.Context) {
+	router := h.ai.TextRouter()
+	c.JSON(http.StatusOK, gin.H{
+		"text_routing": gin.H{
+			"strategy":   router.Strategy(),
+			"candidates": router.Candidates(),
+		},
+		"providers": h.health.Snapshot(),
+	})
+}
@@ -64,6 +64,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		PasswordHash: string(hashedPassword),
 		Nickname:     req.Nickname,
 		Points:       100,
+		Role:         models.RoleUser,
 	}
 
 	if err := h.db.Create(&user).Error; err != nil {
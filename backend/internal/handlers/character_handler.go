@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"gorm.io/gorm"
+)
+
+type CharacterHandler struct {
+	characters *services.CharacterService
+	db         *gorm.DB
+}
+
+func NewCharacterHandler(characters *services.CharacterService, db *gorm.DB) *CharacterHandler {
+	return &CharacterHandler{characters: characters, db: db}
+}
+
+func (h *CharacterHandler) ownedProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return &project, true
+}
+
+func characterIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("characterID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid character ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ListCharacters returns the project's character bible
+// GET /api/v1/projects/:id/characters
+func (h *CharacterHandler) ListCharacters(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	characters, err := h.characters.Characters(c, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch characters"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": characters})
+}
+
+// CreateCharacter adds a character by hand
+// POST /api/v1/projects/:id/characters
+func (h *CharacterHandler) CreateCharacter(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var req services.CharacterInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	character, err := h.characters.Create(c, project.ID, req)
+	if errors.Is(err, services.ErrCharacterExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": character})
+}
+
+// UpdateCharacter edits a character's description, references, seed, LoRA
+// or voice
+// PUT /api/v1/projects/:id/characters/:characterID
+func (h *CharacterHandler) UpdateCharacter(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	characterID, ok := characterIDParam(c)
+	if !ok {
+		return
+	}
+	var req services.CharacterInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	character, err := h.characters.Update(c, project.ID, characterID, req)
+	if errors.Is(err, services.ErrCharacterNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": character})
+}
+
+// DeleteCharacter removes a character from the bible
+// DELETE /api/v1/projects/:id/characters/:characterID
+func (h *CharacterHandler) DeleteCharacter(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	characterID, ok := characterIDParam(c)
+	if !ok {
+		return
+	}
+	err := h.characters.Delete(c, project.ID, characterID)
+	if errors.Is(err, services.ErrCharacterNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete character"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Character deleted"})
+}
+
+// UploadReference adds a reference image from a multipart form with the file
+// POST /api/v1/projects/:id/characters/:characterID/references
+func (h *CharacterHandler) UploadReference(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	characterID, ok := characterIDParam(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	character, err := h.characters.AddReference(c, project.ID, characterID, f, header.Header.Get("Content-Type"))
+	if errors.Is(err, services.ErrCharacterNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": character})
+}
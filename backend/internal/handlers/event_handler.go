@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/richard9219/3kstory/internal/events"
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+)
+
+const eventKeepAlive = 15 * time.Second
+
+type EventHandler struct {
+	db       *gorm.DB
+	broker   *events.Broker
+	upgrader websocket.Upgrader
+}
+
+func NewEventHandler(db *gorm.DB, broker *events.Broker) *EventHandler {
+	return &EventHandler{
+		db:     db,
+		broker: broker,
+		upgrader: websocket.Upgrader{
+			// Origins are already open through the CORS middleware; auth is by token
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// StreamProjectEvents streams a project's progress events. Requests with a
+// WebSocket upgrade get a WebSocket; everything else gets Server-Sent Events.
+// Resume with the Last-Event-ID header or the last_event_id query parameter
+// ("0" replays every stored event).
+// GET /api/v1/projects/:id/events
+func (h *EventHandler) StreamProjectEvents(c *gin.Context) {
+	projectID := c.Param("id")
+	userID := c.GetUint("user_id")
+
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", projectID, userID).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	stream, err := h.broker.Subscribe(c.Request.Context(), project.ID, lastEventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to events"})
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.serveWebSocket(c, stream)
+		return
+	}
+	h.serveSSE(c, stream)
+}
+
+func (h *EventHandler) serveSSE(c *gin.Context, stream <-chan events.Event) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case ev, ok := <-stream:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func (h *EventHandler) serveWebSocket(c *gin.Context, stream <-chan events.Event) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("events: websocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// The stream is server-to-client only; reading just detects the close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+				return
+			}
+		case ev, ok := <-stream:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+)
+
+// ModerationHandler serves the human review queue to admins
+type ModerationHandler struct {
+	moderation *services.ModerationService
+}
+
+func NewModerationHandler(moderation *services.ModerationService) *ModerationHandler {
+	return &ModerationHandler{moderation: moderation}
+}
+
+// ReviewQueueRequest filters the review queue
+type ReviewQueueRequest struct {
+	Status    string `form:"status"`
+	Stage     string `form:"stage"`
+	ProjectID uint   `form:"project_id"`
+	Limit     int    `form:"limit" binding:"max=100"`
+	Offset    int    `form:"offset" binding:"min=0"`
+}
+
+// ReviewDecisionRequest carries the reviewer's note; rejections need one
+type ReviewDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+// ListReviews returns the review queue, pending and appealed results by default
+// GET /api/v1/admin/reviews
+func (h *ModerationHandler) ListReviews(c *gin.Context) {
+	var req ReviewQueueRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	results, total, err := h.moderation.Queue(c, services.ReviewQueueFilter{
+		Status:    req.Status,
+		Stage:     req.Stage,
+		ProjectID: req.ProjectID,
+		Limit:     req.Limit,
+		Offset:    req.Offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review queue"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":  total,
+		"limit":  req.Limit,
+		"offset": req.Offset,
+		"data":   results,
+	})
+}
+
+// GetReview returns a review result with its audit trail
+// GET /api/v1/admin/reviews/:reviewID
+func (h *ModerationHandler) GetReview(c *gin.Context) {
+	reviewID, ok := reviewIDParam(c)
+	if !ok {
+		return
+	}
+	result, err := h.moderation.Get(c, reviewID)
+	if errors.Is(err, services.ErrReviewNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review result"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// ApproveReview clears held content
+// POST /api/v1/admin/reviews/:reviewID/approve
+func (h *ModerationHandler) ApproveReview(c *gin.Context) {
+	h.decide(c, h.moderation.Approve)
+}
+
+// RejectReview blocks content until it is regenerated
+// POST /api/v1/admin/reviews/:reviewID/reject
+func (h *ModerationHandler) RejectReview(c *gin.Context) {
+	h.decide(c, h.moderation.Reject)
+}
+
+func (h *ModerationHandler) decide(c *gin.Context, decide func(ctx context.Context, reviewID, adminID uint, note string) (*models.ReviewResult, error)) {
+	reviewID, ok := reviewIDParam(c)
+	if !ok {
+		return
+	}
+	var req ReviewDecisionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	result, err := decide(c, reviewID, c.GetUint("user_id"), req.Note)
+	writeReviewDecision(c, result, err)
+}
+
+// writeReviewDecision answers a decision or appeal
+func writeReviewDecision(c *gin.Context, result *models.ReviewResult, err error) {
+	switch {
+	case errors.Is(err, services.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReviewTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReviewNoteRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record review decision"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"data": result})
+	}
+}
+
+func reviewIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("reviewID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
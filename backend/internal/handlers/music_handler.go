@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"gorm.io/gorm"
+)
+
+type MusicHandler struct {
+	music *services.MusicService
+	db    *gorm.DB
+}
+
+func NewMusicHandler(music *services.MusicService, db *gorm.DB) *MusicHandler {
+	return &MusicHandler{music: music, db: db}
+}
+
+// SceneMusicRequest sets a scene's cue: a track by hand, none, or auto
+type SceneMusicRequest struct {
+	Mode    string `json:"mode"`
+	TrackID *uint  `json:"track_id"`
+}
+
+func (h *MusicHandler) ownedProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return &project, true
+}
+
+// ListTracks returns the project's music library
+// GET /api/v1/projects/:id/music
+func (h *MusicHandler) ListTracks(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	tracks, err := h.music.Tracks(c, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch music"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tracks})
+}
+
+// UploadTrack adds a track to the library from a multipart form with the
+// file and optional title, genre and comma-separated moods
+// POST /api/v1/projects/:id/music
+func (h *MusicHandler) UploadTrack(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	title := c.PostForm("title")
+	if title == "" {
+		title = strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename))
+	}
+	track, err := h.music.AddTrack(c, project.ID, f, header.Header.Get("Content-Type"), services.MusicTrackInput{
+		Title: title,
+		Genre: c.PostForm("genre"),
+		Moods: strings.Split(c.PostForm("moods"), ","),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": track})
+}
+
+// UpdateTrack retitles or retags a track
+// PUT /api/v1/projects/:id/music/:trackID
+func (h *MusicHandler) UpdateTrack(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	trackID, err := strconv.ParseUint(c.Param("trackID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid track ID"})
+		return
+	}
+	var req services.MusicTrackInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	track, err := h.music.UpdateTrack(c, project.ID, uint(trackID), req)
+	if errors.Is(err, services.ErrTrackNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update track"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": track})
+}
+
+// DeleteTrack removes a track; scenes cued to it go back to auto
+// DELETE /api/v1/projects/:id/music/:trackID
+func (h *MusicHandler) DeleteTrack(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	trackID, err := strconv.ParseUint(c.Param("trackID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid track ID"})
+		return
+	}
+	err = h.music.DeleteTrack(c, project.ID, uint(trackID))
+	if errors.Is(err, services.ErrTrackNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete track"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Track deleted"})
+}
+
+// MatchMusic assigns tracks to the scenes on automatic cues and returns
+// every scene's cue
+// POST /api/v1/projects/:id/music/match
+func (h *MusicHandler) MatchMusic(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	scenes, err := h.music.MatchScenes(c, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to match music", "details": err.Error()})
+		return
+	}
+	cues := make([]gin.H, 0, len(scenes))
+	for _, scene := range scenes {
+		cues = append(cues, gin.H{
+			"scene_id":       scene.ID,
+			"scene_number":   scene.SceneNumber,
+			"music_cue":      scene.MusicCue,
+			"music_track_id": scene.MusicTrackID,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": cues})
+}
+
+// SetSceneMusic picks a scene's music cue
+// PUT /api/v1/projects/:id/scenes/:sceneID/music
+func (h *MusicHandler) SetSceneMusic(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var scene models.Scene
+	if err := h.db.Where("id = ? AND project_id = ?", c.Param("sceneID"), project.ID).First(&scene).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+	var req SceneMusicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Mode == "" && req.TrackID != nil {
+		req.Mode = models.MusicCueManual
+	}
+	err := h.music.SetSceneCue(c, &scene, req.Mode, req.TrackID)
+	if errors.Is(err, services.ErrTrackNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scene})
+}
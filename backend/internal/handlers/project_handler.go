@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/richard9219/3kstory/internal/models"
@@ -10,14 +13,20 @@ import (
 )
 
 type ProjectHandler struct {
-	service *services.ProjectService
-	db      *gorm.DB
+	service  *services.ProjectService
+	tasks    *services.TaskService
+	assets   *services.AssetService
+	versions *services.VersionService
+	db       *gorm.DB
 }
 
-func NewProjectHandler(service *services.ProjectService, db *gorm.DB) *ProjectHandler {
+func NewProjectHandler(service *services.ProjectService, tasks *services.TaskService, assets *services.AssetService, versions *services.VersionService, db *gorm.DB) *ProjectHandler {
 	return &ProjectHandler{
-		service: service,
-		db:      db,
+		service:  service,
+		tasks:    tasks,
+		assets:   assets,
+		versions: versions,
+		db:       db,
 	}
 }
 
@@ -97,6 +106,13 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 		project.Description = req.Description
 	}
 	if req.Status != "" {
+		// Publishing and content review own these statuses
+		switch {
+		case req.Status == "published" || req.Status == "review" || req.Status == "blocked",
+			project.Status == "review" || project.Status == "blocked":
+			c.JSON(http.StatusConflict, gin.H{"error": "Status is set by content review and publishing"})
+			return
+		}
 		project.Status = req.Status
 	}
 
@@ -104,6 +120,9 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
 		return
 	}
+	if _, err := h.versions.Snapshot(c, project.ID, models.AuthorUser, "edit", ""); err != nil {
+		log.Printf("failed to record version of project %d: %v", project.ID, err)
+	}
 
 	c.JSON(http.StatusOK, project)
 }
@@ -123,9 +142,46 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 		return
 	}
 
+	if id, err := strconv.ParseUint(projectID, 10, 32); err == nil {
+		if err := h.assets.DeleteProjectAssets(c, uint(id)); err != nil {
+			log.Printf("failed to delete media of project %d: %v", id, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
 }
 
+// AssetResponse is an asset with a URL the client can read it from
+type AssetResponse struct {
+	models.Asset
+	SignedURL string `json:"signed_url"`
+}
+
+// ListAssets lists the media stored for a project
+// GET /api/v1/projects/:id/assets?kind=image
+func (h *ProjectHandler) ListAssets(c *gin.Context) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	assets, err := h.assets.ListProjectAssets(c, project.ID, c.Query("kind"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assets"})
+		return
+	}
+	out := make([]AssetResponse, 0, len(assets))
+	for _, asset := range assets {
+		signed, err := h.assets.SignedURL(c, &asset)
+		if err != nil {
+			signed = asset.URL
+		}
+		out = append(out, AssetResponse{Asset: asset, SignedURL: signed})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": out})
+}
+
 func (h *ProjectHandler) GetScenes(c *gin.Context) {
 	projectID := c.Param("id")
 	userID := c.GetUint("user_id")
@@ -155,9 +211,37 @@ func (h *ProjectHandler) GenerateScenes(c *gin.Context) {
 		return
 	}
 
-	go func() {
-		h.service.GenerateScenes(c.Request.Context(), project.ID)
-	}()
+	task, err := h.tasks.Submit(c, services.TaskTypeScript, &project.ID, nil, models.JSONMap{"prompt": project.Prompt})
+	if err != nil && task == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue scene generation"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Scene generation started", "task_id": task.ID})
+}
+
+// PublishProject makes a rendered project public once no scene is held by
+// content review and no content was rejected since the last render
+// POST /api/v1/projects/:id/publish
+func (h *ProjectHandler) PublishProject(c *gin.Context) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
 
-	c.JSON(http.StatusAccepted, gin.H{"message": "Scene generation started"})
+	published, err := h.service.Publish(c, project.ID)
+	var held *services.HeldScenesError
+	switch {
+	case errors.As(err, &held):
+		c.JSON(http.StatusConflict, gin.H{"error": "Scenes held by content review must be approved or regenerated before publishing", "scene_numbers": held.SceneNumbers})
+		return
+	case errors.Is(err, services.ErrProjectNotReady), errors.Is(err, services.ErrStaleRender):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish project"})
+		return
+	}
+	c.JSON(http.StatusOK, published)
 }
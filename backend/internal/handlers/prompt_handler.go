@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"gorm.io/gorm"
+)
+
+type PromptHandler struct {
+	prompts *services.PromptService
+	db      *gorm.DB
+}
+
+func NewPromptHandler(prompts *services.PromptService, db *gorm.DB) *PromptHandler {
+	return &PromptHandler{prompts: prompts, db: db}
+}
+
+type CreatePromptRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Body        string `json:"body" binding:"required"`
+	Description string `json:"description"`
+	Activate    bool   `json:"activate"`
+}
+
+type PinPromptRequest struct {
+	Version int `json:"version" binding:"required,min=1"`
+}
+
+// ListTemplates lists prompt template versions
+// GET /api/v1/admin/prompts?name=script
+func (h *PromptHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.prompts.ListTemplates(c, c.Query("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prompt templates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"names": services.PromptNames(), "data": templates})
+}
+
+// GetTemplate returns one template version
+// GET /api/v1/admin/prompts/:templateID
+func (h *PromptHandler) GetTemplate(c *gin.Context) {
+	id, ok := parseTemplateID(c)
+	if !ok {
+		return
+	}
+	tpl, err := h.prompts.GetTemplate(c, id)
+	if err != nil {
+		respondPromptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+// CreateTemplate stores a new version of a template; versions are never edited in place
+// POST /api/v1/admin/prompts
+func (h *PromptHandler) CreateTemplate(c *gin.Context) {
+	var req CreatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tpl, err := h.prompts.CreateVersion(c, req.Name, req.Body, req.Description, req.Activate, c.GetUint("user_id"))
+	if err != nil {
+		respondPromptError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, tpl)
+}
+
+// ActivateTemplate makes a version the default for unpinned projects
+// POST /api/v1/admin/prompts/:templateID/activate
+func (h *PromptHandler) ActivateTemplate(c *gin.Context) {
+	id, ok := parseTemplateID(c)
+	if !ok {
+		return
+	}
+	tpl, err := h.prompts.Activate(c, id)
+	if err != nil {
+		respondPromptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+// DeleteTemplate removes a version that is neither active nor pinned
+// DELETE /api/v1/admin/prompts/:templateID
+func (h *PromptHandler) DeleteTemplate(c *gin.Context) {
+	id, ok := parseTemplateID(c)
+	if !ok {
+		return
+	}
+	if err := h.prompts.DeleteTemplate(c, id); err != nil {
+		respondPromptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Prompt template deleted"})
+}
+
+// GetProjectPrompts shows which template versions a project uses
+// GET /api/v1/projects/:id/prompts
+func (h *PromptHandler) GetProjectPrompts(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	prompts, err := h.prompts.ProjectPrompts(c, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project prompts"})
+		return
+	}
+	c.JSON(http.StatusOK, prompts)
+}
+
+// PinProjectPrompt fixes the project to one version of a template
+// PUT /api/v1/projects/:id/prompts/:name
+func (h *PromptHandler) PinProjectPrompt(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var req PinPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pin, err := h.prompts.Pin(c, project.ID, c.Param("name"), req.Version)
+	if err != nil {
+		respondPromptError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pin)
+}
+
+// UnpinProjectPrompt returns the project to the active template version
+// DELETE /api/v1/projects/:id/prompts/:name
+func (h *PromptHandler) UnpinProjectPrompt(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	if err := h.prompts.Unpin(c, project.ID, c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpin prompt"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Prompt unpinned"})
+}
+
+func (h *PromptHandler) ownedProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return &project, true
+}
+
+func parseTemplateID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("templateID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondPromptError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPromptNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prompt template not found"})
+	case errors.Is(err, services.ErrPromptInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnknownPrompt), errors.Is(err, services.ErrInvalidPrompt):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Prompt template operation failed"})
+	}
+}
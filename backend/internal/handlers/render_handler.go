@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"gorm.io/gorm"
+)
+
+type RenderHandler struct {
+	renders *services.RenderService
+	db      *gorm.DB
+}
+
+func NewRenderHandler(renders *services.RenderService, db *gorm.DB) *RenderHandler {
+	return &RenderHandler{renders: renders, db: db}
+}
+
+// RenderRequest picks how scenes are joined; every field is optional
+type RenderRequest struct {
+	Transition   string `json:"transition"`
+	TransitionMS *int   `json:"transition_ms"`
+	AspectRatio  string `json:"aspect_ratio"`
+	// Subtitles is burn, soft or none
+	Subtitles string `json:"subtitles"`
+}
+
+// RenderProject queues the assembly of the project's scenes into one video
+// POST /api/v1/projects/:id/render
+func (h *RenderHandler) RenderProject(c *gin.Context) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	var req RenderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	opts, err := h.renders.Options(req.Transition, req.TransitionMS, req.AspectRatio, req.Subtitles)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.renders.Submit(c, project.ID, opts)
+	var missing *services.MissingMediaError
+	var held *services.HeldScenesError
+	switch {
+	case errors.Is(err, services.ErrRenderInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "task_id": task.ID})
+		return
+	case errors.As(err, &missing):
+		c.JSON(http.StatusConflict, gin.H{"error": "Every scene needs an image or video before rendering", "scene_numbers": missing.SceneNumbers})
+		return
+	case errors.As(err, &held):
+		c.JSON(http.StatusConflict, gin.H{"error": "Scenes held by content review cannot be rendered", "scene_numbers": held.SceneNumbers})
+		return
+	case err != nil && task == nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue render", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Render queued. Follow progress on the task or the project event stream.",
+		"task_id":    task.ID,
+		"transition": opts.Transition,
+		"subtitles":  opts.Subtitles,
+	})
+}
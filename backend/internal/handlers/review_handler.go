@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"gorm.io/gorm"
+)
+
+type ReviewHandler struct {
+	reviews    *services.ReviewService
+	moderation *services.ModerationService
+	db         *gorm.DB
+}
+
+func NewReviewHandler(reviews *services.ReviewService, moderation *services.ModerationService, db *gorm.DB) *ReviewHandler {
+	return &ReviewHandler{reviews: reviews, moderation: moderation, db: db}
+}
+
+// AppealRequest is the creator's case against a rejection
+type AppealRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ListProjectReviews returns the content review results of a project,
+// optionally filtered with ?status=
+// GET /api/v1/projects/:id/reviews
+func (h *ReviewHandler) ListProjectReviews(c *gin.Context) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	results, err := h.reviews.ProjectReviews(c, project.ID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review results"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": results})
+}
+
+// AppealReview contests a rejection; the result returns to the review queue
+// POST /api/v1/projects/:id/reviews/:reviewID/appeal
+func (h *ReviewHandler) AppealReview(c *gin.Context) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	reviewID, ok := reviewIDParam(c)
+	if !ok {
+		return
+	}
+	var req AppealRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.moderation.Appeal(c, project.ID, reviewID, c.GetUint("user_id"), req.Reason)
+	writeReviewDecision(c, result, err)
+}
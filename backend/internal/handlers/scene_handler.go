@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"gorm.io/gorm"
+)
+
+type SceneHandler struct {
+	scenes *services.SceneService
+	db     *gorm.DB
+}
+
+func NewSceneHandler(scenes *services.SceneService, db *gorm.DB) *SceneHandler {
+	return &SceneHandler{scenes: scenes, db: db}
+}
+
+type InsertSceneRequest struct {
+	services.SceneInput
+	// Position is the scene number the new scene takes; 0 appends it
+	Position int `json:"position" binding:"omitempty,min=1"`
+}
+
+type SceneIDsRequest struct {
+	SceneIDs []uint `json:"scene_ids" binding:"required,min=1"`
+}
+
+type SplitSceneRequest struct {
+	// At is the number of dialogue turns kept in the first half; 0 splits
+	// the scene in the middle
+	At int `json:"at" binding:"omitempty,min=1"`
+}
+
+func (h *SceneHandler) ownedProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return &project, true
+}
+
+func sceneIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("sceneID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func revisionIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("revisionID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// sceneError maps a failed storyboard edit to its response
+func sceneError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSceneNotFound), errors.Is(err, services.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrScenesBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidSceneEdit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit scenes"})
+	}
+}
+
+// GetScene returns one scene of the storyboard
+// GET /api/v1/projects/:id/scenes/:sceneID
+func (h *SceneHandler) GetScene(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	sceneID, ok := sceneIDParam(c)
+	if !ok {
+		return
+	}
+	scene, err := h.scenes.Scene(c, project.ID, sceneID)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scene})
+}
+
+// InsertScene adds a scene at a position and queues its image
+// POST /api/v1/projects/:id/scenes
+func (h *SceneHandler) InsertScene(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var req InsertSceneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scene, err := h.scenes.Insert(c, project.ID, req.Position, req.SceneInput)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": scene})
+}
+
+// UpdateScene edits a scene's script; its image is regenerated when the
+// prompt changes
+// PUT /api/v1/projects/:id/scenes/:sceneID
+func (h *SceneHandler) UpdateScene(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	sceneID, ok := sceneIDParam(c)
+	if !ok {
+		return
+	}
+	var req services.SceneInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scene, err := h.scenes.Update(c, project.ID, sceneID, req)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scene})
+}
+
+// DeleteScene removes a scene and renumbers the rest
+// DELETE /api/v1/projects/:id/scenes/:sceneID
+func (h *SceneHandler) DeleteScene(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	sceneID, ok := sceneIDParam(c)
+	if !ok {
+		return
+	}
+	if err := h.scenes.Delete(c, project.ID, sceneID); err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Scene deleted"})
+}
+
+// ReorderScenes renumbers the scenes in the given order
+// POST /api/v1/projects/:id/scenes/reorder
+func (h *SceneHandler) ReorderScenes(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var req SceneIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scenes, err := h.scenes.Reorder(c, project.ID, req.SceneIDs)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scenes})
+}
+
+// SplitScene divides a scene in two at a dialogue turn
+// POST /api/v1/projects/:id/scenes/:sceneID/split
+func (h *SceneHandler) SplitScene(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	sceneID, ok := sceneIDParam(c)
+	if !ok {
+		return
+	}
+	var req SplitSceneRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	scenes, err := h.scenes.Split(c, project.ID, sceneID, req.At)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scenes})
+}
+
+// MergeScenes joins adjacent scenes into one
+// POST /api/v1/projects/:id/scenes/merge
+func (h *SceneHandler) MergeScenes(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var req SceneIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scenes, err := h.scenes.Merge(c, project.ID, req.SceneIDs)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scenes})
+}
+
+// RegenerateScene has the AI redo a scene's dialogue, image, video or all of
+// it, optionally with an instruction
+// POST /api/v1/projects/:id/scenes/:sceneID/regenerate
+func (h *SceneHandler) RegenerateScene(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	sceneID, ok := sceneIDParam(c)
+	if !ok {
+		return
+	}
+	var req services.RegenerateInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scene, task, err := h.scenes.Regenerate(c, project.ID, sceneID, req)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	resp := gin.H{"data": scene}
+	if task != nil {
+		resp["task_id"] = task.ID
+	}
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// ListRevisions returns a scene's earlier versions, newest first
+// GET /api/v1/projects/:id/scenes/:sceneID/revisions
+func (h *SceneHandler) ListRevisions(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	sceneID, ok := sceneIDParam(c)
+	if !ok {
+		return
+	}
+	revisions, err := h.scenes.Revisions(c, project.ID, sceneID)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": revisions})
+}
+
+// RestoreRevision rolls a scene back to an earlier version
+// POST /api/v1/projects/:id/scenes/:sceneID/revisions/:revisionID/restore
+func (h *SceneHandler) RestoreRevision(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	sceneID, ok := sceneIDParam(c)
+	if !ok {
+		return
+	}
+	revisionID, ok := revisionIDParam(c)
+	if !ok {
+		return
+	}
+	scene, err := h.scenes.Restore(c, project.ID, sceneID, revisionID)
+	if err != nil {
+		sceneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scene})
+}
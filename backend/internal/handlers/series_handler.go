@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+)
+
+type SeriesHandler struct {
+	series     *services.SeriesService
+	characters *services.CharacterService
+}
+
+func NewSeriesHandler(series *services.SeriesService, characters *services.CharacterService) *SeriesHandler {
+	return &SeriesHandler{series: series, characters: characters}
+}
+
+type NextEpisodeRequest struct {
+	// Prompt steers the episode; by default it follows the outline
+	Prompt string `json:"prompt" binding:"max=2000"`
+}
+
+type SeasonRequest struct {
+	// Episodes is how many episodes to write; 0 writes the rest of the
+	// planned season
+	Episodes int `json:"episodes" binding:"omitempty,min=1,max=50"`
+	// Prompts steer the episodes in order
+	Prompts []string `json:"prompts" binding:"max=50,dive,max=2000"`
+}
+
+// ownedSeries loads the user's series from the :id parameter
+func (h *SeriesHandler) ownedSeries(c *gin.Context) (*models.Series, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return nil, false
+	}
+	series, err := h.series.Series(c, c.GetUint("user_id"), uint(id))
+	if errors.Is(err, services.ErrSeriesNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+		return nil, false
+	}
+	return series, true
+}
+
+// seriesError maps a refused episode request to its response
+func seriesError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSeriesBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidSeason):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue episodes"})
+	}
+}
+
+// CreateSeries starts a series
+// POST /api/v1/series
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var req services.SeriesInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	series, err := h.series.Create(c, c.GetUint("user_id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": series})
+}
+
+// ListSeries returns the user's series
+// GET /api/v1/series
+func (h *SeriesHandler) ListSeries(c *gin.Context) {
+	series, err := h.series.List(c, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch series"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// GetSeries returns a series with its episodes
+// GET /api/v1/series/:id
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	series, ok := h.ownedSeries(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": series})
+}
+
+// UpdateSeries edits a series' outline, genre, style or plan
+// PUT /api/v1/series/:id
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	series, ok := h.ownedSeries(c)
+	if !ok {
+		return
+	}
+	var req services.SeriesInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := h.series.Update(c, series.UserID, series.ID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
+// DeleteSeries removes a series; its episodes become standalone projects
+// DELETE /api/v1/series/:id
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	series, ok := h.ownedSeries(c)
+	if !ok {
+		return
+	}
+	if err := h.series.Delete(c, series.UserID, series.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete series"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Series deleted"})
+}
+
+// ListSeriesCharacters returns the cast shared by the series' episodes
+// GET /api/v1/series/:id/characters
+func (h *SeriesHandler) ListSeriesCharacters(c *gin.Context) {
+	series, ok := h.ownedSeries(c)
+	if !ok {
+		return
+	}
+	characters, err := h.characters.SeriesCast(c, series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch characters"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": characters})
+}
+
+// NextEpisode creates the next episode and queues its script
+// POST /api/v1/series/:id/episodes
+func (h *SeriesHandler) NextEpisode(c *gin.Context) {
+	series, ok := h.ownedSeries(c)
+	if !ok {
+		return
+	}
+	var req NextEpisodeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	episode, task, err := h.series.NextEpisode(c, series.ID, req.Prompt)
+	if err != nil {
+		seriesError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"data": episode, "task_id": task.ID})
+}
+
+// GenerateSeason queues the writing of several episodes in a row
+// POST /api/v1/series/:id/season
+func (h *SeriesHandler) GenerateSeason(c *gin.Context) {
+	series, ok := h.ownedSeries(c)
+	if !ok {
+		return
+	}
+	var req SeasonRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	task, err := h.series.QueueSeason(c, series.ID, req.Episodes, req.Prompts)
+	if err != nil {
+		seriesError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Season generation started", "task_id": task.ID})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"gorm.io/gorm"
+)
+
+type SpeechHandler struct {
+	speech *services.SpeechService
+	db     *gorm.DB
+}
+
+func NewSpeechHandler(speech *services.SpeechService, db *gorm.DB) *SpeechHandler {
+	return &SpeechHandler{speech: speech, db: db}
+}
+
+type SetVoicesRequest struct {
+	Voices []services.VoiceAssignment `json:"voices" binding:"required,dive"`
+}
+
+type GenerateSpeechRequest struct {
+	SceneIDs []uint `json:"scene_ids"`
+}
+
+func (h *SpeechHandler) ownedProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return &project, true
+}
+
+// GetVoices lists the voice of each character and the automatic voice pool
+// GET /api/v1/projects/:id/voices
+func (h *SpeechHandler) GetVoices(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	voices, err := h.speech.Voices(c, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch voices"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": voices, "pool": h.speech.VoicePool()})
+}
+
+// SetVoices assigns voices to characters
+// PUT /api/v1/projects/:id/voices
+func (h *SpeechHandler) SetVoices(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var req SetVoicesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.speech.SetVoices(c, project.ID, req.Voices); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.GetVoices(c)
+}
+
+// GenerateSpeech queues dialogue audio for some or all scenes
+// POST /api/v1/projects/:id/speech
+func (h *SpeechHandler) GenerateSpeech(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var req GenerateSpeechRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tasks, err := h.speech.Submit(c, project.ID, req.SceneIDs)
+	if err != nil && len(tasks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ids := make([]uint, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Speech generation queued", "task_ids": ids})
+}
+
+// GetSceneDialogue returns a scene's synthesized lines with their timings
+// GET /api/v1/projects/:id/scenes/:sceneID/dialogue
+func (h *SpeechHandler) GetSceneDialogue(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var scene models.Scene
+	if err := h.db.Where("id = ? AND project_id = ?", c.Param("sceneID"), project.ID).First(&scene).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+	lines, err := h.speech.SceneDialogue(c, scene.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dialogue"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"scene_id":          scene.ID,
+		"audio_url":         scene.AudioURL,
+		"audio_duration_ms": scene.AudioDurationMS,
+		"data":              lines,
+	})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"github.com/richard9219/3kstory/internal/subtitle"
+	"gorm.io/gorm"
+)
+
+type SubtitleHandler struct {
+	subtitles *services.SubtitleService
+	db        *gorm.DB
+}
+
+func NewSubtitleHandler(subtitles *services.SubtitleService, db *gorm.DB) *SubtitleHandler {
+	return &SubtitleHandler{subtitles: subtitles, db: db}
+}
+
+// GetSRT downloads the project's dialogue as an SRT file timed to the episode
+// GET /api/v1/projects/:id/subtitles.srt
+func (h *SubtitleHandler) GetSRT(c *gin.Context) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	cues, err := h.subtitles.ProjectCues(c, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build subtitles", "details": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="project-%d.srt"`, project.ID))
+	c.Data(http.StatusOK, "application/x-subrip; charset=utf-8", subtitle.SRT(cues))
+}
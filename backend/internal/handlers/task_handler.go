@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+)
+
+type TaskHandler struct {
+	tasks *services.TaskService
+}
+
+func NewTaskHandler(tasks *services.TaskService) *TaskHandler {
+	return &TaskHandler{tasks: tasks}
+}
+
+// GetTask returns the state of a queued AI task
+// GET /api/v1/tasks/:taskID
+func (h *TaskHandler) GetTask(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("taskID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	task, err := h.tasks.GetTask(c, uint(taskID))
+	if err != nil || !h.ownsTask(c, task) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) ownsTask(c *gin.Context, task *models.AITask) bool {
+	switch {
+	case task.ProjectID != nil:
+		return h.tasks.ProjectOwnedBy(c, *task.ProjectID, c.GetUint("user_id"))
+	case task.SeriesID != nil:
+		return h.tasks.SeriesOwnedBy(c, *task.SeriesID, c.GetUint("user_id"))
+	}
+	return false
+}
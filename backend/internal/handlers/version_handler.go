@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+	"gorm.io/gorm"
+)
+
+type VersionHandler struct {
+	versions *services.VersionService
+	scenes   *services.SceneService
+	db       *gorm.DB
+}
+
+func NewVersionHandler(versions *services.VersionService, scenes *services.SceneService, db *gorm.DB) *VersionHandler {
+	return &VersionHandler{versions: versions, scenes: scenes, db: db}
+}
+
+type CreateVersionRequest struct {
+	Note string `json:"note" binding:"required,max=500"`
+}
+
+func (h *VersionHandler) ownedProject(c *gin.Context) (*models.Project, bool) {
+	var project models.Project
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).First(&project).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	return &project, true
+}
+
+func versionParam(c *gin.Context) (int, bool) {
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return 0, false
+	}
+	return number, true
+}
+
+// versionQuery reads an optional version number from the query string
+func versionQuery(c *gin.Context, key string) (int, bool) {
+	raw := c.Query(key)
+	if raw == "" {
+		return 0, true
+	}
+	number, err := strconv.Atoi(raw)
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + key + " version"})
+		return 0, false
+	}
+	return number, true
+}
+
+// ListVersions returns the project's script versions, newest first
+// GET /api/v1/projects/:id/versions
+func (h *VersionHandler) ListVersions(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	versions, err := h.versions.Versions(c, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch versions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": versions})
+}
+
+// CreateVersion saves the current script as a named checkpoint
+// POST /api/v1/projects/:id/versions
+func (h *VersionHandler) CreateVersion(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	var req CreateVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, err := h.versions.Snapshot(c, project.ID, models.AuthorUser, "checkpoint", req.Note)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save version"})
+		return
+	}
+	if version == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The project has no scenes yet"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": version})
+}
+
+// GetVersion returns one version with its scenes
+// GET /api/v1/projects/:id/versions/:version
+func (h *VersionHandler) GetVersion(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	number, ok := versionParam(c)
+	if !ok {
+		return
+	}
+	version, err := h.versions.Version(c, project.ID, number)
+	if errors.Is(err, services.ErrVersionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch version"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": version})
+}
+
+// DiffVersions compares two versions scene by scene; by default the latest
+// version with the one before it
+// GET /api/v1/projects/:id/versions/diff?from=&to=
+func (h *VersionHandler) DiffVersions(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	from, ok := versionQuery(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := versionQuery(c, "to")
+	if !ok {
+		return
+	}
+	diff, err := h.versions.Diff(c, project.ID, from, to)
+	if errors.Is(err, services.ErrVersionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare versions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+// RestoreVersion puts the project's script back as it was in a version
+// POST /api/v1/projects/:id/versions/:version/restore
+func (h *VersionHandler) RestoreVersion(c *gin.Context) {
+	project, ok := h.ownedProject(c)
+	if !ok {
+		return
+	}
+	number, ok := versionParam(c)
+	if !ok {
+		return
+	}
+	scenes, err := h.scenes.RestoreVersion(c, project.ID, number)
+	switch {
+	case errors.Is(err, services.ErrVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrScenesBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore version"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": scenes})
+}
@@ -1,22 +1,32 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/media"
+	"github.com/richard9219/3kstory/internal/models"
 	"github.com/richard9219/3kstory/internal/services"
 )
 
 type VideoHandler struct {
 	videoService   *services.VideoService
 	projectService *services.ProjectService
+	tasks          *services.TaskService
+	prompts        *services.PromptService
+	characters     *services.CharacterService
 }
 
-func NewVideoHandler(videoService *services.VideoService, projectService *services.ProjectService) *VideoHandler {
+func NewVideoHandler(videoService *services.VideoService, projectService *services.ProjectService, tasks *services.TaskService, prompts *services.PromptService, characters *services.CharacterService) *VideoHandler {
 	return &VideoHandler{
 		videoService:   videoService,
 		projectService: projectService,
+		tasks:          tasks,
+		prompts:        prompts,
+		characters:     characters,
 	}
 }
 
@@ -28,6 +38,9 @@ type GenerateVideoRequest struct {
 	ImageURL    string `json:"image_url"`
 	Duration    int    `json:"duration" binding:"min=1,max=60"`
 	AspectRatio string `json:"aspect_ratio" binding:"oneof=16:9 9:16"`
+	// Motion picks a Ken Burns preset for the local renderer; by default it
+	// follows the scene's shot type
+	Motion string `json:"motion"`
 }
 
 // GenerateVideoResponse represents the response from video generation
@@ -73,6 +86,39 @@ func (h *VideoHandler) GenerateVideo(c *gin.Context) {
 		return
 	}
 
+	scene := projectScene(project, req.SceneID)
+	if scene == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scene does not belong to this project"})
+		return
+	}
+
+	motion, err := media.Resolve(req.Motion, scene.ShotType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cast, err := h.characters.Cast(c, scene)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch characters"})
+		return
+	}
+
+	// The request prompt is wrapped by the project's video template, which
+	// also describes the scene's characters
+	prompt, promptTpl, err := h.prompts.Prompt(c, project.ID, services.PromptVideo, services.PromptData{
+		Prompt: req.Prompt,
+		Title:  project.Title,
+		Genre:  project.Genre,
+		Style:  project.Style,
+		Scene:  scene,
+		Cast:   cast,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render video prompt", "details": err.Error()})
+		return
+	}
+
 	// Set defaults
 	if req.Duration == 0 {
 		req.Duration = 30
@@ -81,33 +127,45 @@ func (h *VideoHandler) GenerateVideo(c *gin.Context) {
 		req.AspectRatio = "16:9"
 	}
 
-	// Create video generation request
-	videoReq := &services.VideoGenerationRequest{
+	task := &models.VideoTask{
 		ProjectID:   uint(projectID),
 		SceneID:     req.SceneID,
-		Prompt:      req.Prompt,
-		Provider:    services.VideoProvider(req.Provider),
+		Provider:    req.Provider,
+		Prompt:      prompt,
 		ImageURL:    req.ImageURL,
 		Duration:    req.Duration,
 		AspectRatio: req.AspectRatio,
+		Motion:      string(motion),
+		Status:      models.VideoTaskPending,
+	}
+	if err := h.videoService.SaveVideoTask(c, task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create video task"})
+		return
 	}
 
-	// Generate video with failover support
-	result, err := h.videoService.FailoverGenerate(c, videoReq)
-	if err != nil {
+	// Provider submission (with failover) happens on the worker pool
+	projectIDUint := uint(projectID)
+	aiTask, err := h.tasks.SubmitWithPrompt(c, services.TaskTypeVideo, &projectIDUint, &req.SceneID, models.JSONMap{"video_task_id": task.ID}, promptTpl)
+	if err != nil && aiTask == nil {
+		task.Status = models.VideoTaskFailed
+		task.ErrorMessage = err.Error()
+		if saveErr := h.videoService.SaveVideoTask(c, task); saveErr != nil {
+			log.Printf("failed to mark video task %d failed: %v", task.ID, saveErr)
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Video generation failed",
 			"details": err.Error(),
+			"task_id": task.ID,
 		})
 		return
 	}
 
 	c.JSON(http.StatusAccepted, GenerateVideoResponse{
-		VideoID:  result.VideoID,
-		Status:   result.Status,
-		Provider: string(result.Provider),
-		Message:  "Video generation started. Poll for status updates.",
-		VideoURL: result.VideoURL,
+		TaskID:   task.ID,
+		Status:   task.Status,
+		Provider: task.Provider,
+		Message:  "Video generation queued. Poll for status updates.",
 	})
 }
 
@@ -150,7 +208,8 @@ func (h *VideoHandler) GetVideoStatus(c *gin.Context) {
 		return
 	}
 
-	result, err := h.videoService.PollVideoStatus(c, req.VideoID, services.VideoProvider(req.Provider))
+	provider := services.VideoProvider(req.Provider)
+	result, err := h.videoService.PollVideoStatus(c, req.VideoID, provider)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get video status",
@@ -159,19 +218,34 @@ func (h *VideoHandler) GetVideoStatus(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, GenerateVideoResponse{
+	resp := GenerateVideoResponse{
 		VideoID:  result.VideoID,
 		Status:   result.Status,
 		Provider: string(result.Provider),
 		VideoURL: result.VideoURL,
-	})
+	}
+
+	// Record the transition on the task row when the job was started through us
+	if task, err := h.videoService.FindVideoTaskByProviderID(c, uint(projectID), provider, req.VideoID); err == nil {
+		if result.VideoID == "" {
+			result.VideoID = req.VideoID
+		}
+		if err := h.videoService.ApplyVideoResult(c, task, result); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update video task"})
+			return
+		}
+		resp.TaskID = task.ID
+		resp.Status = task.Status
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 // ListVideosRequest represents the request to list videos
 type ListVideosRequest struct {
 	Status string `form:"status"`
 	Limit  int    `form:"limit" binding:"max=100"`
-	Offset int    `form:"offset"`
+	Offset int    `form:"offset" binding:"min=0"`
 }
 
 // ListVideos retrieves all videos for a project
@@ -212,20 +286,26 @@ func (h *VideoHandler) ListVideos(c *gin.Context) {
 		req.Limit = 20
 	}
 
-	tasks, err := h.videoService.ListVideoTasks(c, uint(projectID))
+	tasks, total, err := h.videoService.ListVideoTasks(c, uint(projectID), services.VideoTaskFilter{
+		Status: req.Status,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve videos"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"total": len(tasks),
-		"data":  tasks,
+		"total":  total,
+		"limit":  req.Limit,
+		"offset": req.Offset,
+		"data":   tasks,
 	})
 }
 
-// CancelVideoGeneration cancels an ongoing video generation job
-// DELETE /api/v1/projects/:id/video/:videoID
+// CancelVideoGeneration cancels a queued or running video task by its task ID
+// DELETE /api/v1/projects/:id/video/:taskID
 func (h *VideoHandler) CancelVideoGeneration(c *gin.Context) {
 	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -251,12 +331,39 @@ func (h *VideoHandler) CancelVideoGeneration(c *gin.Context) {
 		return
 	}
 
-	videoID := c.Param("videoID")
+	taskID, err := strconv.ParseUint(c.Param("taskID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video task ID"})
+		return
+	}
 
-	// TODO: Implement video cancellation logic with provider APIs
+	task, err := h.videoService.CancelVideoTask(c, uint(projectID), uint(taskID))
+	if errors.Is(err, services.ErrVideoTaskNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video task not found"})
+		return
+	}
+	if errors.Is(err, services.ErrVideoTaskFinished) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel video task"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Video generation cancelled successfully",
-		"video_id": videoID,
+		"message": "Video generation cancelled successfully",
+		"task_id": task.ID,
+		"status":  task.Status,
 	})
 }
+
+// projectScene returns the project's scene with the given ID, or nil
+func projectScene(project *models.Project, sceneID uint) *models.Scene {
+	for i := range project.Scenes {
+		if project.Scenes[i].ID == sceneID {
+			return &project.Scenes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	StateClosed   = "closed"    // traffic flows normally
+	StateOpen     = "open"      // backend is skipped until the cooldown ends
+	StateHalfOpen = "half_open" // a single trial request decides what happens next
+)
+
+// BreakerConfig tunes when a breaker trips
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive failures that open the circuit
+	ErrorRate        float64       // error rate over the window that opens it (0-1)
+	Window           int           // number of recent outcomes kept for the error rate
+	MinSamples       int           // outcomes needed before ErrorRate applies
+	OpenTimeout      time.Duration // how long the circuit stays open
+}
+
+// Breaker is a per-backend circuit breaker with passive error-rate tracking
+type Breaker struct {
+	cfg BreakerConfig
+
+	mu          sync.Mutex
+	state       string
+	consecutive int
+	outcomes    []bool // ring buffer, true = failure
+	next        int
+	filled      int
+	openedAt    time.Time
+	trialActive bool
+}
+
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 20
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 5
+	}
+	if cfg.ErrorRate <= 0 || cfg.ErrorRate > 1 {
+		cfg.ErrorRate = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	return &Breaker{cfg: cfg, state: StateClosed, outcomes: make([]bool, cfg.Window)}
+}
+
+// Allow reports whether a request may be sent. In the half-open state only
+// one trial request is let through at a time.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+	switch b.state {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		if b.trialActive {
+			return false
+		}
+		b.trialActive = true
+		return true
+	default:
+		return true
+	}
+}
+
+// State returns the current state without consuming a half-open trial
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Record feeds the outcome of a request or probe into the breaker
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+	b.outcomes[b.next] = failed
+	b.next = (b.next + 1) % len(b.outcomes)
+	if b.filled < len(b.outcomes) {
+		b.filled++
+	}
+
+	if !failed {
+		b.consecutive = 0
+		if b.state == StateHalfOpen {
+			b.reset()
+		}
+		return
+	}
+
+	b.consecutive++
+	switch b.state {
+	case StateHalfOpen:
+		b.trip()
+	case StateClosed:
+		if b.consecutive >= b.cfg.FailureThreshold || (b.filled >= b.cfg.MinSamples && b.errorRate() >= b.cfg.ErrorRate) {
+			b.trip()
+		}
+	}
+}
+
+// Release gives back a half-open trial whose request was cancelled, without
+// counting it as a success or failure, so the next request can run the trial
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateHalfOpen {
+		b.trialActive = false
+	}
+}
+
+// ErrorRate returns the failure ratio over the recent window
+func (b *Breaker) ErrorRate() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.errorRate()
+}
+
+func (b *Breaker) errorRate() float64 {
+	if b.filled == 0 {
+		return 0
+	}
+	failures := 0
+	for i := 0; i < b.filled; i++ {
+		if b.outcomes[i] {
+			failures++
+		}
+	}
+	return float64(failures) / float64(b.filled)
+}
+
+// advance moves an open breaker to half-open once its cooldown has passed
+func (b *Breaker) advance() {
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = StateHalfOpen
+		b.trialActive = false
+	}
+}
+
+func (b *Breaker) trip() {
+	b.state = StateOpen
+	b.openedAt = time.Now()
+	b.trialActive = false
+}
+
+func (b *Breaker) reset() {
+	b.state = StateClosed
+	b.consecutive = 0
+	b.trialActive = false
+	b.filled = 0
+	b.next = 0
+}
@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testBreaker() *Breaker {
+	return NewBreaker(BreakerConfig{FailureThreshold: 3, Window: 10, MinSamples: 5, ErrorRate: 0.5, OpenTimeout: time.Minute})
+}
+
+// cooldown makes an open breaker's timeout elapse
+func cooldown(b *Breaker) {
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-2 * b.cfg.OpenTimeout)
+	b.mu.Unlock()
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	b := testBreaker()
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("new breaker state = %s, want %s", got, StateClosed)
+	}
+
+	for i := 0; i < 2; i++ {
+		b.Record(true)
+	}
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state after 2 failures = %s, want %s", got, StateClosed)
+	}
+	b.Record(true)
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("state after 3 failures = %s, want %s", got, StateOpen)
+	}
+	if b.Allow() {
+		t.Fatal("open breaker allowed a request")
+	}
+
+	cooldown(b)
+	if got := b.State(); got != StateHalfOpen {
+		t.Fatalf("state after cooldown = %s, want %s", got, StateHalfOpen)
+	}
+	if !b.Allow() {
+		t.Fatal("half-open breaker refused the trial")
+	}
+	if b.Allow() {
+		t.Fatal("half-open breaker allowed a second trial")
+	}
+
+	b.Record(false)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state after successful trial = %s, want %s", got, StateClosed)
+	}
+	if got := b.ErrorRate(); got != 0 {
+		t.Fatalf("error rate after reset = %v, want 0", got)
+	}
+}
+
+func TestBreakerFailedTrialReopens(t *testing.T) {
+	b := testBreaker()
+	for i := 0; i < 3; i++ {
+		b.Record(true)
+	}
+	cooldown(b)
+	if !b.Allow() {
+		t.Fatal("half-open breaker refused the trial")
+	}
+	b.Record(true)
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("state after failed trial = %s, want %s", got, StateOpen)
+	}
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+	b := testBreaker()
+	// alternating outcomes never reach 3 consecutive failures
+	for i := 0; i < 4; i++ {
+		b.Record(i%2 == 0)
+	}
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state below MinSamples = %s, want %s", got, StateClosed)
+	}
+	b.Record(true)
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("state at 60%% errors = %s, want %s", got, StateOpen)
+	}
+}
+
+func TestBreakerReleasedTrial(t *testing.T) {
+	b := testBreaker()
+	for i := 0; i < 3; i++ {
+		b.Record(true)
+	}
+	cooldown(b)
+	if !b.Allow() {
+		t.Fatal("half-open breaker refused the trial")
+	}
+
+	b.Release()
+	if got := b.State(); got != StateHalfOpen {
+		t.Fatalf("state after release = %s, want %s", got, StateHalfOpen)
+	}
+	if !b.Allow() {
+		t.Fatal("released trial was not handed to the next request")
+	}
+	b.Record(false)
+	if got := b.State(); got != StateClosed {
+		t.Fatalf("state after successful trial = %s, want %s", got, StateClosed)
+	}
+}
+
+func TestMonitorCancelledTrial(t *testing.T) {
+	m := NewMonitor(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}, 0)
+	m.Register("runway", KindVideo, nil)
+	m.Record("runway", context.DeadlineExceeded, time.Second)
+	if m.Healthy("runway") {
+		t.Fatal("backend still healthy after tripping")
+	}
+
+	b := m.get("runway")
+	cooldown(b.breaker)
+	if !m.Allow("runway") {
+		t.Fatal("half-open backend refused the trial")
+	}
+	m.Record("runway", context.Canceled, time.Second)
+	if !m.Allow("runway") {
+		t.Fatal("cancelled trial blocked the backend")
+	}
+
+	b.mu.Lock()
+	requests, failures := b.requests, b.failures
+	b.mu.Unlock()
+	if requests != 1 || failures != 1 {
+		t.Fatalf("requests, failures = %d, %d; the cancelled trial should not be counted", requests, failures)
+	}
+}
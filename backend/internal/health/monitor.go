@@ -0,0 +1,246 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Backend kinds
+const (
+	KindText   = "text"
+	KindVideo  = "video"
+	KindImage  = "image"
+	KindSpeech = "speech"
+	KindReview = "review"
+)
+
+// ProbeFunc actively checks a backend; nil means the backend is only tracked passively
+type ProbeFunc func(ctx context.Context) error
+
+// Status is a point-in-time view of one backend
+type Status struct {
+	Name         string     `json:"name"`
+	Kind         string     `json:"kind"`
+	State        string     `json:"state"`
+	ErrorRate    float64    `json:"error_rate"`
+	Requests     int64      `json:"requests"`
+	Failures     int64      `json:"failures"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastErrorAt  *time.Time `json:"last_error_at,omitempty"`
+	LastLatency  string     `json:"last_latency,omitempty"`
+	LastProbeAt  *time.Time `json:"last_probe_at,omitempty"`
+	LastProbeErr string     `json:"last_probe_error,omitempty"`
+	Probed       bool       `json:"probed"`
+}
+
+type backend struct {
+	name    string
+	kind    string
+	probe   ProbeFunc
+	breaker *Breaker
+
+	mu           sync.Mutex
+	requests     int64
+	failures     int64
+	lastError    string
+	lastErrorAt  *time.Time
+	lastLatency  time.Duration
+	lastProbeAt  *time.Time
+	lastProbeErr string
+}
+
+// Monitor tracks the health of every AI backend and decides whether it may
+// receive traffic. It combines active probes with passive tracking of real
+// request outcomes, both feeding one circuit breaker per backend.
+type Monitor struct {
+	cfg           BreakerConfig
+	probeInterval time.Duration
+	probeTimeout  time.Duration
+
+	mu       sync.RWMutex
+	backends map[string]*backend
+}
+
+func NewMonitor(cfg BreakerConfig, probeInterval time.Duration) *Monitor {
+	if probeInterval <= 0 {
+		probeInterval = 30 * time.Second
+	}
+	return &Monitor{
+		cfg:           cfg,
+		probeInterval: probeInterval,
+		probeTimeout:  5 * time.Second,
+		backends:      map[string]*backend{},
+	}
+}
+
+// Register adds a backend. Registering a name twice keeps the first entry.
+func (m *Monitor) Register(name, kind string, probe ProbeFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.backends[name]; ok {
+		return
+	}
+	m.backends[name] = &backend{name: name, kind: kind, probe: probe, breaker: NewBreaker(m.cfg)}
+}
+
+func (m *Monitor) get(name string) *backend {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.backends[name]
+}
+
+// Allow reports whether a request may go to the backend. Unknown backends are allowed.
+func (m *Monitor) Allow(name string) bool {
+	b := m.get(name)
+	if b == nil {
+		return true
+	}
+	return b.breaker.Allow()
+}
+
+// Healthy reports whether the backend's circuit is not open, without
+// consuming a half-open trial
+func (m *Monitor) Healthy(name string) bool {
+	b := m.get(name)
+	if b == nil {
+		return true
+	}
+	return b.breaker.State() != StateOpen
+}
+
+// Record feeds a real request outcome into the backend's breaker. Caller
+// cancellations say nothing about the backend; they only release a half-open
+// trial, which would otherwise block the backend for good.
+func (m *Monitor) Record(name string, err error, latency time.Duration) {
+	b := m.get(name)
+	if b == nil {
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		b.breaker.Release()
+		return
+	}
+
+	b.mu.Lock()
+	b.requests++
+	b.lastLatency = latency
+	if err != nil {
+		now := time.Now()
+		b.failures++
+		b.lastError = err.Error()
+		b.lastErrorAt = &now
+	}
+	b.mu.Unlock()
+
+	b.breaker.Record(err != nil)
+}
+
+// Snapshot returns the status of every backend, sorted by kind and name
+func (m *Monitor) Snapshot() []Status {
+	m.mu.RLock()
+	list := make([]*backend, 0, len(m.backends))
+	for _, b := range m.backends {
+		list = append(list, b)
+	}
+	m.mu.RUnlock()
+
+	out := make([]Status, 0, len(list))
+	for _, b := range list {
+		b.mu.Lock()
+		st := Status{
+			Name:         b.name,
+			Kind:         b.kind,
+			State:        b.breaker.State(),
+			ErrorRate:    b.breaker.ErrorRate(),
+			Requests:     b.requests,
+			Failures:     b.failures,
+			LastError:    b.lastError,
+			LastErrorAt:  b.lastErrorAt,
+			LastProbeAt:  b.lastProbeAt,
+			LastProbeErr: b.lastProbeErr,
+			Probed:       b.probe != nil,
+		}
+		if b.lastLatency > 0 {
+			st.LastLatency = b.lastLatency.String()
+		}
+		b.mu.Unlock()
+		out = append(out, st)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Run probes every backend with a probe function until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	m.probeAll(ctx)
+
+	ticker := time.NewTicker(m.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.probeAll(ctx)
+		}
+	}
+}
+
+func (m *Monitor) probeAll(ctx context.Context) {
+	m.mu.RLock()
+	list := make([]*backend, 0, len(m.backends))
+	for _, b := range m.backends {
+		if b.probe != nil {
+			list = append(list, b)
+		}
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, b := range list {
+		wg.Add(1)
+		go func(b *backend) {
+			defer wg.Done()
+			m.probe(ctx, b)
+		}(b)
+	}
+	wg.Wait()
+}
+
+func (m *Monitor) probe(ctx context.Context, b *backend) {
+	pctx, cancel := context.WithTimeout(ctx, m.probeTimeout)
+	defer cancel()
+
+	err := b.probe(pctx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	b.mu.Lock()
+	previous := b.lastProbeErr
+	b.lastProbeAt = &now
+	b.lastProbeErr = ""
+	if err != nil {
+		b.lastProbeErr = err.Error()
+	}
+	b.mu.Unlock()
+
+	if err != nil && previous == "" {
+		log.Printf("health: %s %s probe failed: %v", b.kind, b.name, err)
+	} else if err == nil && previous != "" {
+		log.Printf("health: %s %s recovered", b.kind, b.name)
+	}
+
+	b.breaker.Record(err != nil)
+}
@@ -0,0 +1,283 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultComfyWorkflow is a plain txt2img graph in ComfyUI's API format.
+// Exported workflows use the same placeholders: a string that is exactly a
+// placeholder is replaced by the typed value, so "{{width}}" becomes a number.
+// Workflows with a LoraLoader can use {{lora}} and {{lora_strength}} for the
+// first LoRA of the request; reference images are not supported.
+const defaultComfyWorkflow = `{
+  "3": {"class_type": "KSampler", "inputs": {"seed": "{{seed}}", "steps": "{{steps}}", "cfg": 7, "sampler_name": "euler", "scheduler": "normal", "denoise": 1, "model": ["4", 0], "positive": ["6", 0], "negative": ["7", 0], "latent_image": ["5", 0]}},
+  "4": {"class_type": "CheckpointLoaderSimple", "inputs": {"ckpt_name": "{{checkpoint}}"}},
+  "5": {"class_type": "EmptyLatentImage", "inputs": {"width": "{{width}}", "height": "{{height}}", "batch_size": 1}},
+  "6": {"class_type": "CLIPTextEncode", "inputs": {"text": "{{prompt}}", "clip": ["4", 1]}},
+  "7": {"class_type": "CLIPTextEncode", "inputs": {"text": "{{negative_prompt}}", "clip": ["4", 1]}},
+  "8": {"class_type": "VAEDecode", "inputs": {"samples": ["3", 0], "vae": ["4", 2]}},
+  "9": {"class_type": "SaveImage", "inputs": {"filename_prefix": "3kstory", "images": ["8", 0]}}
+}`
+
+// ComfyUIConfig configures a ComfyUI server
+type ComfyUIConfig struct {
+	BaseURL      string
+	WorkflowPath string // API-format workflow JSON; empty uses the built-in txt2img graph
+	Checkpoint   string
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// ComfyUIProvider queues a workflow on ComfyUI, waits for it in the history
+// and downloads the first output image
+type ComfyUIProvider struct {
+	cfg      ComfyUIConfig
+	workflow map[string]interface{}
+	client   *http.Client
+}
+
+func NewComfyUIProvider(cfg ComfyUIConfig) (*ComfyUIProvider, error) {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 300 * time.Second
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	raw := []byte(defaultComfyWorkflow)
+	if cfg.WorkflowPath != "" {
+		var err error
+		if raw, err = os.ReadFile(cfg.WorkflowPath); err != nil {
+			return nil, fmt.Errorf("failed to read ComfyUI workflow: %w", err)
+		}
+	}
+	var workflow map[string]interface{}
+	if err := json.Unmarshal(raw, &workflow); err != nil {
+		return nil, fmt.Errorf("invalid ComfyUI workflow %s: %w", cfg.WorkflowPath, err)
+	}
+
+	// Individual HTTP calls are short; the overall wait is bounded by cfg.Timeout
+	return &ComfyUIProvider{cfg: cfg, workflow: workflow, client: newClient(60 * time.Second)}, nil
+}
+
+func (p *ComfyUIProvider) Name() string {
+	return ProviderComfyUI
+}
+
+func (p *ComfyUIProvider) Generate(ctx context.Context, req Request) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	seed := req.Seed
+	if seed == 0 {
+		seed = rand.Int63n(1 << 48)
+	}
+	lora, loraStrength := "", 0.0
+	if len(req.LoRAs) > 0 {
+		lora, loraStrength = splitLoRA(req.LoRAs[0])
+	}
+	graph := fillWorkflow(p.workflow, map[string]interface{}{
+		"prompt":          req.Prompt,
+		"negative_prompt": req.NegativePrompt,
+		"width":           req.Width,
+		"height":          req.Height,
+		"steps":           req.Steps,
+		"seed":            seed,
+		"checkpoint":      p.cfg.Checkpoint,
+		"lora":            lora,
+		"lora_strength":   loraStrength,
+	})
+
+	promptID, err := p.queue(ctx, graph)
+	if err != nil {
+		return nil, err
+	}
+	image, err := p.wait(ctx, promptID)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("filename", image.Filename)
+	q.Set("subfolder", image.Subfolder)
+	q.Set("type", image.Type)
+	data, mimeType, err := download(ctx, p.client, p.Name(), p.cfg.BaseURL+"/view?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImage(p.Name(), data, mimeType); err != nil {
+		return nil, err
+	}
+	return &Result{Data: data, MIMEType: mimeType, Seed: seed, Provider: p.Name()}, nil
+}
+
+func (p *ComfyUIProvider) queue(ctx context.Context, graph interface{}) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"prompt":    graph,
+		"client_id": fmt.Sprintf("3kstory-%d", rand.Int63()),
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.BaseURL+"/prompt", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	raw, _, err := fetch(p.client, p.Name(), req)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		PromptID   string                 `json:"prompt_id"`
+		NodeErrors map[string]interface{} `json:"node_errors"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return "", fmt.Errorf("failed to decode comfyui response: %w", err)
+	}
+	if len(resp.NodeErrors) > 0 {
+		return "", fmt.Errorf("comfyui rejected the workflow: %s", truncate(string(raw), 512))
+	}
+	if resp.PromptID == "" {
+		return "", errors.New("comfyui returned no prompt_id")
+	}
+	return resp.PromptID, nil
+}
+
+type comfyImage struct {
+	Filename  string `json:"filename"`
+	Subfolder string `json:"subfolder"`
+	Type      string `json:"type"`
+}
+
+type comfyHistory struct {
+	Outputs map[string]struct {
+		Images []comfyImage `json:"images"`
+	} `json:"outputs"`
+	Status struct {
+		StatusStr string            `json:"status_str"`
+		Completed bool              `json:"completed"`
+		Messages  []json.RawMessage `json:"messages"`
+	} `json:"status"`
+}
+
+// wait polls the history until the prompt has finished and returns its first image
+func (p *ComfyUIProvider) wait(ctx context.Context, promptID string) (*comfyImage, error) {
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.BaseURL+"/history/"+url.PathEscape(promptID), nil)
+		if err != nil {
+			return nil, err
+		}
+		raw, _, err := fetch(p.client, p.Name(), req)
+		if err != nil {
+			return nil, err
+		}
+		// The history stays empty until the prompt has run
+		var history map[string]comfyHistory
+		if err := json.Unmarshal(raw, &history); err != nil {
+			return nil, fmt.Errorf("failed to decode comfyui history: %w", err)
+		}
+		if entry, ok := history[promptID]; ok {
+			if entry.Status.StatusStr == "error" {
+				return nil, fmt.Errorf("comfyui workflow failed: %s", lastMessage(entry.Status.Messages))
+			}
+			if img := firstImage(entry); img != nil {
+				return img, nil
+			}
+			if entry.Status.Completed {
+				return nil, errors.New("comfyui workflow produced no images")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("comfyui prompt %s: %w", promptID, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// firstImage prefers saved outputs over previews, in node order
+func firstImage(entry comfyHistory) *comfyImage {
+	nodes := make([]string, 0, len(entry.Outputs))
+	for id := range entry.Outputs {
+		nodes = append(nodes, id)
+	}
+	sort.Strings(nodes)
+
+	var fallback *comfyImage
+	for _, id := range nodes {
+		for _, img := range entry.Outputs[id].Images {
+			img := img
+			if img.Type == "output" {
+				return &img
+			}
+			if fallback == nil {
+				fallback = &img
+			}
+		}
+	}
+	return fallback
+}
+
+func lastMessage(messages []json.RawMessage) string {
+	if len(messages) == 0 {
+		return "no details"
+	}
+	return truncate(string(messages[len(messages)-1]), 512)
+}
+
+func (p *ComfyUIProvider) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.BaseURL+"/system_stats", nil)
+	if err != nil {
+		return err
+	}
+	_, _, err = fetch(p.client, p.Name(), req)
+	return err
+}
+
+// fillWorkflow returns a copy of the workflow with placeholders substituted
+func fillWorkflow(v interface{}, values map[string]interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			out[k] = fillWorkflow(item, values)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			out[i] = fillWorkflow(item, values)
+		}
+		return out
+	case string:
+		if strings.HasPrefix(t, "{{") && strings.HasSuffix(t, "}}") {
+			if value, ok := values[strings.TrimSpace(t[2:len(t)-2])]; ok {
+				return value
+			}
+		}
+		for name, value := range values {
+			t = strings.ReplaceAll(t, "{{"+name+"}}", fmt.Sprint(value))
+		}
+		return t
+	default:
+		return v
+	}
+}
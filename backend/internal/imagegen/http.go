@@ -0,0 +1,152 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPProvider calls a self-hosted image service at AI_IMAGE_SERVICE_URL. The
+// service may answer with the image itself, or with JSON carrying the image
+// as base64 or as a URL, which is downloaded right away. LoRAs and base64
+// reference images are passed along as "loras" and "reference_images".
+type HTTPProvider struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewHTTPProvider(endpoint string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{endpoint: endpoint, client: newClient(timeout)}
+}
+
+func (p *HTTPProvider) Name() string {
+	return ProviderHTTP
+}
+
+type httpImageResponse struct {
+	ImageBase64 string   `json:"image_base64"`
+	B64JSON     string   `json:"b64_json"`
+	ImageURL    string   `json:"image_url"`
+	URL         string   `json:"url"`
+	Images      []string `json:"images"`
+	Seed        int64    `json:"seed"`
+	Error       string   `json:"error"`
+}
+
+func (p *HTTPProvider) Generate(ctx context.Context, req Request) (*Result, error) {
+	references := make([]string, 0, len(req.References))
+	for _, ref := range req.References {
+		references = append(references, base64.StdEncoding.EncodeToString(ref))
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"prompt":           req.Prompt,
+		"negative_prompt":  req.NegativePrompt,
+		"width":            req.Width,
+		"height":           req.Height,
+		"steps":            req.Steps,
+		"seed":             req.Seed,
+		"loras":            req.LoRAs,
+		"reference_images": references,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	raw, contentType, err := fetch(p.client, p.Name(), httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Seed: req.Seed, Provider: p.Name()}
+	if mimeType := imageType(raw, contentType); strings.HasPrefix(mimeType, "image/") {
+		result.Data, result.MIMEType = raw, mimeType
+		return result, nil
+	}
+
+	var resp httpImageResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode image service response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("image service error: %s", resp.Error)
+	}
+	if resp.Seed != 0 {
+		result.Seed = resp.Seed
+	}
+
+	ref := firstNonEmpty(resp.ImageBase64, resp.B64JSON, resp.ImageURL, resp.URL)
+	if ref == "" && len(resp.Images) > 0 {
+		ref = resp.Images[0]
+	}
+	if ref == "" {
+		return nil, errors.New("image service returned no image")
+	}
+
+	if isRemote(ref) {
+		result.Data, result.MIMEType, err = download(ctx, p.client, p.Name(), p.resolve(ref))
+	} else {
+		result.Data, err = decodeBase64Image(ref)
+		result.MIMEType = imageType(result.Data, "")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("image service: %w", err)
+	}
+	if err := checkImage(p.Name(), result.Data, result.MIMEType); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Health checks the /health endpoint next to AI_IMAGE_SERVICE_URL
+func (p *HTTPProvider) Health(ctx context.Context) error {
+	u, err := url.Parse(p.endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid AI_IMAGE_SERVICE_URL: %w", err)
+	}
+	u.Path, u.RawQuery = "/health", ""
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	_, _, err = fetch(p.client, p.Name(), req)
+	return err
+}
+
+// resolve makes a path like /outputs/1.png absolute against the service URL
+func (p *HTTPProvider) resolve(ref string) string {
+	base, err := url.Parse(p.endpoint)
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func isRemote(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "/")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+// Package imagegen generates keyframe images. Every backend returns the image
+// bytes, never a provider URL, so callers can store the result themselves.
+package imagegen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Provider names
+const (
+	ProviderSDWebUI = "sdwebui"
+	ProviderComfyUI = "comfyui"
+	ProviderHTTP    = "image_service"
+	ProviderStub    = "image_stub"
+)
+
+// Request is a provider-neutral text-to-image request
+type Request struct {
+	Prompt         string
+	NegativePrompt string
+	Width          int
+	Height         int
+	Steps          int
+	Seed           int64  // 0 lets the backend pick one
+	Title          string // shown on stub keyframes
+	// LoRAs are applied by backends that support them, as "name" or
+	// "name:weight"
+	LoRAs []string
+	// References are encoded images for IP-adapter conditioning, such as
+	// character reference sheets; backends without support ignore them
+	References [][]byte
+}
+
+// Result is a generated image
+type Result struct {
+	Data     []byte
+	MIMEType string
+	Seed     int64 // the seed actually used, when the backend reports it
+	Provider string
+}
+
+// ImageProvider is implemented by every image generation backend
+type ImageProvider interface {
+	Name() string
+	Generate(ctx context.Context, req Request) (*Result, error)
+	Health(ctx context.Context) error
+}
+
+// StatusError is returned when a backend answers with a non-2xx status
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// maxImageBytes bounds downloads from image backends
+const maxImageBytes = 32 << 20
+
+// fetch performs the request and returns the body of a 2xx response
+func fetch(client *http.Client, provider string, req *http.Request) ([]byte, string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s response: %w", provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", &StatusError{Provider: provider, StatusCode: resp.StatusCode, Body: truncate(string(body), 512)}
+	}
+	if len(body) > maxImageBytes {
+		return nil, "", fmt.Errorf("%s response exceeds %d bytes", provider, maxImageBytes)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// download fetches an image the backend left at a URL
+func download(ctx context.Context, client *http.Client, provider, url string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	data, contentType, err := fetch(client, provider, req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download image: %w", err)
+	}
+	return data, imageType(data, contentType), nil
+}
+
+// imageType trusts the bytes over the Content-Type header, which many
+// backends set to application/octet-stream
+func imageType(data []byte, header string) string {
+	if sniffed := http.DetectContentType(data); strings.HasPrefix(sniffed, "image/") {
+		return sniffed
+	}
+	return header
+}
+
+func checkImage(provider string, data []byte, mimeType string) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%s returned an empty image", provider)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return fmt.Errorf("%s returned %s instead of an image", provider, mimeType)
+	}
+	return nil
+}
+
+// splitLoRA reads a "name:weight" LoRA spec; the weight defaults to 1
+func splitLoRA(spec string) (string, float64) {
+	name, weight := strings.TrimSpace(spec), 1.0
+	if i := strings.LastIndex(name, ":"); i > 0 {
+		if w, err := strconv.ParseFloat(strings.TrimSpace(name[i+1:]), 64); err == nil {
+			name, weight = strings.TrimSpace(name[:i]), w
+		}
+	}
+	return name, weight
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func newClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
@@ -0,0 +1,173 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SDWebUIProvider talks to the Automatic1111 Stable Diffusion WebUI API,
+// which must be started with --api. LoRAs are added to the prompt as
+// <lora:name:weight> tags. Reference images go to the ControlNet extension's
+// IP-adapter when ipAdapterModel is set, and are ignored otherwise.
+type SDWebUIProvider struct {
+	baseURL        string
+	ipAdapterModel string
+	client         *http.Client
+}
+
+func NewSDWebUIProvider(baseURL, ipAdapterModel string, timeout time.Duration) *SDWebUIProvider {
+	return &SDWebUIProvider{
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		ipAdapterModel: ipAdapterModel,
+		client:         newClient(timeout),
+	}
+}
+
+func (p *SDWebUIProvider) Name() string {
+	return ProviderSDWebUI
+}
+
+// ipAdapterWeight keeps the reference's likeness without copying its pose
+const ipAdapterWeight = 0.7
+
+type sdTxt2ImgRequest struct {
+	Prompt         string `json:"prompt"`
+	NegativePrompt string `json:"negative_prompt,omitempty"`
+	Width          int    `json:"width,omitempty"`
+	Height         int    `json:"height,omitempty"`
+	Steps          int    `json:"steps,omitempty"`
+	Seed           int64  `json:"seed"`
+	BatchSize      int    `json:"batch_size"`
+
+	AlwaysOnScripts map[string]interface{} `json:"alwayson_scripts,omitempty"`
+}
+
+// sdControlNetUnit is one ControlNet unit of the sd-webui-controlnet API
+type sdControlNetUnit struct {
+	Image   string  `json:"image"`
+	Module  string  `json:"module"`
+	Model   string  `json:"model"`
+	Weight  float64 `json:"weight"`
+	Enabled bool    `json:"enabled"`
+}
+
+type sdTxt2ImgResponse struct {
+	Images []string `json:"images"`
+	// Info is itself a JSON document
+	Info string `json:"info"`
+}
+
+func (p *SDWebUIProvider) Generate(ctx context.Context, req Request) (*Result, error) {
+	seed := req.Seed
+	if seed == 0 {
+		seed = -1 // random
+	}
+	prompt := req.Prompt
+	for _, spec := range req.LoRAs {
+		if name, weight := splitLoRA(spec); name != "" {
+			prompt += fmt.Sprintf(" <lora:%s:%g>", name, weight)
+		}
+	}
+	txt2img := sdTxt2ImgRequest{
+		Prompt:         prompt,
+		NegativePrompt: req.NegativePrompt,
+		Width:          req.Width,
+		Height:         req.Height,
+		Steps:          req.Steps,
+		Seed:           seed,
+		BatchSize:      1,
+	}
+	if p.ipAdapterModel != "" && len(req.References) > 0 {
+		units := make([]sdControlNetUnit, 0, len(req.References))
+		for _, ref := range req.References {
+			units = append(units, sdControlNetUnit{
+				Image:   base64.StdEncoding.EncodeToString(ref),
+				Module:  "ip-adapter-auto",
+				Model:   p.ipAdapterModel,
+				Weight:  ipAdapterWeight,
+				Enabled: true,
+			})
+		}
+		txt2img.AlwaysOnScripts = map[string]interface{}{"controlnet": map[string]interface{}{"args": units}}
+	}
+	body, err := json.Marshal(txt2img)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/sdapi/v1/txt2img", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	raw, _, err := fetch(p.client, p.Name(), httpReq)
+	if err != nil {
+		return nil, err
+	}
+	var resp sdTxt2ImgResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode sdwebui response: %w", err)
+	}
+	if len(resp.Images) == 0 {
+		return nil, errors.New("sdwebui returned no images")
+	}
+
+	data, err := decodeBase64Image(resp.Images[0])
+	if err != nil {
+		return nil, fmt.Errorf("sdwebui: %w", err)
+	}
+	result := &Result{Data: data, MIMEType: imageType(data, ""), Provider: p.Name()}
+	if err := checkImage(p.Name(), result.Data, result.MIMEType); err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Seed int64 `json:"seed"`
+	}
+	if json.Unmarshal([]byte(resp.Info), &info) == nil {
+		result.Seed = info.Seed
+	}
+	return result, nil
+}
+
+// Health lists the installed checkpoints, which fails unless the API is up
+// and a model is available
+func (p *SDWebUIProvider) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/sdapi/v1/sd-models", nil)
+	if err != nil {
+		return err
+	}
+	raw, _, err := fetch(p.client, p.Name(), req)
+	if err != nil {
+		return err
+	}
+	var models []json.RawMessage
+	if err := json.Unmarshal(raw, &models); err != nil {
+		return fmt.Errorf("failed to decode sdwebui models: %w", err)
+	}
+	if len(models) == 0 {
+		return errors.New("sdwebui has no checkpoints installed")
+	}
+	return nil
+}
+
+// decodeBase64Image accepts plain base64 and data: URLs
+func decodeBase64Image(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "data:") {
+		if _, payload, ok := strings.Cut(s, ","); ok {
+			s = payload
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 image: %w", err)
+	}
+	return data, nil
+}
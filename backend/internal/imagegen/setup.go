@@ -0,0 +1,49 @@
+package imagegen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/config"
+)
+
+// NewFromConfig builds the image provider selected by AI_IMAGE_PROVIDER. When
+// unset, AI_IMAGE_SERVICE_URL selects the HTTP service, otherwise the stub.
+func NewFromConfig(cfg config.AIConfig) (ImageProvider, error) {
+	timeout := time.Duration(cfg.ImageTimeout) * time.Second
+
+	name := cfg.ImageProvider
+	if name == "" {
+		name = "stub"
+		if cfg.ImageServiceURL != "" {
+			name = "http"
+		}
+	}
+
+	switch name {
+	case "sdwebui":
+		if cfg.SDWebUIURL == "" {
+			return nil, fmt.Errorf("AI_IMAGE_PROVIDER=sdwebui needs SD_WEBUI_URL")
+		}
+		return NewSDWebUIProvider(cfg.SDWebUIURL, cfg.ImageIPAdapterModel, timeout), nil
+	case "comfyui":
+		if cfg.ComfyUIURL == "" {
+			return nil, fmt.Errorf("AI_IMAGE_PROVIDER=comfyui needs COMFYUI_URL")
+		}
+		return NewComfyUIProvider(ComfyUIConfig{
+			BaseURL:      cfg.ComfyUIURL,
+			WorkflowPath: cfg.ComfyUIWorkflow,
+			Checkpoint:   cfg.ComfyUICheckpoint,
+			Timeout:      timeout,
+		})
+	case "http":
+		if cfg.ImageServiceURL == "" {
+			return nil, fmt.Errorf("AI_IMAGE_PROVIDER=http needs AI_IMAGE_SERVICE_URL")
+		}
+		return NewHTTPProvider(cfg.ImageServiceURL, timeout), nil
+	case "stub":
+		return NewStubProvider(cfg.ImageStubFont), nil
+	default:
+		return nil, fmt.Errorf("unknown AI_IMAGE_PROVIDER %q (want sdwebui, comfyui, http or stub)", name)
+	}
+}
@@ -0,0 +1,181 @@
+package imagegen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// StubProvider renders a placeholder keyframe locally, for offline
+// development: a gradient seeded by the prompt, with the scene title and
+// prompt drawn on it when ffmpeg is available. Without ffmpeg the plain
+// gradient is returned.
+type StubProvider struct {
+	font string
+
+	warnOnce sync.Once
+}
+
+// NewStubProvider takes an optional font file for the ffmpeg text overlay;
+// CJK titles need a font that covers them
+func NewStubProvider(font string) *StubProvider {
+	if font == "" {
+		font = findFontFile()
+	}
+	return &StubProvider{font: font}
+}
+
+func (p *StubProvider) Name() string {
+	return ProviderStub
+}
+
+func (p *StubProvider) Health(ctx context.Context) error {
+	return nil
+}
+
+func (p *StubProvider) Generate(ctx context.Context, req Request) (*Result, error) {
+	w, h := req.Width, req.Height
+	if w <= 0 || h <= 0 {
+		w, h = 1024, 576
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gradient(w, h, req.Title+"\x00"+req.Prompt)); err != nil {
+		return nil, fmt.Errorf("failed to encode keyframe: %w", err)
+	}
+	data := buf.Bytes()
+
+	if _, err := exec.LookPath("ffmpeg"); err == nil {
+		titled, err := p.drawText(ctx, data, req, h)
+		if err == nil {
+			data = titled
+		} else {
+			p.warnOnce.Do(func() { log.Printf("image stub: ffmpeg text overlay failed, using plain keyframes: %v", err) })
+		}
+	}
+	return &Result{Data: data, MIMEType: "image/png", Seed: req.Seed, Provider: p.Name()}, nil
+}
+
+// gradient draws a diagonal two-colour gradient whose colours are derived
+// from key, so each scene gets a recognisable frame
+func gradient(w, h int, key string) *image.RGBA {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	sum := hash.Sum64()
+	from := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+	to := color.RGBA{R: uint8(sum >> 24), G: uint8(sum >> 32), B: uint8(sum >> 40), A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	span := w + h
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			t := float64(x+y) / float64(span)
+			c := color.RGBA{
+				R: lerp(from.R, to.R, t),
+				G: lerp(from.G, to.G, t),
+				B: lerp(from.B, to.B, t),
+				A: 255,
+			}
+			// Darken the lower third so the caption stays readable
+			if y > h*2/3 {
+				c.R, c.G, c.B = c.R/3, c.G/3, c.B/3
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
+
+// drawText overlays the title and the wrapped prompt with ffmpeg drawtext.
+// Text goes through files to avoid escaping it inside the filter graph.
+func (p *StubProvider) drawText(ctx context.Context, base []byte, req Request, h int) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "3kstory-keyframe-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out.png")
+	titleFile := filepath.Join(dir, "title.txt")
+	promptFile := filepath.Join(dir, "prompt.txt")
+	title := req.Title
+	if title == "" {
+		title = "Keyframe"
+	}
+	for path, content := range map[string]string{
+		in:         string(base),
+		titleFile:  title,
+		promptFile: wrap(req.Prompt, 48, 3),
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	fontOpt := ""
+	if p.font != "" {
+		fontOpt = "fontfile=" + p.font + ":"
+	}
+	captionY := h*2/3 + h/24
+	vf := fmt.Sprintf(
+		"drawtext=%stextfile=%s:fontcolor=white:fontsize=%d:x=(w-text_w)/2:y=%d,"+
+			"drawtext=%stextfile=%s:fontcolor=white@0.85:fontsize=%d:x=(w-text_w)/2:y=%d:line_spacing=8",
+		fontOpt, titleFile, h/12, captionY,
+		fontOpt, promptFile, h/30, captionY+h/12+h/40,
+	)
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-loglevel", "error", "-i", in, "-vf", vf, "-frames:v", "1", out)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return os.ReadFile(out)
+}
+
+// wrap breaks text into at most maxLines lines of width runes
+func wrap(text string, width, maxLines int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	var lines []string
+	for len(runes) > 0 && len(lines) < maxLines {
+		n := width
+		if n > len(runes) {
+			n = len(runes)
+		}
+		lines = append(lines, string(runes[:n]))
+		runes = runes[n:]
+	}
+	if len(runes) > 0 && len(lines) > 0 {
+		lines[len(lines)-1] += "…"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func findFontFile() string {
+	for _, p := range []string{
+		"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+		"/usr/share/fonts/noto-cjk/NotoSansCJK-Regular.ttc",
+		"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+		"/System/Library/Fonts/PingFang.ttc",
+		"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+		"/Library/Fonts/Arial Unicode.ttf",
+		"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	} {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
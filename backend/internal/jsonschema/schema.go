@@ -0,0 +1,278 @@
+// Package jsonschema derives a small JSON Schema subset from Go structs and
+// validates decoded JSON against it. The schema marshals to standard JSON
+// Schema, so it can also be handed to backends that constrain decoding.
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema this package understands
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+}
+
+// For derives a schema from the type of v. Field names come from json tags;
+// constraints come from a jsonschema tag, e.g.
+//
+//	Duration int `json:"duration" jsonschema:"required,minimum=1,maximum=60"`
+//
+// Supported keys are required, minLength, minItems, minimum, maximum and
+// description.
+func For(v interface{}) (*Schema, error) {
+	return forType(reflect.TypeOf(v))
+}
+
+// MustFor is For for package-level schemas; it panics on unsupported types
+func MustFor(v interface{}) *Schema {
+	s, err := For(v)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func forType(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := forType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		return &Schema{Type: "object"}, nil
+	case reflect.Struct:
+		return forStruct(t)
+	default:
+		return nil, fmt.Errorf("jsonschema: unsupported type %s", t)
+	}
+}
+
+func forStruct(t reflect.Type) (*Schema, error) {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop, err := forType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+		required, err := applyTag(prop, f.Tag.Get("jsonschema"))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+	return s, nil
+}
+
+func applyTag(s *Schema, tag string) (required bool, err error) {
+	if tag == "" {
+		return false, nil
+	}
+	for _, part := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "required":
+			required = true
+		case "description":
+			s.Description = value
+		case "minLength", "minItems":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s %q", key, value)
+			}
+			if key == "minLength" {
+				s.MinLength = &n
+			} else {
+				s.MinItems = &n
+			}
+		case "minimum", "maximum":
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s %q", key, value)
+			}
+			if key == "minimum" {
+				s.Minimum = &f
+			} else {
+				s.Maximum = &f
+			}
+		case "":
+		default:
+			return false, fmt.Errorf("unknown jsonschema tag key %q", key)
+		}
+	}
+	return required, nil
+}
+
+// ValidationError locates one schema violation
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Validate checks a value decoded by encoding/json into interface{} and
+// returns every violation, ordered by path
+func (s *Schema) Validate(v interface{}) []ValidationError {
+	var errs []ValidationError
+	s.validate("", v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]ValidationError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("expected object, got %s", typeName(v))
+			return
+		}
+		required := map[string]bool{}
+		for _, name := range s.Required {
+			required[name] = true
+			if _, ok := obj[name]; !ok {
+				*errs = append(*errs, ValidationError{Path: join(path, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, ok := obj[name]
+			// Optional properties may be null, as encoding/json writes nil slices
+			if !ok || (value == nil && !required[name]) {
+				continue
+			}
+			s.Properties[name].validate(join(path, name), value, errs)
+		}
+
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			fail("expected array, got %s", typeName(v))
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			fail("must have at least %d items, got %d", *s.MinItems, len(arr))
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("expected string, got %s", typeName(v))
+			return
+		}
+		if s.MinLength != nil && len([]rune(strings.TrimSpace(str))) < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *s.MinLength)
+			}
+		}
+
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			fail("expected %s, got %s", s.Type, typeName(v))
+			return
+		}
+		if s.Type == "integer" && n != float64(int64(n)) {
+			fail("expected integer, got %v", n)
+			return
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			fail("must be >= %v, got %v", *s.Minimum, n)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			fail("must be <= %v, got %v", *s.Maximum, n)
+		}
+
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("expected boolean, got %s", typeName(v))
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,132 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type testScene struct {
+	Title    string   `json:"title" jsonschema:"required,minLength=1"`
+	Duration int      `json:"duration" jsonschema:"required,minimum=1,maximum=60"`
+	Mood     string   `json:"mood,omitempty"`
+	Tags     []string `json:"tags" jsonschema:"minItems=1"`
+	Internal string   `json:"-"`
+}
+
+type testScript struct {
+	Title  string      `json:"title" jsonschema:"required,description=片名"`
+	Scenes []testScene `json:"scenes" jsonschema:"required,minItems=1"`
+	Score  float64     `json:"score" jsonschema:"minimum=0,maximum=1"`
+	Final  bool        `json:"final"`
+}
+
+func TestFor(t *testing.T) {
+	s, err := For(testScript{})
+	if err != nil {
+		t.Fatalf("For: %v", err)
+	}
+	if !reflect.DeepEqual(s.Required, []string{"title", "scenes"}) {
+		t.Fatalf("required = %v", s.Required)
+	}
+	if got := s.Properties["title"].Description; got != "片名" {
+		t.Fatalf("title description = %q", got)
+	}
+	scene := s.Properties["scenes"].Items
+	if scene == nil || scene.Type != "object" {
+		t.Fatalf("scenes items = %+v, want object", scene)
+	}
+	if _, ok := scene.Properties["Internal"]; ok {
+		t.Fatal(`json:"-" field was included`)
+	}
+	if _, ok := scene.Properties["mood"]; !ok {
+		t.Fatal("omitempty field name was not trimmed")
+	}
+	if d := scene.Properties["duration"]; d.Type != "integer" || *d.Minimum != 1 || *d.Maximum != 60 {
+		t.Fatalf("duration = %+v", d)
+	}
+
+	for _, v := range []interface{}{
+		struct {
+			C chan int `json:"c"`
+		}{},
+		struct {
+			A string `json:"a" jsonschema:"pattern=x"`
+		}{},
+		struct {
+			A string `json:"a" jsonschema:"minLength=x"`
+		}{},
+	} {
+		if _, err := For(v); err == nil {
+			t.Fatalf("For(%T) succeeded, want an error", v)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := MustFor(testScript{})
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "valid",
+			doc:  `{"title": "夜班", "scenes": [{"title": "开场", "duration": 5, "tags": ["夜"]}], "score": 0.5, "final": true}`,
+		},
+		{
+			name: "optional null",
+			doc:  `{"title": "夜班", "scenes": [{"title": "开场", "duration": 5, "tags": null}]}`,
+		},
+		{
+			name: "missing required",
+			doc:  `{"scenes": [{"duration": 5}]}`,
+			want: []string{"title: is required", "scenes[0].title: is required"},
+		},
+		{
+			name: "required null",
+			doc:  `{"title": null, "scenes": [{"title": "a", "duration": 5}]}`,
+			want: []string{"title: expected string, got null"},
+		},
+		{
+			name: "empty values",
+			doc:  `{"title": "x", "scenes": [{"title": "  ", "duration": 5, "tags": []}]}`,
+			want: []string{"scenes[0].tags: must have at least 1 items, got 0", "scenes[0].title: must not be empty"},
+		},
+		{
+			name: "bounds",
+			doc:  `{"title": "x", "scenes": [{"title": "a", "duration": 90}, {"title": "b", "duration": 2.5}], "score": -1}`,
+			want: []string{"scenes[0].duration: must be <= 60, got 90", "scenes[1].duration: expected integer, got 2.5", "score: must be >= 0, got -1"},
+		},
+		{
+			name: "wrong types",
+			doc:  `{"title": 1, "scenes": {}, "final": "yes"}`,
+			want: []string{"final: expected boolean, got string", "scenes: expected array, got object", "title: expected string, got number"},
+		},
+		{
+			name: "empty array",
+			doc:  `{"title": "x", "scenes": []}`,
+			want: []string{"scenes: must have at least 1 items, got 0"},
+		},
+		{
+			name: "not an object",
+			doc:  `[1]`,
+			want: []string{"expected object, got array"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(tt.doc), &v); err != nil {
+				t.Fatalf("bad doc: %v", err)
+			}
+			var got []string
+			for _, e := range s.Validate(v) {
+				got = append(got, e.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Validate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// Decoding constraint modes, from strongest to weakest
+const (
+	ConstraintJSONSchema   = "json_schema"   // response_format json_schema (OpenAI API, recent vLLM)
+	ConstraintGuidedJSON   = "guided_json"   // vLLM's guided_json extension
+	ConstraintFormatSchema = "format_schema" // Ollama >= 0.5: "format" set to the schema
+	ConstraintJSONObject   = "json_object"   // JSON mode without a schema
+	ConstraintNone         = "none"          // the schema is only described in the prompt
+)
+
+// constraintLadder remembers which decoding constraint a backend accepts.
+// It starts at the strongest mode and steps down whenever the backend
+// rejects a constraint, caching the first mode that works so later requests
+// skip the ones already known to fail.
+type constraintLadder struct {
+	provider string
+	modes    []string
+
+	mu    sync.Mutex
+	start int
+}
+
+func newConstraintLadder(provider string, modes ...string) *constraintLadder {
+	return &constraintLadder{provider: provider, modes: modes}
+}
+
+// Mode returns the strongest mode not yet rejected by the backend
+func (l *constraintLadder) Mode() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.modes[l.start]
+}
+
+// run calls the backend with the best known mode for requests that carry a
+// schema. A rejected mode is only cached once a weaker mode succeeds, so an
+// unrelated 400 (e.g. an oversized prompt) doesn't disable constraints.
+func (l *constraintLadder) run(req ChatRequest, call func(mode string) (*Response, error)) (*Response, error) {
+	if req.JSONSchema == nil {
+		return call(ConstraintNone)
+	}
+
+	l.mu.Lock()
+	i := l.start
+	l.mu.Unlock()
+
+	for {
+		mode := l.modes[i]
+		resp, err := call(mode)
+		if err == nil {
+			l.remember(i)
+			return resp, nil
+		}
+		if i == len(l.modes)-1 || !isConstraintRejection(err) {
+			return nil, err
+		}
+		log.Printf("%s rejected %s decoding constraint, trying %s: %v", l.provider, mode, l.modes[i+1], err)
+		i++
+	}
+}
+
+func (l *constraintLadder) remember(i int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i > l.start {
+		l.start = i
+	}
+}
+
+// isConstraintRejection reports whether the backend refused the request body,
+// which is how servers without a feature answer unknown parameters
+func isConstraintRejection(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.StatusCode == http.StatusBadRequest || statusErr.StatusCode == http.StatusUnprocessableEntity
+}
+
+func schemaName(req ChatRequest) string {
+	if req.SchemaName != "" {
+		return req.SchemaName
+	}
+	return "response"
+}
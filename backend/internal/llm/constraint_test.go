@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/richard9219/3kstory/internal/jsonschema"
+)
+
+func TestConstraintLadder(t *testing.T) {
+	rejected := &StatusError{Provider: "test", StatusCode: http.StatusBadRequest, Body: "unknown field"}
+	unprocessable := &StatusError{Provider: "test", StatusCode: http.StatusUnprocessableEntity}
+	unavailable := &StatusError{Provider: "test", StatusCode: http.StatusServiceUnavailable}
+
+	tests := []struct {
+		name      string
+		schema    bool
+		responses map[string]error // mode -> error returned by the backend; missing modes succeed
+		wantCalls []string
+		wantErr   error
+		wantMode  string // cached mode afterwards
+	}{
+		{
+			name:      "no schema skips constraints",
+			wantCalls: []string{ConstraintNone},
+			wantMode:  ConstraintJSONSchema,
+		},
+		{
+			name:      "strongest mode accepted",
+			schema:    true,
+			wantCalls: []string{ConstraintJSONSchema},
+			wantMode:  ConstraintJSONSchema,
+		},
+		{
+			name:      "steps down on rejection",
+			schema:    true,
+			responses: map[string]error{ConstraintJSONSchema: rejected, ConstraintGuidedJSON: unprocessable},
+			wantCalls: []string{ConstraintJSONSchema, ConstraintGuidedJSON, ConstraintJSONObject},
+			wantMode:  ConstraintJSONObject,
+		},
+		{
+			name:      "other errors are returned",
+			schema:    true,
+			responses: map[string]error{ConstraintJSONSchema: unavailable},
+			wantCalls: []string{ConstraintJSONSchema},
+			wantErr:   unavailable,
+			wantMode:  ConstraintJSONSchema,
+		},
+		{
+			name:   "rejections are not cached when every mode fails",
+			schema: true,
+			responses: map[string]error{
+				ConstraintJSONSchema: rejected,
+				ConstraintGuidedJSON: rejected,
+				ConstraintJSONObject: rejected,
+				ConstraintNone:       rejected,
+			},
+			wantCalls: []string{ConstraintJSONSchema, ConstraintGuidedJSON, ConstraintJSONObject, ConstraintNone},
+			wantErr:   rejected,
+			wantMode:  ConstraintJSONSchema,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newConstraintLadder("test", ConstraintJSONSchema, ConstraintGuidedJSON, ConstraintJSONObject, ConstraintNone)
+			req := ChatRequest{}
+			if tt.schema {
+				req.JSONSchema = &jsonschema.Schema{Type: "object"}
+			}
+			var calls []string
+			_, err := l.run(req, func(mode string) (*Response, error) {
+				calls = append(calls, mode)
+				if err := tt.responses[mode]; err != nil {
+					return nil, err
+				}
+				return &Response{Content: "{}"}, nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("run error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Fatalf("modes tried = %v, want %v", calls, tt.wantCalls)
+			}
+			if got := l.Mode(); got != tt.wantMode {
+				t.Fatalf("cached mode = %s, want %s", got, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestConstraintLadderStartsAtCachedMode(t *testing.T) {
+	l := newConstraintLadder("test", ConstraintJSONSchema, ConstraintFormatSchema, ConstraintNone)
+	req := ChatRequest{JSONSchema: &jsonschema.Schema{Type: "object"}}
+	rejected := &StatusError{Provider: "test", StatusCode: http.StatusBadRequest}
+
+	_, err := l.run(req, func(mode string) (*Response, error) {
+		if mode == ConstraintJSONSchema {
+			return nil, rejected
+		}
+		return &Response{}, nil
+	})
+	if err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+
+	var calls []string
+	_, err = l.run(req, func(mode string) (*Response, error) {
+		calls = append(calls, mode)
+		return &Response{}, nil
+	})
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if !reflect.DeepEqual(calls, []string{ConstraintFormatSchema}) {
+		t.Fatalf("second run tried %v, want only %s", calls, ConstraintFormatSchema)
+	}
+}
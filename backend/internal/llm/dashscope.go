@@ -0,0 +1,214 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DashScopeConfig configures Alibaba Cloud DashScope (cloud Qwen)
+type DashScopeConfig struct {
+	Name            string
+	BaseURL         string
+	APIKey          string
+	Model           string
+	MaxTokens       int
+	Timeout         time.Duration
+	CostPer1KTokens float64
+}
+
+// DashScopeProvider talks to the DashScope text-generation API
+type DashScopeProvider struct {
+	cfg         DashScopeConfig
+	client      *http.Client
+	constraints *constraintLadder
+}
+
+func NewDashScopeProvider(cfg DashScopeConfig) *DashScopeProvider {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Name == "" {
+		cfg.Name = "dashscope"
+	}
+	if cfg.Model == "" {
+		cfg.Model = "qwen-plus"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	return &DashScopeProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		// DashScope offers JSON mode but no schema-constrained decoding
+		constraints: newConstraintLadder(cfg.Name, ConstraintJSONObject, ConstraintNone),
+	}
+}
+
+func (p *DashScopeProvider) Info() ModelInfo {
+	return ModelInfo{
+		Provider:        p.cfg.Name,
+		Model:           p.cfg.Model,
+		MaxTokens:       p.cfg.MaxTokens,
+		CostPer1KTokens: p.cfg.CostPer1KTokens,
+	}
+}
+
+type dashScopeResponse struct {
+	Output struct {
+		Text    string `json:"text"`
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+	} `json:"output"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (r *dashScopeResponse) text() string {
+	if len(r.Output.Choices) > 0 {
+		return r.Output.Choices[0].Message.Content
+	}
+	return r.Output.Text
+}
+
+func (p *DashScopeProvider) parameters(temperature float64, maxTokens int, stream bool, mode string) map[string]interface{} {
+	if maxTokens <= 0 {
+		maxTokens = p.cfg.MaxTokens
+	}
+	params := map[string]interface{}{
+		"temperature":   temperature,
+		"result_format": "message",
+	}
+	if maxTokens > 0 {
+		params["max_tokens"] = maxTokens
+	}
+	if stream {
+		params["incremental_output"] = true
+	}
+	if mode == ConstraintJSONObject {
+		params["response_format"] = map[string]interface{}{"type": "json_object"}
+	}
+	return params
+}
+
+func (p *DashScopeProvider) Chat(ctx context.Context, req ChatRequest) (*Response, error) {
+	return p.constraints.run(req, func(mode string) (*Response, error) {
+		return p.generate(ctx, map[string]interface{}{
+			"model":      p.cfg.Model,
+			"input":      map[string]interface{}{"messages": req.Messages},
+			"parameters": p.parameters(req.Temperature, req.MaxTokens, false, mode),
+		})
+	})
+}
+
+func (p *DashScopeProvider) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
+	return p.generate(ctx, map[string]interface{}{
+		"model":      p.cfg.Model,
+		"input":      map[string]interface{}{"prompt": req.Prompt},
+		"parameters": p.parameters(req.Temperature, req.MaxTokens, false, ConstraintNone),
+	})
+}
+
+func (p *DashScopeProvider) generate(ctx context.Context, body map[string]interface{}) (*Response, error) {
+	httpReq, err := p.newRequest(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp dashScopeResponse
+	if err := doJSON(p.client, p.cfg.Name, httpReq, &apiResp); err != nil {
+		return nil, err
+	}
+	if apiResp.Code != "" {
+		return nil, fmt.Errorf("%s error %s: %s", p.cfg.Name, apiResp.Code, apiResp.Message)
+	}
+	return p.response(apiResp.text(), &apiResp), nil
+}
+
+// Stream enables DashScope SSE with incremental output, so each event
+// carries only the new text
+func (p *DashScopeProvider) Stream(ctx context.Context, req ChatRequest, onDelta DeltaFunc) (*Response, error) {
+	return p.constraints.run(req, func(mode string) (*Response, error) {
+		return p.stream(ctx, req, mode, onDelta)
+	})
+}
+
+func (p *DashScopeProvider) stream(ctx context.Context, req ChatRequest, mode string, onDelta DeltaFunc) (*Response, error) {
+	httpReq, err := p.newRequest(ctx, map[string]interface{}{
+		"model":      p.cfg.Model,
+		"input":      map[string]interface{}{"messages": req.Messages},
+		"parameters": p.parameters(req.Temperature, req.MaxTokens, true, mode),
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("X-DashScope-SSE", "enable")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	body, err := openStream(&http.Client{}, p.cfg.Name, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var content strings.Builder
+	var last dashScopeResponse
+	err = readSSE(body, func(data string) (bool, error) {
+		var chunk dashScopeResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return false, fmt.Errorf("failed to decode %s stream chunk: %w", p.cfg.Name, err)
+		}
+		if chunk.Code != "" {
+			return false, fmt.Errorf("%s error %s: %s", p.cfg.Name, chunk.Code, chunk.Message)
+		}
+		last = chunk
+		if delta := chunk.text(); delta != "" {
+			content.WriteString(delta)
+			return false, onDelta(delta)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.response(content.String(), &last), nil
+}
+
+// Health only checks configuration: DashScope has no free endpoint to probe,
+// and a real generation call would cost money on every check
+func (p *DashScopeProvider) Health(ctx context.Context) error {
+	if p.cfg.BaseURL == "" {
+		return errors.New("QWEN_API_BASE is not configured")
+	}
+	if p.cfg.APIKey == "" {
+		return errors.New("QWEN_API_KEY is not configured")
+	}
+	return nil
+}
+
+func (p *DashScopeProvider) newRequest(ctx context.Context, body interface{}) (*http.Request, error) {
+	req, err := newJSONRequest(ctx, http.MethodPost, p.cfg.BaseURL+"/services/aigc/text-generation/generation", body)
+	if err != nil {
+		return nil, err
+	}
+	if p.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+	return req, nil
+}
+
+func (p *DashScopeProvider) response(content string, r *dashScopeResponse) *Response {
+	return &Response{
+		Content:  content,
+		Provider: p.cfg.Name,
+		Model:    p.cfg.Model,
+		Usage:    Usage{PromptTokens: r.Usage.InputTokens, CompletionTokens: r.Usage.OutputTokens},
+	}
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoProviders is returned when routing has no candidate to try
+var ErrNoProviders = errors.New("no text providers available")
+
+// ErrCircuitOpen is recorded for providers skipped because their breaker is open
+var ErrCircuitOpen = errors.New("circuit open, provider skipped")
+
+// ProviderError attributes a failure to the backend that produced it
+type ProviderError struct {
+	Provider string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// FailoverError collects every failure from a failover chain, in the order
+// the providers were tried
+type FailoverError struct {
+	Errors []*ProviderError
+}
+
+func (e *FailoverError) Error() string {
+	if len(e.Errors) == 0 {
+		return ErrNoProviders.Error()
+	}
+	parts := make([]string, len(e.Errors))
+	for i, pe := range e.Errors {
+		parts[i] = pe.Error()
+	}
+	return fmt.Sprintf("all %d text providers failed: %s", len(e.Errors), strings.Join(parts, "; "))
+}
+
+// Unwrap exposes the individual failures to errors.Is and errors.As
+func (e *FailoverError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, pe := range e.Errors {
+		errs[i] = pe
+	}
+	return errs
+}
+
+func (e *FailoverError) add(provider string, err error) {
+	e.Errors = append(e.Errors, &ProviderError{Provider: provider, Err: err})
+}
@@ -0,0 +1,262 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrNoJSON is returned when model output contains no JSON object or array
+var ErrNoJSON = errors.New("no JSON object or array found in model output")
+
+// ExtractJSON pulls the JSON document out of model output that wraps it in a
+// markdown fence or surrounds it with prose. The first balanced object or
+// array is returned; an unterminated one is returned up to the end of the
+// text so RepairJSON can close it.
+func ExtractJSON(raw string) (string, error) {
+	text := raw
+	if fenced, ok := fencedBlock(raw); ok {
+		text = fenced
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return "", ErrNoJSON
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return text[start : i+1], nil
+			}
+		}
+	}
+	return strings.TrimSpace(text[start:]), nil
+}
+
+// fencedBlock returns the body of the first ``` fence that contains JSON
+func fencedBlock(raw string) (string, bool) {
+	rest := raw
+	for {
+		open := strings.Index(rest, "```")
+		if open < 0 {
+			return "", false
+		}
+		body := rest[open+3:]
+		// Skip the info string, e.g. ```json
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 && !strings.ContainsAny(body[:nl], "{[") {
+			body = body[nl+1:]
+		}
+		end := strings.Index(body, "```")
+		if end < 0 {
+			// Unterminated fence: the model stopped mid-block
+			end = len(body)
+		}
+		if strings.ContainsAny(body[:end], "{[") {
+			return body[:end], true
+		}
+		if end == len(body) {
+			return "", false
+		}
+		rest = body[end+3:]
+	}
+}
+
+// RepairJSON fixes the syntax errors local models commonly make: trailing
+// commas, missing commas between values, comments, single-quoted strings,
+// raw newlines inside strings, Python literals and truncated output with
+// unclosed strings, objects or arrays.
+func RepairJSON(s string) string {
+	var out strings.Builder
+	out.Grow(len(s) + 16)
+
+	var stack []byte
+	inString, escaped := false, false
+	quote := byte('"')
+	valueEnded := false
+
+	// trimTrailingComma drops a comma (and the whitespace around it) at the end of the output
+	trimTrailingComma := func() {
+		str := strings.TrimRight(out.String(), " \t\r\n")
+		if strings.HasSuffix(str, ",") {
+			str = strings.TrimRight(str[:len(str)-1], " \t\r\n")
+			out.Reset()
+			out.WriteString(str)
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				// \' is not a JSON escape, so it becomes a bare quote
+				if c != '\'' {
+					out.WriteByte('\\')
+				}
+				out.WriteByte(c)
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				inString = false
+				valueEnded = true
+				out.WriteByte('"')
+			case c == '"':
+				// A double quote inside a single-quoted string
+				out.WriteString(`\"`)
+			case c == '\n':
+				out.WriteString(`\n`)
+			case c == '\r':
+				out.WriteString(`\r`)
+			case c == '\t':
+				out.WriteString(`\t`)
+			default:
+				out.WriteByte(c)
+			}
+			continue
+		}
+
+		switch {
+		case c == '/' && i+1 < len(s) && s[i+1] == '/':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				i = len(s)
+			} else {
+				i += end + 3
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			if valueEnded {
+				out.WriteByte(',')
+			}
+			inString = true
+			quote = c
+			out.WriteByte('"')
+		case '{', '[':
+			if valueEnded {
+				out.WriteByte(',')
+			}
+			stack = append(stack, c)
+			valueEnded = false
+			out.WriteByte(c)
+		case '}', ']':
+			trimTrailingComma()
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			valueEnded = true
+			out.WriteByte(c)
+		case ',', ':':
+			valueEnded = false
+			out.WriteByte(c)
+		case ' ', '\t', '\r', '\n':
+			out.WriteByte(c)
+		default:
+			if word, literal, ok := pythonLiteral(s[i:]); ok {
+				if valueEnded {
+					out.WriteByte(',')
+				}
+				out.WriteString(literal)
+				i += len(word) - 1
+				valueEnded = true
+				continue
+			}
+			// Numbers and true/false/null: a new token after a finished value needs a comma
+			if valueEnded && !isTokenContinuation(out.String()) {
+				out.WriteByte(',')
+			}
+			out.WriteByte(c)
+			valueEnded = true
+		}
+	}
+
+	// Close whatever the model left open when it stopped
+	if inString {
+		if escaped {
+			out.WriteString(`\\`)
+		}
+		out.WriteByte('"')
+	}
+	trimTrailingComma()
+	if str := strings.TrimRight(out.String(), " \t\r\n"); strings.HasSuffix(str, ":") {
+		out.WriteString("null")
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			out.WriteByte('}')
+		} else {
+			out.WriteByte(']')
+		}
+	}
+	return out.String()
+}
+
+// isTokenContinuation reports whether the output ends inside a bare token
+// (a number or literal), in which case the next character continues it
+func isTokenContinuation(out string) bool {
+	if out == "" {
+		return false
+	}
+	last := out[len(out)-1]
+	return last != ' ' && last != '\t' && last != '\r' && last != '\n' &&
+		last != '"' && last != '}' && last != ']'
+}
+
+func pythonLiteral(s string) (word, literal string, ok bool) {
+	for _, w := range [][2]string{{"True", "true"}, {"False", "false"}, {"None", "null"}} {
+		if strings.HasPrefix(s, w[0]) && (len(s) == len(w[0]) || !isIdentByte(s[len(w[0])])) {
+			return w[0], w[1], true
+		}
+	}
+	return "", "", false
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// DecodeJSON extracts JSON from model output and decodes it into v, repairing
+// the syntax only when the document does not parse as-is
+func DecodeJSON(raw string, v interface{}) error {
+	doc, err := ExtractJSON(raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(doc), v); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(RepairJSON(doc)), v); err != nil {
+		return err
+	}
+	return nil
+}
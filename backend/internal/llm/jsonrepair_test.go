@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+		err  error
+	}{
+		{name: "bare object", raw: `{"a": 1}`, want: `{"a": 1}`},
+		{name: "prose around", raw: `Here you go: {"a": 1} Enjoy!`, want: `{"a": 1}`},
+		{name: "fenced", raw: "```json\n{\"a\": [1, 2]}\n```", want: `{"a": [1, 2]}`},
+		{name: "fence without json first", raw: "```\nnotes\n```\n```json\n[1]\n```", want: `[1]`},
+		{name: "unterminated fence", raw: "```json\n{\"a\": 1", want: `{"a": 1`},
+		{name: "braces inside strings", raw: `{"a": "}{", "b": "\"}"} trailing`, want: `{"a": "}{", "b": "\"}"}`},
+		{name: "array", raw: `result: [{"a": 1}, {"b": 2}]`, want: `[{"a": 1}, {"b": 2}]`},
+		{name: "truncated", raw: `{"a": [1, 2`, want: `{"a": [1, 2`},
+		{name: "no json", raw: "sorry, I can't help", err: ErrNoJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractJSON(tt.raw)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ExtractJSON(%q) error = %v, want %v", tt.raw, err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("ExtractJSON(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // compared after decoding
+	}{
+		{name: "valid", in: `{"a": 1, "b": "x"}`, want: `{"a": 1, "b": "x"}`},
+		{name: "trailing commas", in: `{"a": [1, 2,], "b": 3,}`, want: `{"a": [1, 2], "b": 3}`},
+		{name: "missing commas", in: "{\"a\": 1\n\"b\": [1 2]\n\"c\": {\"d\": true} \"e\": null}", want: `{"a": 1, "b": [1, 2], "c": {"d": true}, "e": null}`},
+		{name: "comments", in: "{\n// note\n\"a\": 1, /* block */ \"b\": 2}", want: `{"a": 1, "b": 2}`},
+		{name: "single quotes", in: `{'a': 'b'}`, want: `{"a": "b"}`},
+		{name: "escaped single quote", in: `{'a': 'it\'s'}`, want: `{"a": "it's"}`},
+		{name: "escaped single quote in double quotes", in: `{"a": "it\'s"}`, want: `{"a": "it's"}`},
+		{name: "double quote in single quotes", in: `{'a': 'say "hi"'}`, want: `{"a": "say \"hi\""}`},
+		{name: "json escapes kept", in: `{'a': 'line\nbreak \"q\" \\ é'}`, want: `{"a": "line\nbreak \"q\" \\ é"}`},
+		{name: "raw newline in string", in: "{\"a\": \"one\ntwo\tthree\"}", want: `{"a": "one\ntwo\tthree"}`},
+		{name: "python literals", in: `{"a": True, "b": False, "c": None}`, want: `{"a": true, "b": false, "c": null}`},
+		{name: "literal prefix in word", in: `{"a": Truely}`, want: ""},
+		{name: "unclosed string", in: `{"a": "unfinished`, want: `{"a": "unfinished"}`},
+		{name: "unclosed after escape", in: `{"a": "back\`, want: `{"a": "back\\"}`},
+		{name: "dangling key", in: `{"a": 1, "b":`, want: `{"a": 1, "b": null}`},
+		{name: "dangling comma", in: `{"scenes": [{"a": 1},`, want: `{"scenes": [{"a": 1}]}`},
+		{name: "nested truncation", in: `{"a": {"b": [1, {"c": "d`, want: `{"a": {"b": [1, {"c": "d"}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RepairJSON(tt.in)
+			var gotValue interface{}
+			err := json.Unmarshal([]byte(got), &gotValue)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("RepairJSON(%q) = %s, want invalid JSON", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RepairJSON(%q) = %s, not valid JSON: %v", tt.in, got, err)
+			}
+			var wantValue interface{}
+			if err := json.Unmarshal([]byte(tt.want), &wantValue); err != nil {
+				t.Fatalf("bad want %s: %v", tt.want, err)
+			}
+			if !reflect.DeepEqual(gotValue, wantValue) {
+				t.Fatalf("RepairJSON(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var v struct {
+		Title  string   `json:"title"`
+		Scenes []string `json:"scenes"`
+	}
+	raw := "好的，以下是脚本：\n```json\n{'title': '夜班', 'scenes': ['开场', '结尾',],}\n```"
+	if err := DecodeJSON(raw, &v); err != nil {
+		t.Fatalf("DecodeJSON: %v", err)
+	}
+	if v.Title != "夜班" || !reflect.DeepEqual(v.Scenes, []string{"开场", "结尾"}) {
+		t.Fatalf("DecodeJSON = %+v", v)
+	}
+	if err := DecodeJSON("no json here", &v); !errors.Is(err, ErrNoJSON) {
+		t.Fatalf("DecodeJSON without JSON error = %v, want ErrNoJSON", err)
+	}
+}
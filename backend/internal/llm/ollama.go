@@ -0,0 +1,199 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaConfig configures an Ollama server
+type OllamaConfig struct {
+	Name      string
+	BaseURL   string
+	Model     string
+	MaxTokens int
+	Timeout   time.Duration
+}
+
+// OllamaProvider talks to Ollama's native /api/chat and /api/generate endpoints
+type OllamaProvider struct {
+	cfg         OllamaConfig
+	client      *http.Client
+	constraints *constraintLadder
+}
+
+func NewOllamaProvider(cfg OllamaConfig) *OllamaProvider {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Name == "" {
+		cfg.Name = "ollama"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	return &OllamaProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		// Schemas in "format" need Ollama 0.5+; older servers only know "json"
+		constraints: newConstraintLadder(cfg.Name, ConstraintFormatSchema, ConstraintJSONObject, ConstraintNone),
+	}
+}
+
+func (p *OllamaProvider) Info() ModelInfo {
+	return ModelInfo{
+		Provider:  p.cfg.Name,
+		Model:     p.cfg.Model,
+		MaxTokens: p.cfg.MaxTokens,
+		Local:     true,
+	}
+}
+
+type ollamaChatChunk struct {
+	Model   string  `json:"model"`
+	Message Message `json:"message"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error"`
+
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+func (p *OllamaProvider) chatBody(req ChatRequest, stream bool, mode string) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":    p.cfg.Model,
+		"messages": req.Messages,
+		"stream":   stream,
+		"options":  p.options(req.Temperature, req.MaxTokens),
+	}
+	switch mode {
+	case ConstraintFormatSchema:
+		body["format"] = req.JSONSchema
+	case ConstraintJSONObject:
+		body["format"] = "json"
+	}
+	return body
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, req ChatRequest) (*Response, error) {
+	return p.constraints.run(req, func(mode string) (*Response, error) {
+		return p.chat(ctx, req, mode)
+	})
+}
+
+func (p *OllamaProvider) chat(ctx context.Context, req ChatRequest, mode string) (*Response, error) {
+	httpReq, err := newJSONRequest(ctx, http.MethodPost, p.cfg.BaseURL+"/api/chat", p.chatBody(req, false, mode))
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp ollamaChatChunk
+	if err := doJSON(p.client, p.cfg.Name, httpReq, &apiResp); err != nil {
+		return nil, err
+	}
+	if apiResp.Error != "" {
+		return nil, fmt.Errorf("%s error: %s", p.cfg.Name, apiResp.Error)
+	}
+	return p.response(apiResp.Message.Content, apiResp), nil
+}
+
+func (p *OllamaProvider) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
+	httpReq, err := newJSONRequest(ctx, http.MethodPost, p.cfg.BaseURL+"/api/generate", map[string]interface{}{
+		"model":   p.cfg.Model,
+		"prompt":  req.Prompt,
+		"stream":  false,
+		"options": p.options(req.Temperature, req.MaxTokens),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp struct {
+		ollamaChatChunk
+		Response string `json:"response"`
+	}
+	if err := doJSON(p.client, p.cfg.Name, httpReq, &apiResp); err != nil {
+		return nil, err
+	}
+	if apiResp.Error != "" {
+		return nil, fmt.Errorf("%s error: %s", p.cfg.Name, apiResp.Error)
+	}
+	return p.response(apiResp.Response, apiResp.ollamaChatChunk), nil
+}
+
+// Stream reads Ollama's newline-delimited JSON chat stream
+func (p *OllamaProvider) Stream(ctx context.Context, req ChatRequest, onDelta DeltaFunc) (*Response, error) {
+	return p.constraints.run(req, func(mode string) (*Response, error) {
+		return p.stream(ctx, req, mode, onDelta)
+	})
+}
+
+func (p *OllamaProvider) stream(ctx context.Context, req ChatRequest, mode string, onDelta DeltaFunc) (*Response, error) {
+	httpReq, err := newJSONRequest(ctx, http.MethodPost, p.cfg.BaseURL+"/api/chat", p.chatBody(req, true, mode))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := openStream(&http.Client{}, p.cfg.Name, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var content strings.Builder
+	var last ollamaChatChunk
+	err = readNDJSON(body, func(line []byte) (bool, error) {
+		var chunk ollamaChatChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return false, fmt.Errorf("failed to decode %s stream chunk: %w", p.cfg.Name, err)
+		}
+		if chunk.Error != "" {
+			return false, fmt.Errorf("%s error: %s", p.cfg.Name, chunk.Error)
+		}
+		last = chunk
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if err := onDelta(chunk.Message.Content); err != nil {
+				return false, err
+			}
+		}
+		return chunk.Done, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.response(content.String(), last), nil
+}
+
+// Health lists the local models
+func (p *OllamaProvider) Health(ctx context.Context) error {
+	httpReq, err := newJSONRequest(ctx, http.MethodGet, p.cfg.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+	return doJSON(p.client, p.cfg.Name, httpReq, nil)
+}
+
+func (p *OllamaProvider) options(temperature float64, maxTokens int) map[string]interface{} {
+	if maxTokens <= 0 {
+		maxTokens = p.cfg.MaxTokens
+	}
+	return map[string]interface{}{
+		"num_predict": maxTokens,
+		"temperature": temperature,
+	}
+}
+
+func (p *OllamaProvider) response(content string, chunk ollamaChatChunk) *Response {
+	model := chunk.Model
+	if model == "" {
+		model = p.cfg.Model
+	}
+	return &Response{
+		Content:  content,
+		Provider: p.cfg.Name,
+		Model:    model,
+		Usage:    Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount},
+	}
+}
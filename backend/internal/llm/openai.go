@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIConfig configures a server speaking the OpenAI chat API, such as
+// vLLM, LM Studio or the llama.cpp server
+type OpenAIConfig struct {
+	Name            string
+	BaseURL         string
+	APIKey          string
+	Model           string
+	MaxTokens       int
+	Timeout         time.Duration
+	CostPer1KTokens float64
+	Local           bool
+}
+
+// OpenAIProvider talks to an OpenAI-compatible /v1 API
+type OpenAIProvider struct {
+	cfg         OpenAIConfig
+	client      *http.Client
+	constraints *constraintLadder
+}
+
+func NewOpenAIProvider(cfg OpenAIConfig) *OpenAIProvider {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	return &OpenAIProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		// vLLM understands both schema forms; other servers reject or ignore guided_json
+		constraints: newConstraintLadder(cfg.Name, ConstraintJSONSchema, ConstraintGuidedJSON, ConstraintJSONObject, ConstraintNone),
+	}
+}
+
+func (p *OpenAIProvider) Info() ModelInfo {
+	return ModelInfo{
+		Provider:        p.cfg.Name,
+		Model:           p.cfg.Model,
+		MaxTokens:       p.cfg.MaxTokens,
+		CostPer1KTokens: p.cfg.CostPer1KTokens,
+		Local:           p.cfg.Local,
+	}
+}
+
+func (p *OpenAIProvider) chatBody(req ChatRequest, stream bool, mode string) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":       p.cfg.Model,
+		"messages":    req.Messages,
+		"temperature": req.Temperature,
+		"max_tokens":  p.maxTokens(req.MaxTokens),
+		"stream":      stream,
+	}
+	switch mode {
+	case ConstraintJSONSchema:
+		body["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   schemaName(req),
+				"schema": req.JSONSchema,
+			},
+		}
+	case ConstraintGuidedJSON:
+		body["guided_json"] = req.JSONSchema
+	case ConstraintJSONObject:
+		body["response_format"] = map[string]interface{}{"type": "json_object"}
+	}
+	return body
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*Response, error) {
+	return p.constraints.run(req, func(mode string) (*Response, error) {
+		return p.chat(ctx, req, mode)
+	})
+}
+
+func (p *OpenAIProvider) chat(ctx context.Context, req ChatRequest, mode string) (*Response, error) {
+	httpReq, err := p.newRequest(ctx, "/v1/chat/completions", p.chatBody(req, false, mode))
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+	}
+	if err := doJSON(p.client, p.cfg.Name, httpReq, &apiResp); err != nil {
+		return nil, err
+	}
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("%s returned no choices", p.cfg.Name)
+	}
+	return p.response(apiResp.Choices[0].Message.Content, apiResp.Model, apiResp.Usage), nil
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
+	httpReq, err := p.newRequest(ctx, "/v1/completions", map[string]interface{}{
+		"model":       p.cfg.Model,
+		"prompt":      req.Prompt,
+		"temperature": req.Temperature,
+		"max_tokens":  p.maxTokens(req.MaxTokens),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Text string `json:"text"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+	}
+	if err := doJSON(p.client, p.cfg.Name, httpReq, &apiResp); err != nil {
+		return nil, err
+	}
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("%s returned no choices", p.cfg.Name)
+	}
+	return p.response(apiResp.Choices[0].Text, apiResp.Model, apiResp.Usage), nil
+}
+
+// Stream uses "stream": true, which these servers answer with SSE chunks
+// terminated by "data: [DONE]"
+func (p *OpenAIProvider) Stream(ctx context.Context, req ChatRequest, onDelta DeltaFunc) (*Response, error) {
+	return p.constraints.run(req, func(mode string) (*Response, error) {
+		return p.stream(ctx, req, mode, onDelta)
+	})
+}
+
+func (p *OpenAIProvider) stream(ctx context.Context, req ChatRequest, mode string, onDelta DeltaFunc) (*Response, error) {
+	httpReq, err := p.newRequest(ctx, "/v1/chat/completions", p.chatBody(req, true, mode))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	// Streams can legitimately outlive the request timeout; rely on ctx instead
+	body, err := openStream(&http.Client{}, p.cfg.Name, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var content strings.Builder
+	var model string
+	err = readSSE(body, func(data string) (bool, error) {
+		if data == "[DONE]" {
+			return true, nil
+		}
+		var chunk struct {
+			Model   string `json:"model"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return false, fmt.Errorf("failed to decode %s stream chunk: %w", p.cfg.Name, err)
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return false, nil
+		}
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		return false, onDelta(delta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.response(content.String(), model, Usage{}), nil
+}
+
+// Health lists the served models, which fails fast when the server is down
+func (p *OpenAIProvider) Health(ctx context.Context) error {
+	httpReq, err := newJSONRequest(ctx, http.MethodGet, p.cfg.BaseURL+"/v1/models", nil)
+	if err != nil {
+		return err
+	}
+	p.authorize(httpReq)
+	return doJSON(p.client, p.cfg.Name, httpReq, nil)
+}
+
+func (p *OpenAIProvider) newRequest(ctx context.Context, path string, body interface{}) (*http.Request, error) {
+	req, err := newJSONRequest(ctx, http.MethodPost, p.cfg.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	p.authorize(req)
+	return req, nil
+}
+
+func (p *OpenAIProvider) authorize(req *http.Request) {
+	if p.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+}
+
+func (p *OpenAIProvider) maxTokens(requested int) int {
+	if requested > 0 {
+		return requested
+	}
+	return p.cfg.MaxTokens
+}
+
+func (p *OpenAIProvider) response(content, model string, usage Usage) *Response {
+	if model == "" {
+		model = p.cfg.Model
+	}
+	return &Response{Content: content, Provider: p.cfg.Name, Model: model, Usage: usage}
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/richard9219/3kstory/internal/jsonschema"
+)
+
+// Message is one chat turn
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest is a provider-neutral chat completion request
+type ChatRequest struct {
+	Messages    []Message
+	Temperature float64
+	MaxTokens   int // 0 uses the provider's configured default
+
+	// JSONSchema asks backends that support constrained decoding to only
+	// produce output matching the schema; others fall back to the prompt alone
+	JSONSchema *jsonschema.Schema
+	SchemaName string
+}
+
+// CompletionRequest is a provider-neutral raw prompt completion request
+type CompletionRequest struct {
+	Prompt      string
+	Temperature float64
+	MaxTokens   int
+}
+
+// Usage reports token accounting when the backend returns it
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Response is the text a provider produced
+type Response struct {
+	Content  string
+	Provider string
+	Model    string
+	Usage    Usage
+}
+
+// ModelInfo describes the model behind a provider
+type ModelInfo struct {
+	Provider        string  `json:"provider"`
+	Model           string  `json:"model"`
+	MaxTokens       int     `json:"max_tokens"`
+	CostPer1KTokens float64 `json:"cost_per_1k_tokens"`
+	Local           bool    `json:"local"`
+}
+
+// DeltaFunc receives streamed text as it arrives. Returning an error aborts the stream.
+type DeltaFunc func(delta string) error
+
+// TextProvider is implemented by every text generation backend
+type TextProvider interface {
+	Chat(ctx context.Context, req ChatRequest) (*Response, error)
+	Complete(ctx context.Context, req CompletionRequest) (*Response, error)
+	// Stream runs a chat request, calling onDelta for each piece of text, and
+	// returns the full response once the backend finishes
+	Stream(ctx context.Context, req ChatRequest, onDelta DeltaFunc) (*Response, error)
+	Health(ctx context.Context) error
+	Info() ModelInfo
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry holds the configured text providers by name
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]TextProvider
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: map[string]TextProvider{}}
+}
+
+// Register adds or replaces a provider
+func (r *Registry) Register(name string, p TextProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = p
+}
+
+// Get looks a provider up by name
+func (r *Registry) Get(name string) (TextProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("text provider %q is not registered", name)
+	}
+	return p, nil
+}
+
+// Names lists the registered providers in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
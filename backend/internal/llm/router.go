@@ -0,0 +1,247 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Routing strategies
+const (
+	StrategyPriority = "priority" // try candidates in the configured order
+	StrategyWeighted = "weighted" // weighted random order, without repeats
+	StrategyCheapest = "cheapest" // healthy candidates, cheapest first
+)
+
+// Gate decides whether a provider may receive traffic and learns from the
+// outcome of each call. health.Monitor implements it.
+type Gate interface {
+	Allow(name string) bool
+	Healthy(name string) bool
+	Record(name string, err error, latency time.Duration)
+}
+
+// Router picks providers for a request and fails over between them
+type Router struct {
+	registry      *Registry
+	strategy      string
+	candidates    []string
+	weights       map[string]int
+	healthTimeout time.Duration
+	gate          Gate
+}
+
+func NewRouter(registry *Registry, strategy string, candidates []string, weights map[string]int) *Router {
+	strategy = strings.ToLower(strings.TrimSpace(strategy))
+	if strategy == "" {
+		strategy = StrategyPriority
+	}
+	return &Router{
+		registry:      registry,
+		strategy:      strategy,
+		candidates:    candidates,
+		weights:       weights,
+		healthTimeout: 3 * time.Second,
+	}
+}
+
+// SetGate makes routing skip providers whose circuit is open
+func (r *Router) SetGate(g Gate) {
+	r.gate = g
+}
+
+// Strategy returns the configured routing strategy
+func (r *Router) Strategy() string {
+	return r.strategy
+}
+
+// Candidates returns the provider names the router may use
+func (r *Router) Candidates() []string {
+	return append([]string(nil), r.candidates...)
+}
+
+// Chat runs the request against providers in routing order until one succeeds
+func (r *Router) Chat(ctx context.Context, req ChatRequest) (*Response, error) {
+	return r.do(ctx, func(p TextProvider) (*Response, error) {
+		return p.Chat(ctx, req)
+	})
+}
+
+// Complete runs a completion against providers in routing order until one succeeds
+func (r *Router) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
+	return r.do(ctx, func(p TextProvider) (*Response, error) {
+		return p.Complete(ctx, req)
+	})
+}
+
+// Stream streams from the first provider that succeeds. A provider that fails
+// after emitting text is not retried elsewhere, since the caller has already
+// consumed part of its output.
+func (r *Router) Stream(ctx context.Context, req ChatRequest, onDelta DeltaFunc) (*Response, error) {
+	var emitted bool
+	return r.doUntil(ctx, func(p TextProvider) (*Response, error) {
+		return p.Stream(ctx, req, func(delta string) error {
+			emitted = true
+			return onDelta(delta)
+		})
+	}, func() bool { return emitted })
+}
+
+func (r *Router) do(ctx context.Context, call func(TextProvider) (*Response, error)) (*Response, error) {
+	return r.doUntil(ctx, call, func() bool { return false })
+}
+
+func (r *Router) doUntil(ctx context.Context, call func(TextProvider) (*Response, error), stop func() bool) (*Response, error) {
+	order := r.order(ctx)
+	failures := &FailoverError{}
+
+	for _, name := range order {
+		if err := ctx.Err(); err != nil {
+			failures.add(name, err)
+			break
+		}
+		p, err := r.registry.Get(name)
+		if err != nil {
+			failures.add(name, err)
+			continue
+		}
+		if r.gate != nil && !r.gate.Allow(name) {
+			failures.add(name, ErrCircuitOpen)
+			continue
+		}
+
+		start := time.Now()
+		resp, err := call(p)
+		if r.gate != nil {
+			r.gate.Record(name, err, time.Since(start))
+		}
+		if err == nil {
+			if resp.Provider == "" {
+				resp.Provider = name
+			}
+			return resp, nil
+		}
+		failures.add(name, err)
+		if stop() {
+			break
+		}
+	}
+
+	if len(failures.Errors) == 0 {
+		return nil, ErrNoProviders
+	}
+	return nil, failures
+}
+
+// order returns the candidates in the sequence they should be tried
+func (r *Router) order(ctx context.Context) []string {
+	names := r.Candidates()
+	switch r.strategy {
+	case StrategyWeighted:
+		return r.weightedOrder(names)
+	case StrategyCheapest:
+		return r.cheapestHealthyOrder(ctx, names)
+	default:
+		return names
+	}
+}
+
+func (r *Router) weightedOrder(names []string) []string {
+	remaining := append([]string(nil), names...)
+	out := make([]string, 0, len(names))
+	for len(remaining) > 0 {
+		total := 0
+		for _, name := range remaining {
+			total += r.weight(name)
+		}
+		pick := rand.Intn(total)
+		for i, name := range remaining {
+			pick -= r.weight(name)
+			if pick < 0 {
+				out = append(out, name)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// weight defaults to 1 for providers without an explicit weight
+func (r *Router) weight(name string) int {
+	if w, ok := r.weights[name]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+// cheapestHealthyOrder puts healthy providers first, cheapest first. Unhealthy
+// ones are kept at the end as a last resort, since a health probe can be wrong.
+// With a gate the breaker state is used instead of probing on every request.
+func (r *Router) cheapestHealthyOrder(ctx context.Context, names []string) []string {
+	type candidate struct {
+		name    string
+		cost    float64
+		healthy bool
+	}
+
+	cands := make([]candidate, 0, len(names))
+	for _, name := range names {
+		p, err := r.registry.Get(name)
+		if err != nil {
+			continue
+		}
+		var healthy bool
+		if r.gate != nil {
+			healthy = r.gate.Healthy(name)
+		} else {
+			hctx, cancel := context.WithTimeout(ctx, r.healthTimeout)
+			healthy = p.Health(hctx) == nil
+			cancel()
+		}
+		cands = append(cands, candidate{name: name, cost: p.Info().CostPer1KTokens, healthy: healthy})
+	}
+
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].healthy != cands[j].healthy {
+			return cands[i].healthy
+		}
+		return cands[i].cost < cands[j].cost
+	})
+
+	out := make([]string, len(cands))
+	for i, c := range cands {
+		out[i] = c.name
+	}
+	return out
+}
+
+// ParseWeights parses "vllm=3,ollama=1" into a weight map
+func ParseWeights(spec string) (map[string]int, error) {
+	weights := map[string]int{}
+	for _, part := range splitList(spec) {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid weight %q, expected name=weight", part)
+		}
+		var w int
+		if _, err := fmt.Sscanf(strings.TrimSpace(value), "%d", &w); err != nil || w < 1 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", name, value)
+		}
+		weights[strings.TrimSpace(name)] = w
+	}
+	return weights, nil
+}
+
+func splitList(spec string) []string {
+	var out []string
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
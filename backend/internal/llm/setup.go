@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/config"
+)
+
+// Registered provider names
+const (
+	ProviderDashScope    = "dashscope"
+	ProviderVLLM         = "vllm"
+	ProviderOllama       = "ollama"
+	ProviderOpenAICompat = "openai_compat"
+)
+
+// defaultDashScopeCost is qwen-plus list pricing in CNY per 1K tokens
+const defaultDashScopeCost = 0.004
+
+// NewFromConfig registers every configured text provider and builds the router.
+// LLM_PROVIDERS overrides the candidate list implied by AI_PROVIDER.
+func NewFromConfig(cfg config.AIConfig) (*Registry, *Router, error) {
+	costs, err := parseCosts(cfg.LLMProviderCosts)
+	if err != nil {
+		return nil, nil, err
+	}
+	weights, err := ParseWeights(cfg.LLMProviderWeights)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	registry := NewRegistry()
+	dashScopeCost, ok := costs[ProviderDashScope]
+	if !ok {
+		dashScopeCost = defaultDashScopeCost
+	}
+	registry.Register(ProviderDashScope, NewDashScopeProvider(DashScopeConfig{
+		Name:            ProviderDashScope,
+		BaseURL:         cfg.QwenAPIBase,
+		APIKey:          cfg.QwenAPIKey,
+		Model:           cfg.QwenModelName,
+		Timeout:         60 * time.Second,
+		CostPer1KTokens: dashScopeCost,
+	}))
+	registry.Register(ProviderVLLM, NewOpenAIProvider(OpenAIConfig{
+		Name:            ProviderVLLM,
+		BaseURL:         cfg.VLLMBaseURL,
+		Model:           cfg.VLLMModelName,
+		MaxTokens:       cfg.VLLMMaxTokens,
+		Timeout:         time.Duration(cfg.VLLMTimeout) * time.Second,
+		CostPer1KTokens: costs[ProviderVLLM],
+		Local:           true,
+	}))
+	registry.Register(ProviderOllama, NewOllamaProvider(OllamaConfig{
+		Name:      ProviderOllama,
+		BaseURL:   cfg.OLLAMABaseURL,
+		Model:     cfg.OLLAMAModelName,
+		MaxTokens: cfg.OLLAMAMaxTokens,
+		Timeout:   time.Duration(cfg.OLLAMATimeout) * time.Second,
+	}))
+	if cfg.OpenAICompatBaseURL != "" {
+		registry.Register(ProviderOpenAICompat, NewOpenAIProvider(OpenAIConfig{
+			Name:            ProviderOpenAICompat,
+			BaseURL:         cfg.OpenAICompatBaseURL,
+			APIKey:          cfg.OpenAICompatAPIKey,
+			Model:           cfg.OpenAICompatModelName,
+			MaxTokens:       cfg.VLLMMaxTokens,
+			Timeout:         time.Duration(cfg.VLLMTimeout) * time.Second,
+			CostPer1KTokens: costs[ProviderOpenAICompat],
+			Local:           true,
+		}))
+	}
+
+	candidates := splitList(cfg.LLMProviders)
+	if len(candidates) == 0 {
+		candidates = defaultCandidates(cfg.AIProvider)
+	}
+	for _, name := range candidates {
+		if _, err := registry.Get(name); err != nil {
+			return nil, nil, fmt.Errorf("LLM_PROVIDERS: %w", err)
+		}
+	}
+
+	return registry, NewRouter(registry, cfg.LLMRoutingStrategy, candidates, weights), nil
+}
+
+// defaultCandidates maps the AI_PROVIDER modes onto provider chains
+func defaultCandidates(mode string) []string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "local_vllm":
+		return []string{ProviderVLLM}
+	case "local_ollama":
+		return []string{ProviderOllama}
+	case "hybrid":
+		return []string{ProviderVLLM, ProviderOllama, ProviderDashScope}
+	default:
+		return []string{ProviderDashScope}
+	}
+}
+
+// parseCosts parses "dashscope=0.004,vllm=0" into a cost map
+func parseCosts(spec string) (map[string]float64, error) {
+	costs := map[string]float64{}
+	for _, part := range splitList(spec) {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cost %q, expected name=cost", part)
+		}
+		cost, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("invalid cost for %s: %q", name, value)
+		}
+		costs[strings.TrimSpace(name)] = cost
+	}
+	return costs, nil
+}
@@ -0,0 +1,248 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Transition is how one scene hands over to the next in a finished episode
+type Transition string
+
+const (
+	TransitionCut       Transition = "cut"
+	TransitionCrossfade Transition = "crossfade"
+	TransitionFadeBlack Transition = "fade_black"
+)
+
+// Transitions lists the values a render request may ask for
+var Transitions = []Transition{TransitionCut, TransitionCrossfade, TransitionFadeBlack}
+
+// ParseTransition validates a requested transition; an empty value means cut
+func ParseTransition(s string) (Transition, error) {
+	t := Transition(strings.ToLower(strings.TrimSpace(strings.ReplaceAll(s, "-", "_"))))
+	if t == "" {
+		return TransitionCut, nil
+	}
+	for _, known := range Transitions {
+		if t == known {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown transition %q", s)
+}
+
+// Format is the common shape every clip is normalized to before assembly
+type Format struct {
+	Width  int
+	Height int
+	FPS    int
+}
+
+// audio format of normalized clips
+const (
+	sampleRate    = 48000
+	channelLayout = "stereo"
+)
+
+// Clip is one scene's media: a video file, or a still image that becomes a
+// Ken Burns clip of Seconds length. Audio is an optional dialogue track of
+// AudioSeconds length, mixed over the clip's own sound; the clip is held on
+// its last frame when the dialogue runs longer.
+type Clip struct {
+	Path         string
+	Still        bool
+	Seconds      float64
+	Motion       Motion
+	Audio        string
+	AudioSeconds float64
+}
+
+// clipAudioLevel is how loud a clip's own sound stays under dialogue
+const clipAudioLevel = 0.35
+
+// Normalize re-encodes a clip to f with H.264 video and AAC audio, so the
+// clips of an episode can be joined without surprises. It returns the length
+// of the output in seconds.
+func Normalize(ctx context.Context, clip Clip, f Format, out string, onProgress func(float64)) (float64, error) {
+	args := []string{"-y", "-i", clip.Path}
+	var seconds float64
+	var vf string
+	hasAudio := false
+
+	if clip.Still {
+		seconds = clip.Seconds
+		if seconds <= 0 {
+			return 0, fmt.Errorf("still clip %s has no duration", clip.Path)
+		}
+	} else {
+		info, err := Probe(ctx, clip.Path)
+		if err != nil {
+			return 0, err
+		}
+		if !info.HasVideo || info.Seconds <= 0 {
+			return 0, fmt.Errorf("%s has no video stream", clip.Path)
+		}
+		seconds, hasAudio = info.Seconds, info.HasAudio
+		vf = fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%d",
+			f.Width, f.Height, f.Width, f.Height, f.FPS)
+	}
+
+	dialogue := -1
+	if clip.Audio != "" {
+		audioSeconds := clip.AudioSeconds
+		if audioSeconds <= 0 {
+			info, err := Probe(ctx, clip.Audio)
+			if err != nil {
+				return 0, err
+			}
+			audioSeconds = info.Seconds
+		}
+		if extra := audioSeconds - seconds; extra > 0 {
+			if !clip.Still {
+				vf += fmt.Sprintf(",tpad=stop_mode=clone:stop_duration=%s", formatSeconds(extra))
+			}
+			seconds = audioSeconds
+		}
+		args = append(args, "-i", clip.Audio)
+		dialogue = 1
+	}
+	if clip.Still {
+		vf = KenBurns(clip.Motion, f.Width, f.Height, f.FPS, seconds)
+	}
+
+	graph := []string{fmt.Sprintf("[0:v]%s,format=yuv420p[v]", vf)}
+	switch {
+	case hasAudio && dialogue >= 0:
+		graph = append(graph,
+			fmt.Sprintf("[0:a]%s,volume=%g[bg]", audioFormat(), clipAudioLevel),
+			fmt.Sprintf("[%d:a]%s[dl]", dialogue, audioFormat()),
+			"[bg][dl]amix=inputs=2:duration=longest:dropout_transition=0:normalize=0,apad[a]",
+		)
+	case dialogue >= 0:
+		graph = append(graph, fmt.Sprintf("[%d:a]%s,apad[a]", dialogue, audioFormat()))
+	case hasAudio:
+		graph = append(graph, fmt.Sprintf("[0:a]%s,apad[a]", audioFormat()))
+	default:
+		// A silent track, so every clip can be crossfaded the same way
+		graph = append(graph, fmt.Sprintf("anullsrc=r=%d:cl=%s[a]", sampleRate, channelLayout))
+	}
+
+	args = append(args,
+		"-filter_complex", strings.Join(graph, ";"),
+		"-map", "[v]", "-map", "[a]",
+		"-t", formatSeconds(seconds),
+	)
+	args = append(args, encodeArgs(f)...)
+	args = append(args, out)
+	if err := Run(ctx, args, seconds, onProgress); err != nil {
+		return 0, err
+	}
+	return seconds, nil
+}
+
+// Timeline places clips of the given lengths one after another. offsets holds
+// where each clip starts in the joined video; overlapping transitions pull
+// clips forward by the returned overlap, which never eats more than half of
+// any clip.
+func Timeline(seconds []float64, t Transition, overlap float64) (offsets []float64, actual float64) {
+	if len(seconds) == 0 {
+		return nil, 0
+	}
+	shortest := seconds[0]
+	for _, s := range seconds {
+		shortest = min(shortest, s)
+	}
+	if t == TransitionCut || len(seconds) == 1 || overlap <= 0 {
+		overlap = 0
+	}
+	overlap = min(overlap, shortest/2)
+
+	offsets = make([]float64, len(seconds))
+	for i := 1; i < len(seconds); i++ {
+		offsets[i] = offsets[i-1] + seconds[i-1] - overlap
+	}
+	return offsets, overlap
+}
+
+// Assemble joins normalized clips in order. seconds holds each clip's length;
+// overlapping transitions shorten the result by their duration.
+func Assemble(ctx context.Context, clips []string, seconds []float64, t Transition, overlap float64, out string, onProgress func(float64)) error {
+	if len(clips) == 0 {
+		return fmt.Errorf("nothing to assemble")
+	}
+	if len(clips) != len(seconds) {
+		return fmt.Errorf("got %d clips but %d durations", len(clips), len(seconds))
+	}
+
+	offsets, overlap := Timeline(seconds, t, overlap)
+	last := len(clips) - 1
+	total := offsets[last] + seconds[last]
+	if overlap <= 0 {
+		return concat(ctx, clips, total, out, onProgress)
+	}
+
+	xfade := "fade"
+	if t == TransitionFadeBlack {
+		xfade = "fadeblack"
+	}
+	args := []string{"-y"}
+	for _, clip := range clips {
+		args = append(args, "-i", clip)
+	}
+
+	var graph []string
+	video, audio := "[0:v]", "[0:a]"
+	for i := 1; i < len(clips); i++ {
+		v, a := fmt.Sprintf("[v%d]", i), fmt.Sprintf("[a%d]", i)
+		graph = append(graph,
+			fmt.Sprintf("%s[%d:v]xfade=transition=%s:duration=%s:offset=%s%s", video, i, xfade, formatSeconds(overlap), formatSeconds(offsets[i]), v),
+			fmt.Sprintf("%s[%d:a]acrossfade=d=%s%s", audio, i, formatSeconds(overlap), a),
+		)
+		video, audio = v, a
+	}
+	args = append(args, "-filter_complex", strings.Join(graph, ";"), "-map", video, "-map", audio)
+	args = append(args, encodeArgs(Format{})...)
+	args = append(args, "-movflags", "+faststart", out)
+	return Run(ctx, args, total, onProgress)
+}
+
+// concat joins clips back to back without re-encoding
+func concat(ctx context.Context, clips []string, total float64, out string, onProgress func(float64)) error {
+	var list strings.Builder
+	for _, clip := range clips {
+		abs, err := filepath.Abs(clip)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	listPath := out + ".txt"
+	if err := os.WriteFile(listPath, []byte(list.String()), 0o644); err != nil {
+		return err
+	}
+	defer os.Remove(listPath)
+
+	args := []string{"-y", "-f", "concat", "-safe", "0", "-i", listPath, "-c", "copy", "-movflags", "+faststart", out}
+	return Run(ctx, args, total, onProgress)
+}
+
+// encodeArgs are the codec settings shared by normalized clips and the
+// finished episode; f.FPS is only forced when set
+func encodeArgs(f Format) []string {
+	args := []string{
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "20", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "128k", "-ar", strconv.Itoa(sampleRate), "-ac", "2",
+	}
+	if f.FPS > 0 {
+		args = append(args, "-r", strconv.Itoa(f.FPS))
+	}
+	return args
+}
+
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
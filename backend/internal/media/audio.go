@@ -0,0 +1,43 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TrackPart is one audio file placed on a track
+type TrackPart struct {
+	Path  string
+	Start float64 // seconds from the start of the track
+}
+
+// DialogueTrack lays the parts out on one AAC track of the given length, with
+// silence between them. Parts are expected not to overlap.
+func DialogueTrack(ctx context.Context, parts []TrackPart, seconds float64, out string) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("no audio to place on the track")
+	}
+	args := []string{"-y"}
+	var graph []string
+	var labels string
+	for i, part := range parts {
+		args = append(args, "-i", part.Path)
+		ms := int(part.Start*1000 + 0.5)
+		graph = append(graph, fmt.Sprintf("[%d:a]%s,adelay=%d|%d[p%d]", i, audioFormat(), ms, ms, i))
+		labels += fmt.Sprintf("[p%d]", i)
+	}
+	graph = append(graph, fmt.Sprintf("%samix=inputs=%d:duration=longest:dropout_transition=0:normalize=0,apad[a]", labels, len(parts)))
+	args = append(args,
+		"-filter_complex", strings.Join(graph, ";"),
+		"-map", "[a]", "-t", formatSeconds(seconds),
+		"-c:a", "aac", "-b:a", "128k", "-ar", fmt.Sprint(sampleRate), "-ac", "2",
+		"-f", "mp4", out,
+	)
+	return Run(ctx, args, seconds, nil)
+}
+
+// audioFormat converts any input to the sample format of normalized clips
+func audioFormat() string {
+	return fmt.Sprintf("aresample=%d,aformat=sample_fmts=fltp:channel_layouts=%s", sampleRate, channelLayout)
+}
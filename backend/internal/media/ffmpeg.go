@@ -0,0 +1,125 @@
+package media
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrNoFFmpeg is returned when ffmpeg or ffprobe is not on PATH
+var ErrNoFFmpeg = errors.New("ffmpeg not found on PATH")
+
+// Available reports whether ffmpeg and ffprobe can be run
+func Available() error {
+	for _, bin := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			return fmt.Errorf("%w: %s", ErrNoFFmpeg, bin)
+		}
+	}
+	return nil
+}
+
+// Run executes ffmpeg and reports progress as a 0-1 fraction of seconds,
+// the expected output length. The error carries the tail of ffmpeg's log.
+func Run(ctx context.Context, args []string, seconds float64, onProgress func(float64)) error {
+	// -progress writes key=value lines to stdout; errors stay on stderr
+	args = append([]string{"-hide_banner", "-nostats", "-progress", "pipe:1"}, args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	readProgress(stdout, seconds, onProgress)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, lastLines(stderr.String(), 20))
+	}
+	return nil
+}
+
+// readProgress turns ffmpeg's out_time_us into a 0-1 fraction of the output
+func readProgress(r io.Reader, seconds float64, onProgress func(float64)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || key != "out_time_us" || onProgress == nil || seconds <= 0 {
+			continue
+		}
+		us, err := strconv.ParseFloat(value, 64)
+		if err != nil || us < 0 {
+			continue
+		}
+		onProgress(math.Min(us/seconds/1e6, 1))
+	}
+	_, _ = io.Copy(io.Discard, r)
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Info is what the renderers need to know about an input file
+type Info struct {
+	Seconds  float64
+	Width    int
+	Height   int
+	HasVideo bool
+	HasAudio bool
+}
+
+// Probe reads a media file's duration and streams with ffprobe
+func Probe(ctx context.Context, path string) (*Info, error) {
+	out, err := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-print_format", "json",
+		"-show_entries", "format=duration:stream=codec_type,width,height", path).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("ffprobe failed on %s: %s", path, lastLines(string(exitErr.Stderr), 5))
+		}
+		return nil, fmt.Errorf("ffprobe failed on %s: %w", path, err)
+	}
+
+	var probe struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("unreadable ffprobe output for %s: %w", path, err)
+	}
+	info := &Info{}
+	info.Seconds, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	for _, st := range probe.Streams {
+		switch st.CodecType {
+		case "video":
+			if !info.HasVideo {
+				info.Width, info.Height = st.Width, st.Height
+			}
+			info.HasVideo = true
+		case "audio":
+			info.HasAudio = true
+		}
+	}
+	return info, nil
+}
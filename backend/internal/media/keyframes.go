@@ -0,0 +1,44 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Keyframes samples n frames spread evenly over a clip of the given length,
+// one from the middle of each slice, and writes them as JPEGs no wider than
+// 768 pixels into dir. It returns the frame paths in order.
+func Keyframes(ctx context.Context, in string, seconds float64, n int, dir string) ([]string, error) {
+	if n <= 0 {
+		n = 1
+	}
+	if seconds <= 0 {
+		return nil, fmt.Errorf("cannot sample frames from a clip of %gs", seconds)
+	}
+	slice := seconds / float64(n)
+	pattern := filepath.Join(dir, "keyframe-%03d.jpg")
+	args := []string{"-y",
+		"-ss", formatSeconds(slice / 2), "-i", in,
+		"-vf", fmt.Sprintf("fps=1/%s,scale='min(768,iw)':-2", formatSeconds(slice)),
+		"-frames:v", strconv.Itoa(n), "-q:v", "3", pattern,
+	}
+	if err := Run(ctx, args, 0, nil); err != nil {
+		return nil, err
+	}
+
+	frames := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		path := fmt.Sprintf(pattern, i)
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+		frames = append(frames, path)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("ffmpeg wrote no frames for %s", in)
+	}
+	return frames, nil
+}
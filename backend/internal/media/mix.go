@@ -0,0 +1,77 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MusicCue places a music file on the episode, looped when it is shorter
+// than the cue
+type MusicCue struct {
+	Path    string
+	Start   float64
+	Seconds float64
+}
+
+// MixOptions set the levels of the final mix
+type MixOptions struct {
+	// MusicDB is the music level while nothing else plays, in dB
+	MusicDB float64
+	// LUFS is the integrated loudness the episode is normalized to
+	LUFS float64
+}
+
+// Fades at the edges of a music cue, and the true peak ceiling, in seconds
+// and dBTP
+const (
+	musicFadeIn  = 1.0
+	musicFadeOut = 1.5
+	truePeak     = -1.5
+)
+
+// MixAudio lays music cues under an episode's sound and ducks them whenever
+// the episode's own audio, mostly dialogue, plays. The result is normalized
+// to opts.LUFS; the video stream is copied. seconds is the length of the
+// episode.
+func MixAudio(ctx context.Context, in string, cues []MusicCue, opts MixOptions, seconds float64, out string, onProgress func(float64)) error {
+	args := []string{"-y", "-i", in}
+	loudnorm := fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=11,%s", dB(opts.LUFS), dB(truePeak), audioFormat())
+
+	var graph []string
+	var labels string
+	for i, cue := range cues {
+		args = append(args, "-stream_loop", "-1", "-i", cue.Path)
+		fadeIn, fadeOut := min(musicFadeIn, cue.Seconds/4), min(musicFadeOut, cue.Seconds/4)
+		ms := int(cue.Start*1000 + 0.5)
+		graph = append(graph, fmt.Sprintf(
+			"[%d:a]%s,atrim=0:%s,asetpts=PTS-STARTPTS,afade=t=in:d=%s,afade=t=out:st=%s:d=%s,adelay=%d|%d,volume=%sdB[m%d]",
+			i+1, audioFormat(), formatSeconds(cue.Seconds), formatSeconds(fadeIn),
+			formatSeconds(cue.Seconds-fadeOut), formatSeconds(fadeOut), ms, ms, dB(opts.MusicDB), i))
+		labels += fmt.Sprintf("[m%d]", i)
+	}
+	if len(cues) == 0 {
+		graph = append(graph, "[0:a]"+loudnorm+"[a]")
+	} else {
+		graph = append(graph,
+			fmt.Sprintf("%samix=inputs=%d:duration=longest:dropout_transition=0:normalize=0,apad[music]", labels, len(cues)),
+			"[0:a]asplit=2[main][key]",
+			// The episode's own sound pushes the music down while it plays
+			"[music][key]sidechaincompress=threshold=0.02:ratio=8:attack=20:release=500[ducked]",
+			"[main][ducked]amix=inputs=2:duration=first:dropout_transition=0:normalize=0,"+loudnorm+"[a]",
+		)
+	}
+
+	args = append(args,
+		"-filter_complex", strings.Join(graph, ";"),
+		"-map", "0:v", "-map", "[a]",
+		"-c:v", "copy", "-c:a", "aac", "-b:a", "192k", "-ar", strconv.Itoa(sampleRate), "-ac", "2",
+		"-t", formatSeconds(seconds), "-movflags", "+faststart", out,
+	)
+	return Run(ctx, args, seconds, onProgress)
+}
+
+func dB(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
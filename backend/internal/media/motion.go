@@ -0,0 +1,141 @@
+// Package media drives ffmpeg for the local renderers: Ken Burns motion over
+// stills, clip normalization and episode assembly
+package media
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Motion is a Ken Burns camera move over a still image
+type Motion string
+
+const (
+	MotionStatic   Motion = "static"
+	MotionZoomIn   Motion = "zoom_in"
+	MotionZoomOut  Motion = "zoom_out"
+	MotionPanLeft  Motion = "pan_left"
+	MotionPanRight Motion = "pan_right"
+	MotionPush     Motion = "push" // faster zoom toward the upper third, where faces usually are
+	MotionAuto     Motion = ""     // pick from the shot type
+)
+
+const (
+	defaultMotion = MotionZoomIn
+	// zoompan rounds crop offsets to whole pixels; working larger hides the jitter
+	upscale  = 4
+	maxZoom  = 1.2
+	pushZoom = 1.35
+	panZoom  = 1.15
+	// share of the spare height above the crop when pushing in
+	subjectHeight = 0.35
+)
+
+// Motions lists the presets a request may ask for
+var Motions = []Motion{MotionStatic, MotionZoomIn, MotionZoomOut, MotionPanLeft, MotionPanRight, MotionPush}
+
+// ParseMotion validates a requested preset; an empty value means auto
+func ParseMotion(s string) (Motion, error) {
+	m := Motion(strings.ToLower(strings.TrimSpace(strings.ReplaceAll(s, "-", "_"))))
+	if m == MotionAuto {
+		return MotionAuto, nil
+	}
+	for _, known := range Motions {
+		if m == known {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown motion %q", s)
+}
+
+// shotMotions maps shot type keywords, Chinese and English, to a preset.
+// More specific keywords come first.
+var shotMotions = []struct {
+	keywords []string
+	motion   Motion
+}{
+	{[]string{"大特写", "特写", "close-up", "closeup", "close up", "ecu"}, MotionZoomIn},
+	{[]string{"近景", "medium close", "推镜", "推", "push", "dolly in"}, MotionPush},
+	{[]string{"拉镜", "拉", "pull", "dolly out", "reveal"}, MotionZoomOut},
+	{[]string{"航拍", "aerial", "drone"}, MotionPanRight},
+	{[]string{"全景", "远景", "大远景", "wide", "establishing", "long shot", "full shot"}, MotionZoomOut},
+	{[]string{"左摇", "pan left"}, MotionPanLeft},
+	{[]string{"摇", "右摇", "pan", "tracking", "跟拍", "移"}, MotionPanRight},
+	{[]string{"中景", "medium", "mid shot", "过肩", "over the shoulder"}, MotionPanLeft},
+	{[]string{"固定", "static", "still"}, MotionStatic},
+}
+
+// MotionForShot picks a preset for a scene's shot type, e.g. 特写 or
+// close-up gets a slow zoom-in and 全景 a slow zoom-out
+func MotionForShot(shotType string) Motion {
+	shot := strings.ToLower(strings.TrimSpace(shotType))
+	if shot == "" {
+		return defaultMotion
+	}
+	for _, entry := range shotMotions {
+		for _, kw := range entry.keywords {
+			if strings.Contains(shot, kw) {
+				return entry.motion
+			}
+		}
+	}
+	return defaultMotion
+}
+
+// Resolve returns the requested preset, or the one for the shot type when
+// none was requested
+func Resolve(requested, shotType string) (Motion, error) {
+	m, err := ParseMotion(requested)
+	if err != nil {
+		return "", err
+	}
+	if m == MotionAuto {
+		return MotionForShot(shotType), nil
+	}
+	return m, nil
+}
+
+// KenBurns returns a filter chain that turns a single still image into a
+// w x h clip of the given length with the preset's camera move. The input
+// must be one frame (no -loop): zoompan emits every output frame itself.
+func KenBurns(m Motion, w, h, fps int, seconds float64) string {
+	if m == MotionAuto {
+		m = defaultMotion
+	}
+	frames := int(seconds*float64(fps) + 0.5)
+	if frames < 1 {
+		frames = 1
+	}
+	if m == MotionStatic {
+		return fmt.Sprintf("%s,zoompan=z=1:d=%d:s=%dx%d:fps=%d", fill(w, h), frames, w, h, fps)
+	}
+
+	// t eases from 0 to 1 over the clip so moves start and stop gently
+	last := frames - 1
+	if last < 1 {
+		last = 1
+	}
+	t := fmt.Sprintf("((1-cos(PI*min(on/%d,1)))/2)", last)
+	centerX := "iw/2-(iw/zoom/2)"
+	centerY := "ih/2-(ih/zoom/2)"
+
+	var z, x, y string
+	switch m {
+	case MotionZoomOut:
+		z, x, y = fmt.Sprintf("%g-%g*%s", maxZoom, maxZoom-1, t), centerX, centerY
+	case MotionPanLeft:
+		z, x, y = fmt.Sprintf("%g", panZoom), fmt.Sprintf("(iw-iw/zoom)*(1-%s)", t), centerY
+	case MotionPanRight:
+		z, x, y = fmt.Sprintf("%g", panZoom), fmt.Sprintf("(iw-iw/zoom)*%s", t), centerY
+	case MotionPush:
+		z, x, y = fmt.Sprintf("1+%g*%s", pushZoom-1, t), centerX, fmt.Sprintf("(ih-ih/zoom)*%g", subjectHeight)
+	default:
+		z, x, y = fmt.Sprintf("1+%g*%s", maxZoom-1, t), centerX, centerY
+	}
+	return fmt.Sprintf("%s,zoompan=z='%s':x='%s':y='%s':d=%d:s=%dx%d:fps=%d", fill(w*upscale, h*upscale), z, x, y, frames, w, h, fps)
+}
+
+// fill scales and crops the image to cover w x h whatever its aspect ratio
+func fill(w, h int) string {
+	return fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,setsar=1", w, h, w, h)
+}
@@ -0,0 +1,78 @@
+package media
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		requested string
+		shotType  string
+		want      Motion
+		err       bool
+	}{
+		{shotType: "特写", want: MotionZoomIn},
+		{shotType: "大特写", want: MotionZoomIn},
+		{shotType: "Close-Up", want: MotionZoomIn},
+		{shotType: "medium close-up", want: MotionZoomIn},
+		{shotType: "近景", want: MotionPush},
+		{shotType: "dolly in", want: MotionPush},
+		{shotType: "拉镜", want: MotionZoomOut},
+		{shotType: "全景", want: MotionZoomOut},
+		{shotType: "Establishing shot", want: MotionZoomOut},
+		{shotType: "航拍", want: MotionPanRight},
+		{shotType: "左摇", want: MotionPanLeft},
+		{shotType: "tracking", want: MotionPanRight},
+		{shotType: "中景", want: MotionPanLeft},
+		{shotType: "over the shoulder", want: MotionPanLeft},
+		{shotType: "固定机位", want: MotionStatic},
+		{shotType: "", want: MotionZoomIn},
+		{shotType: "鸟瞰", want: MotionZoomIn},
+		{requested: "zoom-out", shotType: "特写", want: MotionZoomOut},
+		{requested: " PAN_LEFT ", want: MotionPanLeft},
+		{requested: "static", shotType: "航拍", want: MotionStatic},
+		{requested: "spin", shotType: "特写", err: true},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.requested, tt.shotType)
+		if (err != nil) != tt.err {
+			t.Errorf("Resolve(%q, %q) error = %v, want error %v", tt.requested, tt.shotType, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%q, %q) = %q, want %q", tt.requested, tt.shotType, got, tt.want)
+		}
+	}
+}
+
+func TestKenBurns(t *testing.T) {
+	const (
+		upscaled = "scale=5120:2880:force_original_aspect_ratio=increase,crop=5120:2880,setsar=1,"
+		ease     = "((1-cos(PI*min(on/49,1)))/2)"
+		center   = ":x='iw/2-(iw/zoom/2)':y='ih/2-(ih/zoom/2)'"
+		output   = ":d=50:s=1280x720:fps=25"
+	)
+	tests := []struct {
+		motion Motion
+		want   string
+	}{
+		{MotionStatic, "scale=1280:720:force_original_aspect_ratio=increase,crop=1280:720,setsar=1,zoompan=z=1" + output},
+		{MotionZoomIn, upscaled + "zoompan=z='1+0.2*" + ease + "'" + center + output},
+		{MotionAuto, upscaled + "zoompan=z='1+0.2*" + ease + "'" + center + output},
+		{MotionZoomOut, upscaled + "zoompan=z='1.2-0.2*" + ease + "'" + center + output},
+		{MotionPanLeft, upscaled + "zoompan=z='1.15':x='(iw-iw/zoom)*(1-" + ease + ")':y='ih/2-(ih/zoom/2)'" + output},
+		{MotionPanRight, upscaled + "zoompan=z='1.15':x='(iw-iw/zoom)*" + ease + "':y='ih/2-(ih/zoom/2)'" + output},
+		{MotionPush, upscaled + "zoompan=z='1+0.35*" + ease + "':x='iw/2-(iw/zoom/2)':y='(ih-ih/zoom)*0.35'" + output},
+	}
+	for _, tt := range tests {
+		if got := KenBurns(tt.motion, 1280, 720, 25, 2); got != tt.want {
+			t.Errorf("KenBurns(%q) =\n%s\nwant\n%s", tt.motion, got, tt.want)
+		}
+	}
+
+	// A clip shorter than a frame still gets one
+	if got := KenBurns(MotionZoomIn, 640, 360, 24, 0); !strings.Contains(got, "min(on/1,1)") || !strings.HasSuffix(got, ":d=1:s=640x360:fps=24") {
+		t.Errorf("KenBurns() for an empty clip = %s", got)
+	}
+}
@@ -0,0 +1,54 @@
+package media
+
+import (
+	"context"
+	"strings"
+)
+
+// SubtitlesFilter burns an ASS or SRT file into the picture with libass.
+// fontsDir adds fonts that are not installed system-wide.
+func SubtitlesFilter(path, fontsDir string) string {
+	f := "subtitles=filename=" + filterEscape(path)
+	if fontsDir != "" {
+		f += ":fontsdir=" + filterEscape(fontsDir)
+	}
+	return f
+}
+
+// AddSubtitles copies in to out with subtitles: burned into the picture, or
+// with burn unset as a mov_text track that players can switch off. seconds
+// is the length of the video, for progress.
+func AddSubtitles(ctx context.Context, in, subs string, burn bool, fontsDir string, seconds float64, out string, onProgress func(float64)) error {
+	var args []string
+	if burn {
+		args = []string{"-y", "-i", in,
+			"-vf", SubtitlesFilter(subs, fontsDir),
+			"-c:v", "libx264", "-preset", "veryfast", "-crf", "20", "-pix_fmt", "yuv420p",
+			"-c:a", "copy",
+		}
+	} else {
+		args = []string{"-y", "-i", in, "-i", subs,
+			"-map", "0:v", "-map", "0:a?", "-map", "1:0",
+			"-c:v", "copy", "-c:a", "copy", "-c:s", "mov_text",
+			"-metadata:s:s:0", "language=chi",
+		}
+	}
+	args = append(args, "-movflags", "+faststart", out)
+	return Run(ctx, args, seconds, onProgress)
+}
+
+// filterEscape quotes a value for a filter option inside a filtergraph:
+// once for the option parser and once for the graph parser
+func filterEscape(s string) string {
+	escape := func(s, special string) string {
+		var b strings.Builder
+		for _, r := range s {
+			if strings.ContainsRune(special, r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	return escape(escape(s, `\':`), `\'[],;`)
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+)
+
+// AdminRequired lets only users with the admin role through. It runs after
+// AuthRequired and reads the role from the database, so a role change takes
+// effect without a new token.
+func AdminRequired(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := db.Select("id", "role", "status").First(&user, c.GetUint("user_id")).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+		if user.Role != models.RoleAdmin || user.Status != "active" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -16,36 +16,53 @@ type Claims struct {
 }
 
 func AuthRequired(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, cfg, c.GetHeader("Authorization"))
+	}
+}
+
+// StreamAuthRequired is AuthRequired for streaming endpoints. EventSource and
+// browser WebSockets cannot set headers, so the token may also be passed as
+// the access_token query parameter. Mount it only on those routes; the query
+// string ends up in proxy and access logs.
+func StreamAuthRequired(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
+		if authHeader == "" && c.Query("access_token") != "" {
+			authHeader = "Bearer " + c.Query("access_token")
 		}
+		authenticate(c, cfg, authHeader)
+	}
+}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
-			c.Abort()
-			return
-		}
+func authenticate(c *gin.Context, cfg *config.Config, authHeader string) {
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		c.Abort()
+		return
+	}
 
-		tokenString := parts[1]
-		claims := &Claims{}
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
+		c.Abort()
+		return
+	}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWT.Secret), nil
-		})
+	tokenString := parts[1]
+	claims := &Claims{}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
-			return
-		}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.JWT.Secret), nil
+	})
 
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Next()
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return
 	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Next()
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 "log"
+"net/url"
 "time"
 
 "github.com/gin-gonic/gin"
@@ -21,7 +22,7 @@ method := c.Request.Method
 statusCode := c.Writer.Status()
 
 if raw != "" {
-path = path + "?" + raw
+path = path + "?" + redactQuery(raw)
 }
 
 log.Printf("[GIN] %v | %3d | %13v | %15s | %-7s %s",
@@ -35,6 +36,19 @@ path,
 }
 }
 
+// redactQuery masks the access_token parameter accepted by event streams
+func redactQuery(raw string) string {
+query, err := url.ParseQuery(raw)
+if err != nil {
+return "[unparseable]"
+}
+if query.Has("access_token") {
+query.Set("access_token", "REDACTED")
+return query.Encode()
+}
+return raw
+}
+
 func Recovery() gin.HandlerFunc {
 return gin.Recovery()
 }
@@ -10,6 +10,7 @@ type AITask struct {
 ID           uint       `gorm:"primaryKey" json:"id"`
 ProjectID    *uint      `gorm:"index" json:"project_id"`
 SceneID      *uint      `gorm:"index" json:"scene_id"`
+SeriesID     *uint      `gorm:"index" json:"series_id,omitempty"`
 TaskType     string     `gorm:"size:50;not null;index" json:"task_type"`
 ModelName    string     `gorm:"size:100" json:"model_name"`
 InputData    JSONMap    `gorm:"type:jsonb" json:"input_data"`
@@ -21,6 +22,10 @@ StartedAt    *time.Time `json:"started_at"`
 CompletedAt  *time.Time `json:"completed_at"`
 DurationMs   int        `json:"duration_ms"`
 CreatedAt    time.Time  `json:"created_at"`
+
+// Prompt template version that produced the task's prompt, when one was used
+PromptTemplateID *uint `gorm:"index" json:"prompt_template_id"`
+PromptVersion    int   `json:"prompt_version"`
 }
 
 type JSONMap map[string]interface{}
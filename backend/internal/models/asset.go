@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Asset kinds
+const (
+	AssetImage  = "image"
+	AssetVideo  = "video"
+	AssetAudio  = "audio"
+	AssetExport = "export"
+	AssetMusic  = "music"
+)
+
+// Asset tracks one object in media storage. Keys are content addressed, so
+// several assets (e.g. two scenes with the same keyframe) may share a key.
+type Asset struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProjectID *uint     `gorm:"index" json:"project_id"`
+	SceneID   *uint     `gorm:"index" json:"scene_id"`
+	Kind      string    `gorm:"size:20;not null;index" json:"kind"`
+	Key       string    `gorm:"size:500;not null;index" json:"key"`
+	URL       string    `gorm:"size:1000" json:"url"`
+	Hash      string    `gorm:"size:64;not null;index" json:"hash"`
+	MIMEType  string    `gorm:"size:100" json:"mime_type"`
+	Size      int64     `json:"size"`
+	SourceURL string    `gorm:"size:1000" json:"source_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
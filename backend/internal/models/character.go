@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// ProjectCharacter is a project's canonical description of one character,
+// added to the image and video prompt of every scene the character is in so
+// they look the same from shot to shot. Characters extracted from the script
+// have Auto set and are updated when the script is regenerated; once edited
+// by the user they are left alone.
+type ProjectCharacter struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ProjectID  uint   `gorm:"not null;uniqueIndex:idx_project_character" json:"project_id"`
+	Name       string `gorm:"size:100;not null;uniqueIndex:idx_project_character" json:"name"`
+	Age        string `gorm:"size:50" json:"age"`
+	Appearance string `gorm:"type:text" json:"appearance"`
+	Wardrobe   string `gorm:"type:text" json:"wardrobe"`
+	// ReferenceImages are sent to IP-adapter capable image backends
+	ReferenceImages StringArray `gorm:"type:jsonb" json:"reference_images"`
+	// Seed is used for scenes the character is in; 0 keeps the seed of the
+	// character's first keyframe
+	Seed int64 `gorm:"default:0" json:"seed"`
+	// LoRA names a LoRA trained on the character, optionally with a weight
+	// as "name:0.8"
+	LoRA string `gorm:"column:lora;size:200" json:"lora"`
+	// VoiceID is the character's voice from voice_mappings
+	VoiceID   string    `gorm:"-" json:"voice_id"`
+	Auto      bool      `gorm:"default:false" json:"auto"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (ProjectCharacter) TableName() string {
+	return "characters"
+}
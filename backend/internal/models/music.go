@@ -0,0 +1,48 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Scene music cue modes
+const (
+	// MusicCueAuto lets the renderer match a track to the scene's mood
+	MusicCueAuto   = "auto"
+	MusicCueManual = "manual"
+	// MusicCueNone keeps the scene free of music
+	MusicCueNone = "none"
+)
+
+// MusicTrack is a royalty-free track in a project's music library, tagged so
+// scenes can be matched to it
+type MusicTrack struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	ProjectID  uint        `gorm:"not null;index" json:"project_id"`
+	AssetID    uint        `gorm:"not null" json:"asset_id"`
+	URL        string      `gorm:"size:1000" json:"url"`
+	Title      string      `gorm:"size:200" json:"title"`
+	Genre      string      `gorm:"size:50" json:"genre"`
+	Moods      StringArray `gorm:"type:jsonb" json:"moods"`
+	DurationMS int         `json:"duration_ms"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+type StringArray []string
+
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		a = StringArray{}
+	}
+	return json.Marshal(a)
+}
+
+func (a *StringArray) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, a)
+}
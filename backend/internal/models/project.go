@@ -16,9 +16,16 @@ Genre          string    `gorm:"size:50;index" json:"genre"`
 Style          string    `gorm:"size:50" json:"style"`
 TargetDuration int       `gorm:"default:30" json:"target_duration"`
 CoverURL       string    `gorm:"size:500" json:"cover_url"`
+VideoURL       string    `gorm:"size:500" json:"video_url"`
+VideoAssetID   *uint     `json:"video_asset_id"`
 Status         string    `gorm:"size:20;default:draft;index" json:"status"`
 ViewCount      int       `gorm:"default:0" json:"view_count"`
 LikeCount      int       `gorm:"default:0" json:"like_count"`
+// SeriesID and EpisodeNumber place an episode in its series
+SeriesID       *uint     `gorm:"uniqueIndex:idx_series_episode" json:"series_id"`
+EpisodeNumber  int       `gorm:"default:0;uniqueIndex:idx_series_episode" json:"episode_number"`
+// Summary is what happens in the episode, for the next episodes' recap
+Summary        string    `gorm:"type:text" json:"summary"`
 CreatedAt      time.Time `json:"created_at"`
 UpdatedAt      time.Time `json:"updated_at"`
 
@@ -39,6 +46,12 @@ ShotType        string         `gorm:"size:50" json:"shot_type"`
 Duration        int            `gorm:"default:5" json:"duration"`
 MediaType       string         `gorm:"size:20;default:image" json:"media_type"`
 MediaURL        string         `gorm:"size:500" json:"media_url"`
+MediaAssetID    *uint          `json:"media_asset_id"`
+AudioURL        string         `gorm:"size:500" json:"audio_url"`
+AudioAssetID    *uint          `json:"audio_asset_id"`
+AudioDurationMS int            `json:"audio_duration_ms"`
+MusicCue        string         `gorm:"size:20;default:auto" json:"music_cue"`
+MusicTrackID    *uint          `json:"music_track_id"`
 PromptForImage  string         `gorm:"type:text" json:"prompt_for_image"`
 PromptForVideo  string         `gorm:"type:text" json:"prompt_for_video"`
 Status          string         `gorm:"size:20;default:pending;index" json:"status"`
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// Who wrote a project version
+const (
+	AuthorUser = "user"
+	AuthorAI   = "ai"
+)
+
+// ProjectVersion is an immutable snapshot of a project's script: the
+// project's own fields and every scene. A version is taken after each change
+// to the script, by the AI or by hand; Checksum covers the script only, so
+// media arriving later doesn't make a new version.
+type ProjectVersion struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	ProjectID      uint   `gorm:"not null;uniqueIndex:idx_project_version" json:"project_id"`
+	Version        int    `gorm:"not null;uniqueIndex:idx_project_version" json:"version"`
+	Author         string `gorm:"size:20;not null" json:"author"` // user or ai
+	AuthorID       *uint  `json:"author_id"`                      // the user, for user versions
+	Reason         string `gorm:"size:50;not null" json:"reason"`
+	Note           string `gorm:"size:500" json:"note"`
+	Checksum       string `gorm:"size:64" json:"-"`
+	Title          string `gorm:"size:200" json:"title"`
+	Description    string `gorm:"type:text" json:"description"`
+	Prompt         string `gorm:"type:text" json:"prompt"`
+	Genre          string `gorm:"size:50" json:"genre"`
+	Style          string `gorm:"size:50" json:"style"`
+	TargetDuration int    `json:"target_duration"`
+	SceneCount     int    `json:"scene_count"`
+	// Scenes is left out of version lists
+	Scenes    SceneSnapshots `gorm:"type:jsonb" json:"scenes,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// SceneSnapshot is a scene as it was when its project version was taken
+type SceneSnapshot struct {
+	ID             uint           `json:"id"`
+	SceneNumber    int            `json:"scene_number"`
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	Location       string         `json:"location"`
+	Characters     CharacterArray `json:"characters"`
+	Dialogue       string         `json:"dialogue"`
+	ShotType       string         `json:"shot_type"`
+	Duration       int            `json:"duration"`
+	MusicCue       string         `json:"music_cue"`
+	MusicTrackID   *uint          `json:"music_track_id"`
+	MediaType      string         `json:"media_type"`
+	MediaURL       string         `json:"media_url"`
+	MediaAssetID   *uint          `json:"media_asset_id"`
+	PromptForImage string         `json:"prompt_for_image"`
+	PromptForVideo string         `json:"prompt_for_video"`
+	Status         string         `json:"status"`
+}
+
+type SceneSnapshots []SceneSnapshot
+
+func (s SceneSnapshots) Value() (driver.Value, error) {
+	if s == nil {
+		s = SceneSnapshots{}
+	}
+	return json.Marshal(s)
+}
+
+func (s *SceneSnapshots) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, s)
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// PromptTemplate is one immutable version of a named prompt. Editing a
+// prompt creates a new version; IsActive marks the version used by projects
+// that don't pin one.
+type PromptTemplate struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:50;not null;uniqueIndex:idx_prompt_name_version" json:"name"`
+	Version     int       `gorm:"not null;uniqueIndex:idx_prompt_name_version" json:"version"`
+	Body        string    `gorm:"type:text;not null" json:"body"`
+	Description string    `gorm:"size:500" json:"description"`
+	IsActive    bool      `gorm:"default:false;index" json:"is_active"`
+	CreatedBy   *uint     `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ProjectPromptPin fixes the template version a project uses for one prompt name
+type ProjectPromptPin struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProjectID  uint      `gorm:"not null;uniqueIndex:idx_project_prompt" json:"project_id"`
+	Name       string    `gorm:"size:50;not null;uniqueIndex:idx_project_prompt" json:"name"`
+	TemplateID uint      `gorm:"not null;index" json:"template_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	Template PromptTemplate `gorm:"foreignKey:TemplateID" json:"template,omitempty"`
+}
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Content review stages, in pipeline order
+const (
+	ReviewStagePrompt = "prompt"
+	ReviewStageScript = "script"
+	ReviewStageImage  = "image"
+	ReviewStageVideo  = "video"
+)
+
+// Review result statuses
+const (
+	ReviewPassed = "passed"
+	// ReviewFlagged is approved content with medium risk, kept for auditing
+	ReviewFlagged = "flagged"
+	// ReviewPending waits in the human review queue
+	ReviewPending  = "pending"
+	ReviewRejected = "rejected"
+	// ReviewApproved was cleared by a human reviewer
+	ReviewApproved = "approved"
+	// ReviewAppealed is a rejection the creator has contested
+	ReviewAppealed = "appealed"
+)
+
+// Review decision actions
+const (
+	ReviewActionApprove = "approve"
+	ReviewActionReject  = "reject"
+	ReviewActionAppeal  = "appeal"
+)
+
+// ReviewResult is one moderation verdict on a project's prompt, a scene's
+// script or its generated media
+type ReviewResult struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	ProjectID  uint        `gorm:"not null;index" json:"project_id"`
+	SceneID    *uint       `gorm:"index" json:"scene_id"`
+	Stage      string      `gorm:"size:20;not null;index" json:"stage"`
+	Content    string      `gorm:"type:text" json:"content"`
+	MediaURL   string      `gorm:"size:1000" json:"media_url"`
+	Reviewer   string      `gorm:"size:100" json:"reviewer"`
+	Approved   bool        `json:"approved"`
+	RiskLevel  string      `gorm:"size:10;index" json:"risk_level"`
+	Categories StringArray `gorm:"type:jsonb" json:"categories"`
+	Reason     string      `gorm:"type:text" json:"reason"`
+	Status     string      `gorm:"size:20;not null;index" json:"status"`
+	// Appeal is the creator's argument against a rejection
+	Appeal       string     `gorm:"type:text" json:"appeal,omitempty"`
+	AppealedAt   *time.Time `json:"appealed_at,omitempty"`
+	DecidedBy    *uint      `json:"decided_by,omitempty"`
+	DecisionNote string     `gorm:"type:text" json:"decision_note,omitempty"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	Decisions []ReviewDecision `gorm:"foreignKey:ReviewID" json:"decisions,omitempty"`
+}
+
+// Held reports whether the reviewed content is kept from the pipeline
+func (r *ReviewResult) Held() bool {
+	switch r.Status {
+	case ReviewPending, ReviewRejected, ReviewAppealed:
+		return true
+	}
+	return false
+}
+
+// ReviewDecision is an audit trail entry: a human decision on, or an appeal
+// against, a review result. Entries are never updated or deleted.
+type ReviewDecision struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ReviewID   uint      `gorm:"not null;index" json:"review_id"`
+	ProjectID  uint      `gorm:"not null;index" json:"project_id"`
+	ActorID    uint      `gorm:"not null;index" json:"actor_id"`
+	Action     string    `gorm:"size:20;not null" json:"action"`
+	FromStatus string    `gorm:"size:20" json:"from_status"`
+	ToStatus   string    `gorm:"size:20" json:"to_status"`
+	Note       string    `gorm:"type:text" json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Scene revision reasons, besides the regenerate targets
+const (
+	RevisionScript  = "script"
+	RevisionRestore = "restore"
+)
+
+// SceneRevision is a scene's script and media as they were before the AI
+// replaced them, so the user can roll back. Media is only restored while
+// its asset still exists.
+type SceneRevision struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	SceneID        uint           `gorm:"not null;index" json:"scene_id"`
+	ProjectID      uint           `gorm:"not null;index" json:"project_id"`
+	Reason         string         `gorm:"size:20;not null" json:"reason"` // dialogue, image, video, all, script or restore
+	Instruction    string         `gorm:"type:text" json:"instruction"`
+	Title          string         `gorm:"size:200" json:"title"`
+	Description    string         `gorm:"type:text" json:"description"`
+	Location       string         `gorm:"size:100" json:"location"`
+	Characters     CharacterArray `gorm:"type:jsonb" json:"characters"`
+	Dialogue       string         `gorm:"type:text" json:"dialogue"`
+	ShotType       string         `gorm:"size:50" json:"shot_type"`
+	Duration       int            `json:"duration"`
+	MediaType      string         `gorm:"size:20" json:"media_type"`
+	MediaURL       string         `gorm:"size:500" json:"media_url"`
+	MediaAssetID   *uint          `json:"media_asset_id"`
+	PromptForImage string         `gorm:"type:text" json:"prompt_for_image"`
+	PromptForVideo string         `gorm:"type:text" json:"prompt_for_video"`
+	Status         string         `gorm:"size:20" json:"status"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Series is a multi-episode show. Its episodes are projects with SeriesID
+// set, written from the series outline and the summaries of the episodes
+// before them; they share the series' genre and style, and new episodes
+// inherit the characters of earlier ones.
+type Series struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	UserID  uint   `gorm:"not null;index" json:"user_id"`
+	Title   string `gorm:"size:200;not null" json:"title"`
+	Outline string `gorm:"type:text" json:"outline"`
+	Genre   string `gorm:"size:50" json:"genre"`
+	Style   string `gorm:"size:50" json:"style"`
+	// EpisodeCount is the planned length of the season
+	EpisodeCount int `gorm:"default:0" json:"episode_count"`
+	// EpisodeDuration is each episode's target duration in seconds
+	EpisodeDuration int `gorm:"default:120" json:"episode_duration"`
+	// Summary is the "previously on" recap, rebuilt from the episode
+	// summaries whenever an episode's script is written
+	Summary   string    `gorm:"type:text" json:"summary"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Episodes []Project `gorm:"foreignKey:SeriesID" json:"episodes,omitempty"`
+}
+
+func (Series) TableName() string {
+	return "series"
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// VoiceMapping assigns a TTS voice to one character of a project. Mappings
+// made automatically from the TTS_VOICES pool have Auto set and may be
+// overridden by the user at any time.
+type VoiceMapping struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProjectID uint      `gorm:"not null;uniqueIndex:idx_project_voice" json:"project_id"`
+	Character string    `gorm:"column:character_name;size:100;not null;uniqueIndex:idx_project_voice" json:"character"`
+	VoiceID   string    `gorm:"size:100;not null" json:"voice_id"`
+	Speed     float64   `gorm:"default:0" json:"speed"` // 0 uses TTS_SPEED
+	Auto      bool      `gorm:"default:false" json:"auto"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DialogueLine is one synthesized line of a scene with its place on the
+// scene's audio track, so subtitles line up with the speech
+type DialogueLine struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ProjectID    uint      `gorm:"not null;index" json:"project_id"`
+	SceneID      uint      `gorm:"not null;index" json:"scene_id"`
+	LineNumber   int       `gorm:"not null" json:"line_number"`
+	Speaker      string    `gorm:"size:100" json:"speaker"`
+	Text         string    `gorm:"type:text;not null" json:"text"`
+	VoiceID      string    `gorm:"size:100" json:"voice_id"`
+	AudioURL     string    `gorm:"size:500" json:"audio_url"`
+	AudioAssetID *uint     `json:"audio_asset_id"`
+	StartMS      int       `json:"start_ms"`
+	EndMS        int       `json:"end_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
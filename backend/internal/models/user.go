@@ -4,6 +4,12 @@ import (
 "time"
 )
 
+// User roles
+const (
+RoleUser  = "user"
+RoleAdmin = "admin"
+)
+
 type User struct {
 ID           uint      `gorm:"primaryKey" json:"id"`
 Username     string    `gorm:"uniqueIndex;size:50;not null" json:"username"`
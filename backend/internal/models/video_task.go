@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// VideoTask statuses. A task moves pending -> processing -> completed|failed,
+// or to cancelled from any non-terminal state.
+const (
+	VideoTaskPending    = "pending"
+	VideoTaskProcessing = "processing"
+	VideoTaskCompleted  = "completed"
+	VideoTaskFailed     = "failed"
+	VideoTaskCancelled  = "cancelled"
+)
+
+type VideoTask struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	ProjectID       uint       `gorm:"not null;index" json:"project_id"`
+	SceneID         uint       `gorm:"index" json:"scene_id"`
+	Provider        string     `gorm:"size:20;not null" json:"provider"`
+	ProviderVideoID string     `gorm:"size:100;index" json:"provider_video_id"`
+	Prompt          string     `gorm:"type:text;not null" json:"prompt"`
+	ImageURL        string     `gorm:"size:500" json:"image_url"`
+	Duration        int        `gorm:"default:5" json:"duration"`
+	AspectRatio     string     `gorm:"size:10;default:16:9" json:"aspect_ratio"`
+	Motion          string     `gorm:"size:20" json:"motion"`
+	Status          string     `gorm:"size:20;default:pending;index" json:"status"`
+	VideoURL        string     `gorm:"size:500" json:"video_url"`
+	AssetID         *uint      `json:"asset_id"`
+	ErrorMessage    string     `gorm:"type:text" json:"error_message"`
+	PollCount       int        `gorm:"default:0" json:"poll_count"`
+	NextPollAt      *time.Time `gorm:"index" json:"next_poll_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	CompletedAt     *time.Time `json:"completed_at"`
+}
+
+// IsTerminal reports whether the task has reached a final status.
+func (t *VideoTask) IsTerminal() bool {
+	switch t.Status {
+	case VideoTaskCompleted, VideoTaskFailed, VideoTaskCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ProviderHTTP names the self-hosted review service
+const ProviderHTTP = "review_service"
+
+// maxResponseBytes bounds answers from the review service
+const maxResponseBytes = 1 << 20
+
+// HTTPReviewer calls the review service at AI_REVIEW_SERVICE_URL, such as a
+// Qwen2-VL wrapper. It posts {"stage", "text", "images"} with images as
+// base64 and expects a verdict like the review prompt's, or {"error"}.
+type HTTPReviewer struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewHTTPReviewer(endpoint string, timeout time.Duration) *HTTPReviewer {
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &HTTPReviewer{endpoint: endpoint, client: &http.Client{Timeout: timeout}}
+}
+
+func (r *HTTPReviewer) Name() string {
+	return ProviderHTTP
+}
+
+// Review sends the content to the service. Transport failures and error
+// statuses are returned as errors; an answer that is not a verdict comes
+// back as the Unparseable verdict.
+func (r *HTTPReviewer) Review(ctx context.Context, req Request) (*Verdict, error) {
+	images := make([]string, 0, len(req.Images))
+	for _, img := range req.Images {
+		images = append(images, base64.StdEncoding.EncodeToString(img))
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"stage":  req.Stage,
+		"text":   req.Text,
+		"images": images,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	raw, err := r.fetch(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	var failure struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+		return nil, fmt.Errorf("review service error: %s", failure.Error)
+	}
+
+	v, err := ParseVerdict(string(raw))
+	if err != nil {
+		return Unparseable(r.Name(), err), nil
+	}
+	v.Reviewer = r.Name()
+	return v, nil
+}
+
+// Health checks the /health endpoint next to AI_REVIEW_SERVICE_URL
+func (r *HTTPReviewer) Health(ctx context.Context) error {
+	u, err := url.Parse(r.endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid AI_REVIEW_SERVICE_URL: %w", err)
+	}
+	u.Path, u.RawQuery = "/health", ""
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	_, err = r.fetch(req)
+	return err
+}
+
+func (r *HTTPReviewer) fetch(req *http.Request) ([]byte, error) {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review service response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(body) > 512 {
+			body = body[:512]
+		}
+		return nil, fmt.Errorf("review service error (status %d): %s", resp.StatusCode, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,127 @@
+// Package moderation reviews prompts, scripts and generated media for unsafe
+// content. Verdicts that cannot be understood are treated as high risk, so
+// nothing passes review by accident.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/llm"
+)
+
+// Risk levels, lowest first
+const (
+	RiskLow    = "LOW"
+	RiskMedium = "MEDIUM"
+	RiskHigh   = "HIGH"
+)
+
+// CategoryUnparseable marks verdicts that were synthesized because the
+// reviewer's answer could not be read
+const CategoryUnparseable = "unparseable"
+
+// ErrUnparseable is returned when a reviewer's answer is not a verdict
+var ErrUnparseable = errors.New("review verdict could not be parsed")
+
+// Request is one piece of content to review. Images are encoded JPEG or PNG
+// files, such as a scene keyframe or frames sampled from a clip.
+type Request struct {
+	Stage  string
+	Text   string
+	Images [][]byte
+}
+
+// Verdict is a reviewer's decision
+type Verdict struct {
+	Approved   bool     `json:"approved"`
+	RiskLevel  string   `json:"risk_level"`
+	Categories []string `json:"categories"`
+	Reason     string   `json:"reason"`
+	Reviewer   string   `json:"reviewer"`
+}
+
+// Held reports whether the content must not be used without a human decision
+func (v *Verdict) Held() bool {
+	return !v.Approved || v.RiskLevel == RiskHigh
+}
+
+// Reviewer is implemented by every review backend
+type Reviewer interface {
+	Name() string
+	Review(ctx context.Context, req Request) (*Verdict, error)
+	Health(ctx context.Context) error
+}
+
+// rawVerdict accepts both the review prompt's "reasons" list and a single
+// "reason"
+type rawVerdict struct {
+	Approved   *bool    `json:"approved"`
+	RiskLevel  string   `json:"risk_level"`
+	Categories []string `json:"categories"`
+	Reasons    []string `json:"reasons"`
+	Reason     string   `json:"reason"`
+}
+
+// ParseVerdict reads a verdict from reviewer output, which may be wrapped in
+// prose or code fences. A missing "approved" field or an unknown risk level
+// is an error wrapping ErrUnparseable; a missing risk level is LOW for
+// approved content and HIGH otherwise.
+func ParseVerdict(raw string) (*Verdict, error) {
+	var rv rawVerdict
+	if err := llm.DecodeJSON(raw, &rv); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnparseable, err)
+	}
+	if rv.Approved == nil {
+		return nil, fmt.Errorf("%w: no approved field", ErrUnparseable)
+	}
+
+	v := &Verdict{Approved: *rv.Approved}
+	switch risk := strings.ToUpper(strings.TrimSpace(rv.RiskLevel)); risk {
+	case RiskLow, RiskMedium, RiskHigh:
+		v.RiskLevel = risk
+	case "":
+		v.RiskLevel = RiskHigh
+		if v.Approved {
+			v.RiskLevel = RiskLow
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown risk level %q", ErrUnparseable, rv.RiskLevel)
+	}
+
+	for _, c := range rv.Categories {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			v.Categories = append(v.Categories, c)
+		}
+	}
+	reasons := rv.Reasons
+	if rv.Reason != "" {
+		reasons = append([]string{rv.Reason}, reasons...)
+	}
+	v.Reason = strings.Join(nonEmpty(reasons), "; ")
+	return v, nil
+}
+
+// Unparseable is the verdict recorded when a reviewer's answer could not be
+// read: not approved, high risk
+func Unparseable(reviewer string, cause error) *Verdict {
+	return &Verdict{
+		Approved:   false,
+		RiskLevel:  RiskHigh,
+		Categories: []string{CategoryUnparseable},
+		Reason:     cause.Error(),
+		Reviewer:   reviewer,
+	}
+}
+
+func nonEmpty(values []string) []string {
+	out := values[:0:0]
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
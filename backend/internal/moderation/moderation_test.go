@@ -0,0 +1,54 @@
+package moderation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want *Verdict
+		err  error
+	}{
+		{
+			name: "full verdict",
+			raw:  `{"approved": true, "risk_level": "medium", "categories": [" Violence ", ""], "reasons": ["blood", " "]}`,
+			want: &Verdict{Approved: true, RiskLevel: RiskMedium, Categories: []string{"violence"}, Reason: "blood"},
+		},
+		{
+			name: "reason and reasons",
+			raw:  `{"approved": false, "risk_level": "HIGH", "reason": "nudity", "reasons": ["minor"]}`,
+			want: &Verdict{Approved: false, RiskLevel: RiskHigh, Reason: "nudity; minor"},
+		},
+		{
+			name: "prose around",
+			raw:  `Verdict: {"approved": true, "risk_level": "LOW"} — looks fine.`,
+			want: &Verdict{Approved: true, RiskLevel: RiskLow},
+		},
+		{
+			name: "fenced",
+			raw:  "```json\n{\"approved\": false, \"risk_level\": \"HIGH\"}\n```",
+			want: &Verdict{Approved: false, RiskLevel: RiskHigh},
+		},
+		{name: "missing risk approved", raw: `{"approved": true}`, want: &Verdict{Approved: true, RiskLevel: RiskLow}},
+		{name: "missing risk not approved", raw: `{"approved": false}`, want: &Verdict{Approved: false, RiskLevel: RiskHigh}},
+		{name: "missing approved", raw: `{"risk_level": "LOW"}`, err: ErrUnparseable},
+		{name: "approved as string", raw: `{"approved": "true", "risk_level": "LOW"}`, err: ErrUnparseable},
+		{name: "unknown risk level", raw: `{"approved": true, "risk_level": "SEVERE"}`, err: ErrUnparseable},
+		{name: "no json", raw: "I cannot review this image.", err: ErrUnparseable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVerdict(tt.raw)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseVerdict(%q) error = %v, want %v", tt.raw, err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseVerdict(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Package music matches library tracks to scenes by mood and genre
+package music
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Moods tracks are matched on; free-form tags and emotions map onto them
+const (
+	MoodTense      = "tense"
+	MoodSad        = "sad"
+	MoodHappy      = "happy"
+	MoodRomantic   = "romantic"
+	MoodCalm       = "calm"
+	MoodMysterious = "mysterious"
+	MoodEpic       = "epic"
+)
+
+// moodWords are matched as substrings, so "非常愤怒" is tense too, unless
+// negated as in "不开心". English words must match a whole word.
+var moodWords = []struct {
+	mood  string
+	words []string
+}{
+	{MoodTense, []string{"紧张", "愤怒", "生气", "恐惧", "害怕", "焦急", "焦虑", "严肃", "冷酷", "冷漠", "警惕", "震惊", "惊恐", "动作", "tense", "angry", "fear", "suspense", "thriller", "action"}},
+	{MoodSad, []string{"悲伤", "难过", "伤心", "失落", "痛苦", "哭", "绝望", "心碎", "遗憾", "委屈", "sad", "melancholy", "melancholic", "grief"}},
+	{MoodHappy, []string{"开心", "高兴", "兴奋", "喜悦", "欢快", "得意", "轻松", "调皮", "喜剧", "happy", "joy", "cheerful", "upbeat", "comedy"}},
+	{MoodRomantic, []string{"温柔", "深情", "害羞", "甜蜜", "心动", "暧昧", "浪漫", "爱情", "romantic", "romance", "love", "tender"}},
+	{MoodCalm, []string{"平静", "淡定", "冷静", "安静", "沉思", "思考", "温暖", "calm", "peaceful", "ambient", "warm"}},
+	{MoodMysterious, []string{"疑惑", "神秘", "怀疑", "困惑", "好奇", "诡异", "悬疑", "mysterious", "mystery", "curious", "eerie"}},
+	{MoodEpic, []string{"激昂", "坚定", "热血", "霸气", "复仇", "史诗", "epic", "heroic", "triumph"}},
+}
+
+// Mood maps an emotion, tag or genre such as 愤怒 or "Suspense" onto one of
+// the moods; anything else comes back trimmed and lower-cased
+func Mood(word string) string {
+	w := strings.ToLower(strings.TrimSpace(word))
+	for _, m := range moodWords {
+		for _, kw := range m.words {
+			if containsMoodWord(w, kw) {
+				return m.mood
+			}
+		}
+	}
+	return w
+}
+
+// negations turn the keyword right after them around: 不害怕 is not tense
+var negations = []string{"不", "没", "没有", "别"}
+
+func containsMoodWord(w, kw string) bool {
+	if kw[0] < 0x80 {
+		for _, field := range strings.FieldsFunc(w, func(r rune) bool {
+			return r > unicode.MaxASCII || !unicode.IsLetter(r)
+		}) {
+			if field == kw {
+				return true
+			}
+		}
+		return false
+	}
+
+	for from := 0; ; {
+		i := strings.Index(w[from:], kw)
+		if i < 0 {
+			return false
+		}
+		i += from
+		if !negated(w[:i]) {
+			return true
+		}
+		from = i + len(kw)
+	}
+}
+
+func negated(before string) bool {
+	for _, n := range negations {
+		if strings.HasSuffix(before, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// Track is what matching needs to know about a library track
+type Track struct {
+	ID    uint
+	Genre string
+	Moods []string
+}
+
+// Match picks a track for every scene from the emotions of its characters.
+// A track scores two points per emotion that fits one of its moods and one
+// each for sharing the project's genre or fitting it. Scenes without
+// emotions and ties keep the previous scene's track so music carries across
+// scenes; other ties favour tracks used less. With an empty library every
+// scene gets 0.
+func Match(tracks []Track, genre string, scenes [][]string) []uint {
+	picks := make([]uint, len(scenes))
+	if len(tracks) == 0 {
+		return picks
+	}
+
+	moods := make([]map[string]bool, len(tracks))
+	for i, t := range tracks {
+		moods[i] = map[string]bool{}
+		for _, tag := range t.Moods {
+			moods[i][Mood(tag)] = true
+		}
+	}
+	genreMood := Mood(genre)
+	used := map[uint]int{}
+	var previous uint
+
+	for s, emotions := range scenes {
+		if previous != 0 && strings.TrimSpace(strings.Join(emotions, "")) == "" {
+			picks[s] = previous
+			used[previous]++
+			continue
+		}
+		best, bestScore := -1, -1
+		for i, t := range tracks {
+			score := 0
+			for _, e := range emotions {
+				if e != "" && moods[i][Mood(e)] {
+					score += 2
+				}
+			}
+			if genre != "" && strings.EqualFold(strings.TrimSpace(t.Genre), strings.TrimSpace(genre)) {
+				score++
+			}
+			if genre != "" && moods[i][genreMood] {
+				score++
+			}
+
+			switch {
+			case score > bestScore:
+				best, bestScore = i, score
+			case score < bestScore:
+			case t.ID == previous:
+				best = i
+			case tracks[best].ID != previous && used[t.ID] < used[tracks[best].ID]:
+				best = i
+			}
+		}
+		picks[s] = tracks[best].ID
+		used[picks[s]]++
+		previous = picks[s]
+	}
+	return picks
+}
@@ -0,0 +1,66 @@
+package music
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMood(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "愤怒", want: MoodTense},
+		{word: "非常愤怒", want: MoodTense},
+		{word: " Suspense ", want: MoodTense},
+		{word: "开心", want: MoodHappy},
+		{word: "不开心", want: "不开心"},
+		{word: "不害怕", want: "不害怕"},
+		{word: "没有害怕", want: "没有害怕"},
+		{word: "不开心又难过", want: MoodSad},
+		{word: "不哭，很开心", want: MoodHappy},
+		{word: "love", want: MoodRomantic},
+		{word: "first love", want: MoodRomantic},
+		{word: "glove", want: "glove"},
+		{word: "melancholy", want: MoodSad},
+		{word: "Action-Comedy", want: MoodTense},
+		{word: "西部", want: "西部"},
+	}
+	for _, tt := range tests {
+		if got := Mood(tt.word); got != tt.want {
+			t.Errorf("Mood(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tracks := []Track{
+		{ID: 1, Genre: "drama", Moods: []string{"sad"}},
+		{ID: 2, Genre: "indie", Moods: []string{"Melancholy"}},
+		{ID: 3, Genre: "thriller", Moods: []string{"tense"}},
+		{ID: 4, Genre: "romance", Moods: []string{"calm", "romantic"}},
+	}
+	tests := []struct {
+		name   string
+		tracks []Track
+		genre  string
+		scenes [][]string
+		want   []uint
+	}{
+		{name: "empty library", scenes: [][]string{{"难过"}, {}}, want: []uint{0, 0}},
+		{name: "emotion fits mood", tracks: tracks, scenes: [][]string{{"愤怒"}, {"温柔"}}, want: []uint{3, 4}},
+		{name: "tie keeps previous track", tracks: tracks, scenes: [][]string{{"紧张"}, {"难过"}, {"伤心"}}, want: []uint{3, 1, 1}},
+		{name: "no emotions carries track", tracks: tracks, scenes: [][]string{{"温柔"}, {}, {" ", ""}}, want: []uint{4, 4, 4}},
+		{name: "tie prefers least used", tracks: tracks, scenes: [][]string{{"难过"}, {"愤怒"}, {"难过"}}, want: []uint{1, 3, 2}},
+		{name: "more fitting emotions win", tracks: tracks, scenes: [][]string{{"平静", "害羞"}, {"难过", "害怕", "恐惧"}}, want: []uint{4, 3}},
+		{name: "genre breaks tie", tracks: tracks, genre: "Indie", scenes: [][]string{{"难过"}, {"愤怒"}}, want: []uint{2, 3}},
+		{name: "genre mood counts", tracks: tracks, genre: "爱情", scenes: [][]string{{}}, want: []uint{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.tracks, tt.genre, tt.scenes); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Job is the unit of work stored in Redis. The heavy payload lives in the
+// models.AITask row referenced by TaskID; the queue only tracks delivery.
+type Job struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	TaskID      uint      `json:"task_id"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+}
+
+// Options configures delivery semantics for a queue
+type Options struct {
+	Name              string
+	VisibilityTimeout time.Duration
+	MaxAttempts       int
+	BaseBackoff       time.Duration
+	MaxBackoff        time.Duration
+}
+
+// Queue is a durable at-least-once job queue on top of Redis.
+//
+// Keys (all prefixed with "queue:<name>:"):
+//   - jobs   HASH  job ID -> job JSON
+//   - ready  ZSET  job ID scored by the time it becomes available
+//   - leased ZSET  job ID scored by its visibility deadline
+//   - dead   LIST  job IDs that exhausted their attempts
+type Queue struct {
+	rdb  *redis.Client
+	opts Options
+}
+
+// enqueueScript makes the job ready before storing it: Redis doesn't undo a
+// script's earlier writes when a later one fails, and a ready ID without a
+// stored job is simply dropped by leaseScript
+var enqueueScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+var leaseScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+local id = ids[1]
+redis.call('ZREM', KEYS[1], id)
+local job = redis.call('HGET', KEYS[3], id)
+if not job then
+	return false
+end
+redis.call('ZADD', KEYS[2], ARGV[2], id)
+return job
+`)
+
+var requeueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('ZADD', KEYS[2], ARGV[1], id)
+end
+return #ids
+`)
+
+func New(rdb *redis.Client, opts Options) *Queue {
+	if opts.Name == "" {
+		opts.Name = "default"
+	}
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = 5 * time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 10 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Minute
+	}
+	return &Queue{rdb: rdb, opts: opts}
+}
+
+// JobID returns the queue ID used for an AITask, so enqueueing is idempotent per task
+func JobID(taskID uint) string {
+	return "task:" + strconv.FormatUint(uint64(taskID), 10)
+}
+
+func (q *Queue) key(name string) string {
+	return "queue:" + q.opts.Name + ":" + name
+}
+
+// VisibilityTimeout is how long a leased job stays invisible before it is redelivered
+func (q *Queue) VisibilityTimeout() time.Duration {
+	return q.opts.VisibilityTimeout
+}
+
+// Enqueue makes a job for the task available immediately. Enqueueing a task
+// that is already queued or leased is a no-op.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, taskID uint) (*Job, error) {
+	job := &Job{
+		ID:          JobID(taskID),
+		Type:        jobType,
+		TaskID:      taskID,
+		MaxAttempts: q.opts.MaxAttempts,
+		EnqueuedAt:  time.Now(),
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	// One script, so a job is never stored without becoming ready
+	err = enqueueScript.Run(ctx, q.rdb,
+		[]string{q.key("jobs"), q.key("ready")},
+		job.ID, data, score(time.Now()),
+	).Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return job, nil
+}
+
+// Has reports whether the task currently has a queued, leased or dead-lettered job
+func (q *Queue) Has(ctx context.Context, taskID uint) (bool, error) {
+	return q.rdb.HExists(ctx, q.key("jobs"), JobID(taskID)).Result()
+}
+
+// Lease takes the next available job and hides it for the visibility timeout.
+// It returns nil, nil when nothing is ready.
+func (q *Queue) Lease(ctx context.Context) (*Job, error) {
+	now := time.Now()
+	res, err := leaseScript.Run(ctx, q.rdb,
+		[]string{q.key("ready"), q.key("leased"), q.key("jobs")},
+		score(now), score(now.Add(q.opts.VisibilityTimeout)),
+	).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+
+	raw, ok := res.(string)
+	if !ok {
+		return nil, nil
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+
+	job.Attempts++
+	if err := q.save(ctx, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Extend pushes the visibility deadline of a leased job forward
+func (q *Queue) Extend(ctx context.Context, job *Job) error {
+	deadline := score(time.Now().Add(q.opts.VisibilityTimeout))
+	return q.rdb.ZAddXX(ctx, q.key("leased"), &redis.Z{Score: deadline, Member: job.ID}).Err()
+}
+
+// Ack removes a successfully processed job
+func (q *Queue) Ack(ctx context.Context, job *Job) error {
+	pipe := q.rdb.TxPipeline()
+	pipe.ZRem(ctx, q.key("leased"), job.ID)
+	pipe.HDel(ctx, q.key("jobs"), job.ID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Retry schedules a failed job again with exponential backoff, or moves it to
+// the dead-letter list once it has used all attempts. It reports whether the
+// job was dead-lettered.
+func (q *Queue) Retry(ctx context.Context, job *Job, cause error) (bool, error) {
+	if cause != nil {
+		job.LastError = cause.Error()
+	}
+	if job.Attempts >= job.MaxAttempts {
+		return true, q.Bury(ctx, job)
+	}
+
+	if err := q.save(ctx, job); err != nil {
+		return false, err
+	}
+	pipe := q.rdb.TxPipeline()
+	pipe.ZRem(ctx, q.key("leased"), job.ID)
+	pipe.ZAdd(ctx, q.key("ready"), &redis.Z{Score: score(time.Now().Add(q.Backoff(job.Attempts))), Member: job.ID})
+	_, err := pipe.Exec(ctx)
+	return false, err
+}
+
+// Release hands a leased job back right away without using up an attempt,
+// e.g. when a worker shuts down mid-run
+func (q *Queue) Release(ctx context.Context, job *Job) error {
+	if job.Attempts > 0 {
+		job.Attempts--
+	}
+	if err := q.save(ctx, job); err != nil {
+		return err
+	}
+	pipe := q.rdb.TxPipeline()
+	pipe.ZRem(ctx, q.key("leased"), job.ID)
+	pipe.ZAdd(ctx, q.key("ready"), &redis.Z{Score: score(time.Now()), Member: job.ID})
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Bury moves a job to the dead-letter list
+func (q *Queue) Bury(ctx context.Context, job *Job) error {
+	if err := q.save(ctx, job); err != nil {
+		return err
+	}
+	pipe := q.rdb.TxPipeline()
+	pipe.ZRem(ctx, q.key("leased"), job.ID)
+	pipe.ZRem(ctx, q.key("ready"), job.ID)
+	pipe.LPush(ctx, q.key("dead"), job.ID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RequeueExpired makes leased jobs whose visibility deadline passed available
+// again, which is how jobs held by a crashed worker get redelivered.
+func (q *Queue) RequeueExpired(ctx context.Context) (int, error) {
+	n, err := requeueScript.Run(ctx, q.rdb,
+		[]string{q.key("leased"), q.key("ready")},
+		score(time.Now()),
+	).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+	return n, nil
+}
+
+// DeadLetters returns the most recently buried jobs
+func (q *Queue) DeadLetters(ctx context.Context, limit int64) ([]*Job, error) {
+	ids, err := q.rdb.LRange(ctx, q.key("dead"), 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	values, err := q.rdb.HMGet(ctx, q.key("jobs"), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for _, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err == nil {
+			jobs = append(jobs, &job)
+		}
+	}
+	return jobs, nil
+}
+
+// Backoff returns the delay before the given attempt is retried, with jitter
+func (q *Queue) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := q.opts.BaseBackoff
+	for i := 1; i < attempt && delay < q.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.opts.MaxBackoff {
+		delay = q.opts.MaxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
+
+func (q *Queue) save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.rdb.HSet(ctx, q.key("jobs"), job.ID, data).Err()
+}
+
+func score(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
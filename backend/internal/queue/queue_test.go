@@ -0,0 +1,220 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestQueue(t *testing.T, opts Options) (*Queue, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return New(rdb, opts), mr
+}
+
+func mustLease(t *testing.T, q *Queue) *Job {
+	t.Helper()
+	job, err := q.Lease(context.Background())
+	if err != nil {
+		t.Fatalf("Lease: %v", err)
+	}
+	if job == nil {
+		t.Fatal("Lease returned no job")
+	}
+	return job
+}
+
+func expectEmpty(t *testing.T, q *Queue) {
+	t.Helper()
+	job, err := q.Lease(context.Background())
+	if err != nil {
+		t.Fatalf("Lease: %v", err)
+	}
+	if job != nil {
+		t.Fatalf("Lease returned %s, want nothing ready", job.ID)
+	}
+}
+
+func TestEnqueueLeaseAck(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, Options{Name: "test"})
+
+	if _, err := q.Enqueue(ctx, "script", 7); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	// A second enqueue of the same task is a no-op
+	if _, err := q.Enqueue(ctx, "script", 7); err != nil {
+		t.Fatalf("Enqueue again: %v", err)
+	}
+	if members, _ := mr.ZMembers(q.key("ready")); len(members) != 1 {
+		t.Fatalf("ready set = %v, want one job", members)
+	}
+
+	job := mustLease(t, q)
+	if job.ID != JobID(7) || job.Type != "script" || job.TaskID != 7 || job.Attempts != 1 {
+		t.Fatalf("leased job = %+v", job)
+	}
+	expectEmpty(t, q)
+	if has, _ := q.Has(ctx, 7); !has {
+		t.Fatal("Has = false for a leased job")
+	}
+
+	if err := q.Ack(ctx, job); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if has, _ := q.Has(ctx, 7); has {
+		t.Fatal("Has = true after Ack")
+	}
+	if members, _ := mr.ZMembers(q.key("leased")); len(members) != 0 {
+		t.Fatalf("leased set after Ack = %v", members)
+	}
+}
+
+func TestEnqueueFailureStoresNothing(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, Options{Name: "test"})
+	// A ready key of the wrong type makes the ZADD fail inside the script
+	mr.Set(q.key("ready"), "not a zset")
+
+	if _, err := q.Enqueue(ctx, "script", 7); err == nil {
+		t.Fatal("Enqueue succeeded with a broken ready set")
+	}
+	if has, _ := q.Has(ctx, 7); has {
+		t.Fatal("job stored although it never became ready")
+	}
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestQueue(t, Options{Name: "test", VisibilityTimeout: 50 * time.Millisecond})
+	if _, err := q.Enqueue(ctx, "image", 1); err != nil {
+		t.Fatal(err)
+	}
+	job := mustLease(t, q)
+
+	if n, err := q.RequeueExpired(ctx); err != nil || n != 0 {
+		t.Fatalf("RequeueExpired before the deadline = %d, %v", n, err)
+	}
+
+	// Extending keeps a slow job invisible past its first deadline
+	time.Sleep(30 * time.Millisecond)
+	if err := q.Extend(ctx, job); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n, err := q.RequeueExpired(ctx); err != nil || n != 0 {
+		t.Fatalf("RequeueExpired after Extend = %d, %v", n, err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if n, err := q.RequeueExpired(ctx); err != nil || n != 1 {
+		t.Fatalf("RequeueExpired after the deadline = %d, %v", n, err)
+	}
+	again := mustLease(t, q)
+	if again.ID != job.ID || again.Attempts != 2 {
+		t.Fatalf("redelivered job = %+v, want %s on attempt 2", again, job.ID)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestQueue(t, Options{Name: "test", MaxAttempts: 3, BaseBackoff: 50 * time.Millisecond})
+	if _, err := q.Enqueue(ctx, "video", 2); err != nil {
+		t.Fatal(err)
+	}
+	job := mustLease(t, q)
+
+	buried, err := q.Retry(ctx, job, errors.New("provider timeout"))
+	if err != nil || buried {
+		t.Fatalf("Retry = %v, %v; want a retry", buried, err)
+	}
+	expectEmpty(t, q)
+
+	time.Sleep(80 * time.Millisecond)
+	job = mustLease(t, q)
+	if job.Attempts != 2 || job.LastError != "provider timeout" {
+		t.Fatalf("retried job = %+v", job)
+	}
+}
+
+func TestRetryDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	q, mr := newTestQueue(t, Options{Name: "test", MaxAttempts: 2, BaseBackoff: time.Millisecond})
+	if _, err := q.Enqueue(ctx, "render", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	job := mustLease(t, q)
+	if buried, err := q.Retry(ctx, job, errors.New("first")); err != nil || buried {
+		t.Fatalf("first Retry = %v, %v", buried, err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	job = mustLease(t, q)
+	buried, err := q.Retry(ctx, job, errors.New("ffmpeg crashed"))
+	if err != nil || !buried {
+		t.Fatalf("last Retry = %v, %v; want the job buried", buried, err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	expectEmpty(t, q)
+	if members, _ := mr.ZMembers(q.key("leased")); len(members) != 0 {
+		t.Fatalf("leased set after Bury = %v", members)
+	}
+	// Dead jobs stay known, so recovery doesn't enqueue them again
+	if has, _ := q.Has(ctx, 3); !has {
+		t.Fatal("Has = false for a dead-lettered job")
+	}
+	dead, err := q.DeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].ID != JobID(3) || dead[0].Attempts != 2 || dead[0].LastError != "ffmpeg crashed" {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	ctx := context.Background()
+	q, _ := newTestQueue(t, Options{Name: "test", MaxAttempts: 1})
+	if _, err := q.Enqueue(ctx, "script", 4); err != nil {
+		t.Fatal(err)
+	}
+	job := mustLease(t, q)
+	if err := q.Release(ctx, job); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	// The released job is ready again and its attempt was not counted
+	job = mustLease(t, q)
+	if job.Attempts != 1 {
+		t.Fatalf("attempts after release = %d, want 1", job.Attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := New(nil, Options{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{10, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			got := q.Backoff(tt.attempt)
+			if got < tt.base || got > tt.base+tt.base/5 {
+				t.Fatalf("Backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.base, tt.base+tt.base/5)
+			}
+		}
+	}
+}
@@ -1,23 +1,43 @@
 package router
 
 import (
+	"net/url"
+
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 	"github.com/richard9219/3kstory/internal/config"
 	"github.com/richard9219/3kstory/internal/handlers"
 	"github.com/richard9219/3kstory/internal/middleware"
 	"github.com/richard9219/3kstory/internal/services"
+	"github.com/richard9219/3kstory/internal/storage"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(r *gin.Engine, db *gorm.DB, rdb *redis.Client, cfg *config.Config) {
-	aiService := services.NewAIService(cfg)
-	projectService := services.NewProjectService(db, aiService)
-	videoService := services.NewVideoService(cfg)
-
+func SetupRoutes(r *gin.Engine, db *gorm.DB, svc *services.Services, cfg *config.Config) {
 	authHandler := handlers.NewAuthHandler(db, cfg)
-	projectHandler := handlers.NewProjectHandler(projectService, db)
-	videoHandler := handlers.NewVideoHandler(videoService, projectService)
+	projectHandler := handlers.NewProjectHandler(svc.Project, svc.Task, svc.Assets, svc.Versions, db)
+	videoHandler := handlers.NewVideoHandler(svc.Video, svc.Project, svc.Task, svc.Prompt, svc.Characters)
+	taskHandler := handlers.NewTaskHandler(svc.Task)
+	eventHandler := handlers.NewEventHandler(db, svc.Events)
+	adminHandler := handlers.NewAdminHandler(svc.Health, svc.AI)
+	promptHandler := handlers.NewPromptHandler(svc.Prompt, db)
+	renderHandler := handlers.NewRenderHandler(svc.Render, db)
+	speechHandler := handlers.NewSpeechHandler(svc.Speech, db)
+	subtitleHandler := handlers.NewSubtitleHandler(svc.Subtitles, db)
+	musicHandler := handlers.NewMusicHandler(svc.Music, db)
+	reviewHandler := handlers.NewReviewHandler(svc.Review, svc.Moderation, db)
+	moderationHandler := handlers.NewModerationHandler(svc.Moderation)
+	characterHandler := handlers.NewCharacterHandler(svc.Characters, db)
+	sceneHandler := handlers.NewSceneHandler(svc.Scenes, db)
+	versionHandler := handlers.NewVersionHandler(svc.Versions, svc.Scenes, db)
+	seriesHandler := handlers.NewSeriesHandler(svc.Series, svc.Characters)
+
+	// With local storage the API server also serves the media directory,
+	// under the path of MEDIA_BASE_URL
+	if cfg.Storage.Driver == storage.DriverLocal {
+		if u, err := url.Parse(cfg.Storage.BaseURL); err == nil && u.Path != "" && u.Path != "/" {
+			r.Static(u.Path, cfg.Storage.LocalRoot)
+		}
+	}
 
 	v1 := r.Group("/api/v1")
 	{
@@ -27,6 +47,14 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, rdb *redis.Client, cfg *config.Conf
 			auth.POST("/login", authHandler.Login)
 		}
 
+		// Event streams accept the token as a query parameter as well, so
+		// they are kept out of the authorized group
+		streams := v1.Group("")
+		streams.Use(middleware.StreamAuthRequired(cfg))
+		{
+			streams.GET("/projects/:id/events", eventHandler.StreamProjectEvents)
+		}
+
 		authorized := v1.Group("")
 		authorized.Use(middleware.AuthRequired(cfg))
 		{
@@ -44,13 +72,86 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, rdb *redis.Client, cfg *config.Conf
 				projects.PUT("/:id", projectHandler.UpdateProject)
 				projects.DELETE("/:id", projectHandler.DeleteProject)
 				projects.GET("/:id/scenes", projectHandler.GetScenes)
+				projects.POST("/:id/scenes", sceneHandler.InsertScene)
+				projects.POST("/:id/scenes/reorder", sceneHandler.ReorderScenes)
+				projects.POST("/:id/scenes/merge", sceneHandler.MergeScenes)
+				projects.GET("/:id/scenes/:sceneID", sceneHandler.GetScene)
+				projects.PUT("/:id/scenes/:sceneID", sceneHandler.UpdateScene)
+				projects.DELETE("/:id/scenes/:sceneID", sceneHandler.DeleteScene)
+				projects.POST("/:id/scenes/:sceneID/split", sceneHandler.SplitScene)
+				projects.POST("/:id/scenes/:sceneID/regenerate", sceneHandler.RegenerateScene)
+				projects.GET("/:id/scenes/:sceneID/revisions", sceneHandler.ListRevisions)
+				projects.POST("/:id/scenes/:sceneID/revisions/:revisionID/restore", sceneHandler.RestoreRevision)
+				projects.GET("/:id/versions", versionHandler.ListVersions)
+				projects.POST("/:id/versions", versionHandler.CreateVersion)
+				projects.GET("/:id/versions/diff", versionHandler.DiffVersions)
+				projects.GET("/:id/versions/:version", versionHandler.GetVersion)
+				projects.POST("/:id/versions/:version/restore", versionHandler.RestoreVersion)
+				projects.GET("/:id/assets", projectHandler.ListAssets)
 				projects.POST("/:id/generate", projectHandler.GenerateScenes)
+				projects.POST("/:id/render", renderHandler.RenderProject)
+				projects.GET("/:id/subtitles.srt", subtitleHandler.GetSRT)
+				projects.GET("/:id/music", musicHandler.ListTracks)
+				projects.POST("/:id/music", musicHandler.UploadTrack)
+				projects.POST("/:id/music/match", musicHandler.MatchMusic)
+				projects.PUT("/:id/music/:trackID", musicHandler.UpdateTrack)
+				projects.DELETE("/:id/music/:trackID", musicHandler.DeleteTrack)
+				projects.PUT("/:id/scenes/:sceneID/music", musicHandler.SetSceneMusic)
+				projects.GET("/:id/reviews", reviewHandler.ListProjectReviews)
+				projects.POST("/:id/reviews/:reviewID/appeal", reviewHandler.AppealReview)
+				projects.POST("/:id/publish", projectHandler.PublishProject)
+				projects.GET("/:id/characters", characterHandler.ListCharacters)
+				projects.POST("/:id/characters", characterHandler.CreateCharacter)
+				projects.PUT("/:id/characters/:characterID", characterHandler.UpdateCharacter)
+				projects.DELETE("/:id/characters/:characterID", characterHandler.DeleteCharacter)
+				projects.POST("/:id/characters/:characterID/references", characterHandler.UploadReference)
+				projects.GET("/:id/voices", speechHandler.GetVoices)
+				projects.PUT("/:id/voices", speechHandler.SetVoices)
+				projects.POST("/:id/speech", speechHandler.GenerateSpeech)
+				projects.GET("/:id/scenes/:sceneID/dialogue", speechHandler.GetSceneDialogue)
+				projects.GET("/:id/prompts", promptHandler.GetProjectPrompts)
+				projects.PUT("/:id/prompts/:name", promptHandler.PinProjectPrompt)
+				projects.DELETE("/:id/prompts/:name", promptHandler.UnpinProjectPrompt)
 
 				// Video generation endpoints (Milestone 1.1)
 				projects.POST("/:id/generate-video", videoHandler.GenerateVideo)
 				projects.POST("/:id/video-status", videoHandler.GetVideoStatus)
 				projects.GET("/:id/videos", videoHandler.ListVideos)
-				projects.DELETE("/:id/video/:videoID", videoHandler.CancelVideoGeneration)
+				projects.DELETE("/:id/video/:taskID", videoHandler.CancelVideoGeneration)
+			}
+
+			series := authorized.Group("/series")
+			{
+				series.POST("", seriesHandler.CreateSeries)
+				series.GET("", seriesHandler.ListSeries)
+				series.GET("/:id", seriesHandler.GetSeries)
+				series.PUT("/:id", seriesHandler.UpdateSeries)
+				series.DELETE("/:id", seriesHandler.DeleteSeries)
+				series.GET("/:id/characters", seriesHandler.ListSeriesCharacters)
+				series.POST("/:id/episodes", seriesHandler.NextEpisode)
+				series.POST("/:id/season", seriesHandler.GenerateSeason)
+			}
+
+			tasks := authorized.Group("/tasks")
+			{
+				tasks.GET("/:taskID", taskHandler.GetTask)
+			}
+
+			admin := authorized.Group("/admin")
+			admin.Use(middleware.AdminRequired(db))
+			{
+				admin.GET("/providers", adminHandler.ListProviders)
+
+				admin.GET("/prompts", promptHandler.ListTemplates)
+				admin.POST("/prompts", promptHandler.CreateTemplate)
+				admin.GET("/prompts/:templateID", promptHandler.GetTemplate)
+				admin.POST("/prompts/:templateID/activate", promptHandler.ActivateTemplate)
+				admin.DELETE("/prompts/:templateID", promptHandler.DeleteTemplate)
+
+				admin.GET("/reviews", moderationHandler.ListReviews)
+				admin.GET("/reviews/:reviewID", moderationHandler.GetReview)
+				admin.POST("/reviews/:reviewID/approve", moderationHandler.ApproveReview)
+				admin.POST("/reviews/:reviewID/reject", moderationHandler.RejectReview)
 			}
 		}
 	}
@@ -1,223 +1,331 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/health"
+	"github.com/richard9219/3kstory/internal/imagegen"
+	"github.com/richard9219/3kstory/internal/llm"
+	"github.com/richard9219/3kstory/internal/tts"
 )
 
 type AIService struct {
-	cfg *config.Config
+	cfg      *config.Config
+	registry *llm.Registry
+	router   *llm.Router
+	images   imagegen.ImageProvider
+	speech   tts.Provider
+	health   *health.Monitor
 }
 
-func NewAIService(cfg *config.Config) *AIService {
-	return &AIService{cfg: cfg}
-}
-
-func (s *AIService) GenerateScript(ctx context.Context, prompt string) (*ScriptResult, error) {
-	switch strings.ToLower(strings.TrimSpace(s.cfg.AI.AIProvider)) {
-	case "local_vllm":
-		return s.generateScriptWithVLLM(ctx, prompt)
-	case "local_ollama":
-		return s.generateScriptWithOllama(ctx, prompt)
-	case "hybrid":
-		// Minimal failover: vLLM -> Ollama -> cloud_qwen
-		if res, err := s.generateScriptWithVLLM(ctx, prompt); err == nil {
-			return res, nil
-		}
-		if res, err := s.generateScriptWithOllama(ctx, prompt); err == nil {
-			return res, nil
-		}
-		return s.generateScriptWithCloudQwen(ctx, prompt)
-	case "cloud_qwen", "":
-		fallthrough
-	default:
-		return s.generateScriptWithCloudQwen(ctx, prompt)
-	}
-}
-
-func (s *AIService) scriptSystemPrompt() string {
-	return `你是一个专业的短剧编剧和分镜导演。你必须只输出严格JSON，不要输出任何解释、Markdown、代码块标记。
-
-JSON Schema（必须完全符合）：
-{
-  "title": "string",
-  "genre": "string",
-  "style": "string",
-  "scenes": [
-    {
-      "scene_number": 1,
-      "title": "string",
-      "location": "string",
-      "characters": [{"name": "string", "emotion": "string"}],
-      "dialogue": "string",
-      "shot_type": "string",
-      "duration": 10
-    }
-  ]
-}`
-}
-
-func (s *AIService) generateScriptWithCloudQwen(ctx context.Context, prompt string) (*ScriptResult, error) {
-	requestBody := map[string]interface{}{
-		"model": "qwen-plus",
-		"messages": []map[string]string{
-			{"role": "system", "content": s.scriptSystemPrompt()},
-			{"role": "user", "content": prompt},
-		},
-		"temperature": 0.8,
-	}
-
-	jsonData, _ := json.Marshal(requestBody)
-	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.AI.QwenAPIBase+"/services/aigc/text-generation/generation", bytes.NewBuffer(jsonData))
+func NewAIService(cfg *config.Config, monitor *health.Monitor) *AIService {
+	registry, router, err := llm.NewFromConfig(cfg.AI)
 	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if s.cfg.AI.QwenAPIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+s.cfg.AI.QwenAPIKey)
+		log.Fatalf("Invalid text provider configuration: %v", err)
 	}
-
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
+	images, err := imagegen.NewFromConfig(cfg.AI)
 	if err != nil {
-		return nil, err
+		log.Fatalf("Invalid image provider configuration: %v", err)
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("cloud_qwen API error (status %d): %s", resp.StatusCode, string(body))
+	speech, err := tts.NewFromConfig(cfg.AI)
+	if err != nil {
+		log.Fatalf("Invalid speech provider configuration: %v", err)
 	}
 
-	var apiResp struct {
-		Output struct {
-			Text string `json:"text"`
-		} `json:"output"`
+	// Only routed providers are probed, so unused backends don't show up as down
+	if monitor != nil {
+		for _, name := range router.Candidates() {
+			p, _ := registry.Get(name)
+			monitor.Register(name, health.KindText, p.Health)
+		}
+		router.SetGate(monitor)
+		monitor.Register(images.Name(), health.KindImage, images.Health)
+		monitor.Register(speech.Name(), health.KindSpeech, speech.Health)
 	}
+	return &AIService{cfg: cfg, registry: registry, router: router, images: images, speech: speech, health: monitor}
+}
 
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, err
-	}
+// TextProviders exposes the provider registry, e.g. for health reporting
+func (s *AIService) TextProviders() *llm.Registry {
+	return s.registry
+}
 
-	return parseScriptResult(apiResp.Output.Text)
+// TextRouter exposes the routing configuration, e.g. for health reporting
+func (s *AIService) TextRouter() *llm.Router {
+	return s.router
 }
 
-func (s *AIService) generateScriptWithVLLM(ctx context.Context, prompt string) (*ScriptResult, error) {
-	baseURL := strings.TrimRight(s.cfg.AI.VLLMBaseURL, "/")
-	endpoint := baseURL + "/v1/chat/completions"
+// GenerateScript asks the routed text providers for a storyboard script. With
+// several candidates configured, every provider's failure is kept in the
+// returned llm.FailoverError. Output that fails validation is sent back to
+// the model with the validation errors, up to SCRIPT_VALIDATION_RETRIES times.
+func (s *AIService) GenerateScript(ctx context.Context, system, prompt string) (*ScriptResult, error) {
+	messages := scriptMessages(system, prompt)
+	for attempt := 0; ; attempt++ {
+		resp, err := s.router.Chat(ctx, s.scriptRequest(messages))
+		if err != nil {
+			return nil, err
+		}
 
-	requestBody := map[string]interface{}{
-		"model": s.cfg.AI.VLLMModelName,
-		"messages": []map[string]string{
-			{"role": "system", "content": s.scriptSystemPrompt()},
-			{"role": "user", "content": prompt},
-		},
-		"temperature": 0.8,
-		"max_tokens":  s.cfg.AI.VLLMMaxTokens,
+		result, problems := checkScript(resp.Content)
+		if len(problems) == 0 {
+			return result, nil
+		}
+		if attempt >= s.cfg.AI.ScriptValidationRetries {
+			return result, &ScriptValidationError{Problems: problems}
+		}
+		log.Printf("script attempt %d failed validation, re-prompting: %s", attempt+1, strings.Join(problems, "; "))
+		messages = append(messages,
+			llm.Message{Role: "assistant", Content: resp.Content},
+			llm.Message{Role: "user", Content: scriptRepairPrompt(problems)},
+		)
 	}
+}
 
-	jsonData, _ := json.Marshal(requestBody)
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
+// RewriteScene asks the routed text providers for a single scene, validated
+// and repaired like GenerateScript. system is the rendered scene template,
+// which carries the surrounding scenes; prompt is the user's instruction.
+func (s *AIService) RewriteScene(ctx context.Context, system, prompt string) (*SceneDetail, error) {
+	messages := scriptMessages(system, prompt)
+	for attempt := 0; ; attempt++ {
+		resp, err := s.router.Chat(ctx, llm.ChatRequest{
+			Messages:    messages,
+			Temperature: 0.8,
+			JSONSchema:  sceneSchema,
+			SchemaName:  "storyboard_scene",
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	client := &http.Client{Timeout: time.Duration(s.cfg.AI.VLLMTimeout) * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+		var scene SceneDetail
+		var problems []string
+		if err := llm.DecodeJSON(resp.Content, &scene); err != nil {
+			problems = []string{fmt.Sprintf("output is not valid JSON: %v", err)}
+		} else {
+			problems = validateScene(scene)
+		}
+		if len(problems) == 0 {
+			return &scene, nil
+		}
+		if attempt >= s.cfg.AI.ScriptValidationRetries {
+			return nil, &ScriptValidationError{Problems: problems}
+		}
+		log.Printf("scene rewrite attempt %d failed validation, re-prompting: %s", attempt+1, strings.Join(problems, "; "))
+		messages = append(messages,
+			llm.Message{Role: "assistant", Content: resp.Content},
+			llm.Message{Role: "user", Content: scriptRepairPrompt(problems)},
+		)
 	}
-	defer resp.Body.Close()
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("vLLM API error (status %d): %s", resp.StatusCode, string(body))
-	}
+// SceneFunc receives each scene as soon as it has been streamed. header
+// carries the title, genre and style seen so far.
+type SceneFunc func(header *ScriptResult, scene SceneDetail) error
+
+// StreamScript streams the storyboard script and hands every finished scene
+// to onScene while the model is still writing the rest. Scenes already
+// delivered stay delivered when the stream later fails. Like GenerateScript,
+// a script that fails validation is re-prompted; corrected scenes are handed
+// to onScene again under the same scene number.
+func (s *AIService) StreamScript(ctx context.Context, system, prompt string, onScene SceneFunc) (*ScriptResult, error) {
+	messages := scriptMessages(system, prompt)
+	for attempt := 0; ; attempt++ {
+		streamed, content, err := s.streamScriptOnce(ctx, messages, onScene)
+		if err != nil {
+			return streamed, err
+		}
 
-	var apiResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, err
-	}
-	if len(apiResp.Choices) == 0 {
-		return nil, fmt.Errorf("vLLM returned no choices")
-	}
+		result, problems := checkScript(content)
+		if result != nil {
+			// The streaming scanner skips scenes it can't decode; the repaired
+			// document may still contain them
+			delivered := map[int]bool{}
+			for _, scene := range streamed.Scenes {
+				delivered[scene.SceneNumber] = true
+			}
+			for _, scene := range result.Scenes {
+				if !delivered[scene.SceneNumber] && len(validateScene(scene)) == 0 {
+					if err := onScene(result, scene); err != nil {
+						return result, err
+					}
+				}
+			}
+		} else {
+			result = streamed
+		}
 
-	return parseScriptResult(apiResp.Choices[0].Message.Content)
+		if len(problems) == 0 {
+			return result, nil
+		}
+		if attempt >= s.cfg.AI.ScriptValidationRetries {
+			return result, &ScriptValidationError{Problems: problems}
+		}
+		log.Printf("streamed script attempt %d failed validation, re-prompting: %s", attempt+1, strings.Join(problems, "; "))
+		messages = append(messages,
+			llm.Message{Role: "assistant", Content: content},
+			llm.Message{Role: "user", Content: scriptRepairPrompt(problems)},
+		)
+	}
 }
 
-func (s *AIService) generateScriptWithOllama(ctx context.Context, prompt string) (*ScriptResult, error) {
-	baseURL := strings.TrimRight(s.cfg.AI.OLLAMABaseURL, "/")
-	endpoint := baseURL + "/api/generate"
-
-	// Ollama 的 /api/generate 不是 chat messages；这里把系统约束拼到 prompt 前面。
-	fullPrompt := s.scriptSystemPrompt() + "\n\n用户需求：\n" + prompt
-
-	requestBody := map[string]interface{}{
-		"model":  s.cfg.AI.OLLAMAModelName,
-		"prompt": fullPrompt,
-		"stream": false,
-		"options": map[string]interface{}{
-			"num_predict": s.cfg.AI.OLLAMAMaxTokens,
-			"temperature": 0.8,
+// streamScriptOnce runs one streamed generation. It returns the scenes that
+// passed validation and were delivered, plus the raw model output.
+func (s *AIService) streamScriptOnce(ctx context.Context, messages []llm.Message, onScene SceneFunc) (*ScriptResult, string, error) {
+	result := &ScriptResult{}
+	parser := &scriptStreamParser{
+		onField: func(key string, raw []byte) error {
+			if key == "characters" {
+				var characters []CharacterProfile
+				if err := json.Unmarshal([]byte(llm.RepairJSON(string(raw))), &characters); err != nil {
+					log.Printf("skipping malformed character list in streamed script: %v", err)
+					return nil
+				}
+				result.Characters = characters
+				return nil
+			}
+			var value string
+			if json.Unmarshal(raw, &value) != nil {
+				return nil
+			}
+			switch key {
+			case "title":
+				result.Title = value
+			case "genre":
+				result.Genre = value
+			case "style":
+				result.Style = value
+			case "summary":
+				result.Summary = value
+			}
+			return nil
+		},
+		onScene: func(index int, raw []byte) error {
+			var scene SceneDetail
+			if err := json.Unmarshal([]byte(llm.RepairJSON(string(raw))), &scene); err != nil {
+				log.Printf("skipping malformed scene %d in streamed script: %v", index+1, err)
+				return nil
+			}
+			if scene.SceneNumber == 0 {
+				scene.SceneNumber = index + 1
+			}
+			if problems := validateScene(scene); len(problems) > 0 {
+				log.Printf("holding back invalid scene %d in streamed script: %s", index+1, strings.Join(problems, "; "))
+				return nil
+			}
+			result.Scenes = append(result.Scenes, scene)
+			return onScene(result, scene)
 		},
 	}
 
-	jsonData, _ := json.Marshal(requestBody)
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	resp, err := s.router.Stream(ctx, s.scriptRequest(messages), parser.Write)
 	if err != nil {
-		return nil, err
+		return result, "", err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return result, resp.Content, nil
+}
 
-	client := &http.Client{Timeout: time.Duration(s.cfg.AI.OLLAMATimeout) * time.Second}
-	resp, err := client.Do(req)
+// checkScript parses raw model output and returns the validation problems,
+// phrased so they can be sent back to the model
+func checkScript(raw string) (*ScriptResult, []string) {
+	result, err := parseScriptResult(raw)
 	if err != nil {
-		return nil, err
+		return nil, []string{fmt.Sprintf("output is not valid JSON: %v", err)}
 	}
-	defer resp.Body.Close()
+	return result, validateScript(result)
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("ollama API error (status %d): %s", resp.StatusCode, string(body))
+// scriptRequest attaches the ScriptResult schema, so backends that support
+// constrained decoding can only produce well-formed scripts
+func (s *AIService) scriptRequest(messages []llm.Message) llm.ChatRequest {
+	return llm.ChatRequest{
+		Messages:    messages,
+		Temperature: 0.8,
+		JSONSchema:  scriptSchema,
+		SchemaName:  "storyboard_script",
 	}
+}
 
-	var apiResp struct {
-		Response string `json:"response"`
-	}
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, err
+// scriptMessages starts the conversation; system is the rendered script template
+func scriptMessages(system, prompt string) []llm.Message {
+	return []llm.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: prompt},
 	}
-
-	return parseScriptResult(apiResp.Response)
 }
 
 func parseScriptResult(raw string) (*ScriptResult, error) {
-	trimmed := strings.TrimSpace(raw)
 	var result ScriptResult
-	if err := json.Unmarshal([]byte(trimmed), &result); err != nil {
+	if err := llm.DecodeJSON(raw, &result); err != nil {
 		return nil, fmt.Errorf("failed to parse AI response as ScriptResult JSON: %w", err)
 	}
 	return &result, nil
 }
 
-func (s *AIService) GenerateImage(ctx context.Context, prompt string) (string, error) {
-	return fmt.Sprintf("https://placeholder.com/800x600?text=%s", prompt), nil
+// ImageProvider reports which image backend is configured
+func (s *AIService) ImageProvider() string {
+	return s.images.Name()
+}
+
+// GenerateImage renders a keyframe with the configured image backend. Unset
+// size, steps and negative prompt fall back to the IMAGE_* settings.
+func (s *AIService) GenerateImage(ctx context.Context, req imagegen.Request) (*imagegen.Result, error) {
+	if req.Width == 0 || req.Height == 0 {
+		req.Width, req.Height = s.cfg.AI.ImageWidth, s.cfg.AI.ImageHeight
+	}
+	if req.Steps == 0 {
+		req.Steps = s.cfg.AI.ImageSteps
+	}
+	if req.NegativePrompt == "" {
+		req.NegativePrompt = s.cfg.AI.ImageNegativePrompt
+	}
+
+	name := s.images.Name()
+	if s.health != nil && !s.health.Allow(name) {
+		return nil, fmt.Errorf("%s: %w", name, ErrProviderUnavailable)
+	}
+	start := time.Now()
+	result, err := s.images.Generate(ctx, req)
+	if s.health != nil {
+		s.health.Record(name, err, time.Since(start))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("image generation failed: %w", err)
+	}
+	return result, nil
+}
+
+// SpeechProvider reports which speech backend is configured
+func (s *AIService) SpeechProvider() string {
+	return s.speech.Name()
+}
+
+// SynthesizeSpeech speaks one dialogue line with the configured speech
+// backend; an unset speed falls back to TTS_SPEED
+func (s *AIService) SynthesizeSpeech(ctx context.Context, req tts.Request) (*tts.Result, error) {
+	if req.Speed <= 0 {
+		req.Speed = s.cfg.AI.TTSSpeed
+	}
+
+	name := s.speech.Name()
+	if s.health != nil && !s.health.Allow(name) {
+		return nil, fmt.Errorf("%s: %w", name, ErrProviderUnavailable)
+	}
+	start := time.Now()
+	result, err := s.speech.Synthesize(ctx, req)
+	if s.health != nil {
+		s.health.Record(name, err, time.Since(start))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("speech synthesis failed: %w", err)
+	}
+	return result, nil
 }
 
 func (s *AIService) GenerateVideo(ctx context.Context, prompt string) (string, error) {
@@ -225,23 +333,34 @@ func (s *AIService) GenerateVideo(ctx context.Context, prompt string) (string, e
 }
 
 type ScriptResult struct {
-	Title  string        `json:"title"`
-	Genre  string        `json:"genre"`
-	Style  string        `json:"style"`
-	Scenes []SceneDetail `json:"scenes"`
+	Title      string             `json:"title" jsonschema:"required,minLength=1"`
+	Genre      string             `json:"genre"`
+	Style      string             `json:"style"`
+	Summary    string             `json:"summary"` // recap of a series episode
+	Characters []CharacterProfile `json:"characters"`
+	Scenes     []SceneDetail      `json:"scenes" jsonschema:"required,minItems=1"`
+}
+
+// CharacterProfile is the script's description of a character, kept in the
+// project's character bible
+type CharacterProfile struct {
+	Name       string `json:"name" jsonschema:"required,minLength=1"`
+	Age        string `json:"age"`
+	Appearance string `json:"appearance"`
+	Wardrobe   string `json:"wardrobe"`
 }
 
 type SceneDetail struct {
-	SceneNumber int          `json:"scene_number"`
-	Title       string       `json:"title"`
-	Location    string       `json:"location"`
+	SceneNumber int          `json:"scene_number" jsonschema:"required,minimum=1"`
+	Title       string       `json:"title" jsonschema:"required,minLength=1"`
+	Location    string       `json:"location" jsonschema:"required,minLength=1"`
 	Characters  []CharDetail `json:"characters"`
-	Dialogue    string       `json:"dialogue"`
-	ShotType    string       `json:"shot_type"`
-	Duration    int          `json:"duration"`
+	Dialogue    string       `json:"dialogue" jsonschema:"required,minLength=1"`
+	ShotType    string       `json:"shot_type" jsonschema:"required,minLength=1"`
+	Duration    int          `json:"duration" jsonschema:"required,minimum=1,maximum=60"`
 }
 
 type CharDetail struct {
-	Name    string `json:"name"`
+	Name    string `json:"name" jsonschema:"required,minLength=1"`
 	Emotion string `json:"emotion"`
 }
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/storage"
+	"gorm.io/gorm"
+)
+
+// AssetOwner is the project, and optionally the scene, media belongs to
+type AssetOwner struct {
+	ProjectID uint
+	SceneID   uint
+}
+
+// AssetService puts generated media into storage and tracks every object in
+// the assets table
+type AssetService struct {
+	db            *gorm.DB
+	store         storage.Store
+	client        *http.Client
+	presignExpiry time.Duration
+}
+
+func NewAssetService(db *gorm.DB, store storage.Store, presignExpiry time.Duration) *AssetService {
+	if presignExpiry <= 0 {
+		presignExpiry = time.Hour
+	}
+	return &AssetService{
+		db:            db,
+		store:         store,
+		client:        &http.Client{Timeout: 10 * time.Minute},
+		presignExpiry: presignExpiry,
+	}
+}
+
+// Store exposes the storage backend, e.g. to read media back for rendering
+func (s *AssetService) Store() storage.Store {
+	return s.store
+}
+
+// SaveBytes stores media held in memory
+func (s *AssetService) SaveBytes(ctx context.Context, kind string, owner AssetOwner, data []byte, contentType string) (*models.Asset, error) {
+	return s.save(ctx, kind, owner, bytes.NewReader(data), contentType, "")
+}
+
+// Save streams media into storage. An empty or generic content type is
+// sniffed from the data. Identical content is uploaded once.
+func (s *AssetService) Save(ctx context.Context, kind string, owner AssetOwner, r io.Reader, contentType string) (*models.Asset, error) {
+	return s.save(ctx, kind, owner, r, contentType, "")
+}
+
+// Import takes over media a provider left at a URL. URLs that already point
+// into our storage are only recorded, everything else is downloaded.
+func (s *AssetService) Import(ctx context.Context, kind string, owner AssetOwner, url string) (*models.Asset, error) {
+	if key, ok := storage.KeyForURL(s.store, url); ok {
+		return s.adopt(ctx, kind, owner, key)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", kind, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to download %s (status %d)", kind, resp.StatusCode)
+	}
+	return s.save(ctx, kind, owner, resp.Body, resp.Header.Get("Content-Type"), url)
+}
+
+func (s *AssetService) save(ctx context.Context, kind string, owner AssetOwner, r io.Reader, contentType, source string) (*models.Asset, error) {
+	// Spool to disk to learn the hash, and therefore the key, before uploading
+	tmp, err := os.CreateTemp("", "3kstory-asset-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	hash := sha256.New()
+	head := &prefixBuffer{limit: 512}
+	size, err := io.Copy(io.MultiWriter(tmp, hash, head), r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", kind, err)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("refusing to store empty %s", kind)
+	}
+	if contentType == "" || strings.HasPrefix(contentType, "application/octet-stream") || strings.HasPrefix(contentType, "binary/") {
+		contentType = http.DetectContentType(head.Bytes())
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	key := storage.HashKey(assetPrefix(kind), sum, contentType)
+
+	_, err = s.store.Stat(ctx, key)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := s.store.Put(ctx, key, tmp, contentType); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	return s.record(ctx, &models.Asset{
+		Kind:      kind,
+		Key:       key,
+		URL:       s.store.URL(key),
+		Hash:      sum,
+		MIMEType:  contentType,
+		Size:      size,
+		SourceURL: source,
+	}, owner)
+}
+
+// adopt records an object that is already in storage, e.g. a clip the local
+// video service wrote there itself
+func (s *AssetService) adopt(ctx context.Context, kind string, owner AssetOwner, key string) (*models.Asset, error) {
+	body, obj, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+	defer body.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return s.record(ctx, &models.Asset{
+		Kind:     kind,
+		Key:      key,
+		URL:      s.store.URL(key),
+		Hash:     hex.EncodeToString(hash.Sum(nil)),
+		MIMEType: obj.ContentType,
+		Size:     size,
+	}, owner)
+}
+
+func (s *AssetService) record(ctx context.Context, asset *models.Asset, owner AssetOwner) (*models.Asset, error) {
+	if owner.ProjectID != 0 {
+		id := owner.ProjectID
+		asset.ProjectID = &id
+	}
+	if owner.SceneID != 0 {
+		id := owner.SceneID
+		asset.SceneID = &id
+	}
+	if err := s.db.WithContext(ctx).Create(asset).Error; err != nil {
+		return nil, fmt.Errorf("failed to record asset: %w", err)
+	}
+	return asset, nil
+}
+
+// ListProjectAssets returns a project's assets, newest first
+func (s *AssetService) ListProjectAssets(ctx context.Context, projectID uint, kind string) ([]models.Asset, error) {
+	query := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("id DESC")
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var assets []models.Asset
+	err := query.Find(&assets).Error
+	return assets, err
+}
+
+// SignedURL grants temporary read access, for private buckets
+func (s *AssetService) SignedURL(ctx context.Context, asset *models.Asset) (string, error) {
+	return s.store.PresignedURL(ctx, asset.Key, s.presignExpiry)
+}
+
+// ShareURL turns one of our media URLs into one an external provider can
+// fetch; other URLs are returned unchanged
+func (s *AssetService) ShareURL(ctx context.Context, url string) string {
+	key, ok := storage.KeyForURL(s.store, url)
+	if !ok {
+		return url
+	}
+	signed, err := s.store.PresignedURL(ctx, key, s.presignExpiry)
+	if err != nil {
+		log.Printf("failed to presign %s, passing the public URL: %v", key, err)
+		return url
+	}
+	return signed
+}
+
+// Open reads stored media back by its URL
+func (s *AssetService) Open(ctx context.Context, url string) (io.ReadCloser, error) {
+	key, ok := storage.KeyForURL(s.store, url)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in media storage", url)
+	}
+	body, _, err := s.store.Get(ctx, key)
+	return body, err
+}
+
+// Fetch reads media from our storage, or downloads it when a scene still
+// points somewhere else
+func (s *AssetService) Fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	if _, ok := storage.KeyForURL(s.store, url); ok {
+		return s.Open(ctx, url)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s (status %d)", url, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes an asset, and its object once no other asset shares it
+func (s *AssetService) Delete(ctx context.Context, asset *models.Asset) error {
+	if err := s.db.WithContext(ctx).Delete(asset).Error; err != nil {
+		return err
+	}
+	return s.releaseKeys(ctx, []string{asset.Key})
+}
+
+// DeleteProjectAssets removes everything a deleted project owned
+func (s *AssetService) DeleteProjectAssets(ctx context.Context, projectID uint) error {
+	var assets []models.Asset
+	if err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Find(&assets).Error; err != nil {
+		return err
+	}
+	if len(assets) == 0 {
+		return nil
+	}
+	if err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Delete(&models.Asset{}).Error; err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(assets))
+	for _, a := range assets {
+		keys = append(keys, a.Key)
+	}
+	return s.releaseKeys(ctx, keys)
+}
+
+// releaseKeys deletes objects no asset refers to any more
+func (s *AssetService) releaseKeys(ctx context.Context, keys []string) error {
+	seen := map[string]bool{}
+	var errs []error
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		var refs int64
+		if err := s.db.WithContext(ctx).Model(&models.Asset{}).Where("key = ?", key).Count(&refs).Error; err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if refs == 0 {
+			if err := s.store.Delete(ctx, key); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func assetPrefix(kind string) string {
+	switch kind {
+	case models.AssetImage:
+		return "images"
+	case models.AssetVideo:
+		return "videos"
+	case models.AssetExport:
+		return "exports"
+	default:
+		return kind
+	}
+}
+
+// prefixBuffer keeps the first bytes written to it, for content sniffing
+type prefixBuffer struct {
+	limit int
+	buf   []byte
+}
+
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - len(b.buf); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		b.buf = append(b.buf, p[:room]...)
+	}
+	return len(p), nil
+}
+
+func (b *prefixBuffer) Bytes() []byte {
+	return b.buf
+}
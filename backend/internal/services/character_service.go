@@ -0,0 +1,563 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/imagegen"
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCharacterNotFound is returned for characters outside the project
+	ErrCharacterNotFound = errors.New("character not found")
+	// ErrCharacterExists is returned when creating a character whose name the
+	// project already uses
+	ErrCharacterExists = errors.New("character already exists")
+	// ErrInvalidReference is returned for reference images that aren't image
+	// assets of the character's project
+	ErrInvalidReference = errors.New("invalid reference image")
+)
+
+// maxReferenceBytes bounds each reference image sent to the image backend
+const maxReferenceBytes = 16 << 20
+
+// CharacterInput describes a character; empty fields are left alone when
+// updating. The name identifies the character in scenes and is only set on
+// creation.
+type CharacterInput struct {
+	Name            string   `json:"name"`
+	Age             string   `json:"age"`
+	Appearance      string   `json:"appearance"`
+	Wardrobe        string   `json:"wardrobe"`
+	ReferenceImages []string `json:"reference_images"`
+	Seed            *int64   `json:"seed"`
+	LoRA            *string  `json:"lora"`
+	VoiceID         string   `json:"voice_id"`
+}
+
+// CharacterService keeps each project's character bible and applies it to
+// scene media: descriptions go into prompts, while seeds, LoRAs and
+// reference images go to the image backend
+type CharacterService struct {
+	db     *gorm.DB
+	assets *AssetService
+	speech *SpeechService
+}
+
+func NewCharacterService(db *gorm.DB, assets *AssetService, speech *SpeechService) *CharacterService {
+	return &CharacterService{db: db, assets: assets, speech: speech}
+}
+
+// Characters lists a project's characters with their voices
+func (s *CharacterService) Characters(ctx context.Context, projectID uint) ([]models.ProjectCharacter, error) {
+	var characters []models.ProjectCharacter
+	if err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("id ASC").Find(&characters).Error; err != nil {
+		return nil, err
+	}
+	if err := s.withVoices(ctx, projectID, characters); err != nil {
+		return nil, err
+	}
+	return characters, nil
+}
+
+// Names lists the names of a project's characters
+func (s *CharacterService) Names(ctx context.Context, projectID uint) ([]string, error) {
+	var names []string
+	err := s.db.WithContext(ctx).Model(&models.ProjectCharacter{}).Where("project_id = ?", projectID).Order("id ASC").Pluck("name", &names).Error
+	return names, err
+}
+
+// Create adds a character by hand
+func (s *CharacterService) Create(ctx context.Context, projectID uint, in CharacterInput) (*models.ProjectCharacter, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.ProjectCharacter{}).Where("project_id = ? AND name = ?", projectID, name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCharacterExists, name)
+	}
+
+	character := &models.ProjectCharacter{ProjectID: projectID, Name: name, ReferenceImages: models.StringArray{}}
+	if err := s.applyInput(ctx, character, in); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(character).Error; err != nil {
+		return nil, err
+	}
+	if err := s.setVoice(ctx, character, in.VoiceID); err != nil {
+		return nil, err
+	}
+	return character, nil
+}
+
+// Update edits a character. Edited characters are no longer updated from
+// regenerated scripts.
+func (s *CharacterService) Update(ctx context.Context, projectID, characterID uint, in CharacterInput) (*models.ProjectCharacter, error) {
+	character, err := s.character(ctx, projectID, characterID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInput(ctx, character, in); err != nil {
+		return nil, err
+	}
+	character.Auto = false
+	if err := s.db.WithContext(ctx).Save(character).Error; err != nil {
+		return nil, err
+	}
+	if err := s.setVoice(ctx, character, in.VoiceID); err != nil {
+		return nil, err
+	}
+	return character, nil
+}
+
+// Delete removes a character from the bible; scenes keep the name but lose
+// the description
+func (s *CharacterService) Delete(ctx context.Context, projectID, characterID uint) error {
+	character, err := s.character(ctx, projectID, characterID)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Delete(character).Error
+}
+
+// AddReference stores an uploaded reference image and adds it to the
+// character's references
+func (s *CharacterService) AddReference(ctx context.Context, projectID, characterID uint, r io.Reader, contentType string) (*models.ProjectCharacter, error) {
+	character, err := s.character(ctx, projectID, characterID)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := s.assets.Save(ctx, models.AssetImage, AssetOwner{ProjectID: projectID}, r, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store reference image: %w", err)
+	}
+	if !strings.HasPrefix(asset.MIMEType, "image/") {
+		if err := s.assets.Delete(ctx, asset); err != nil {
+			log.Printf("failed to delete rejected reference upload %d: %v", asset.ID, err)
+		}
+		return nil, errors.New("upload is not an image")
+	}
+	character.ReferenceImages = append(character.ReferenceImages, asset.URL)
+	character.Auto = false
+	if err := s.db.WithContext(ctx).Save(character).Error; err != nil {
+		return nil, err
+	}
+	if err := s.setVoice(ctx, character, ""); err != nil {
+		return nil, err
+	}
+	return character, nil
+}
+
+// Extract adds the script's characters to the bible, plus bare entries for
+// names that only appear in scenes. Characters still on their extracted
+// description take the new one; edited characters are kept as they are.
+func (s *CharacterService) Extract(ctx context.Context, projectID uint, profiles []CharacterProfile, names []string) error {
+	for _, name := range names {
+		profiles = append(profiles, CharacterProfile{Name: name})
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, p := range profiles {
+			name := strings.TrimSpace(p.Name)
+			if name == "" {
+				continue
+			}
+			var character models.ProjectCharacter
+			err := tx.Where("project_id = ? AND name = ?", projectID, name).First(&character).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				character = models.ProjectCharacter{ProjectID: projectID, Name: name, ReferenceImages: models.StringArray{}, Auto: true}
+			} else if err != nil {
+				return err
+			} else if !character.Auto {
+				continue
+			}
+
+			changed := character.ID == 0
+			changed = setText(&character.Age, p.Age) || changed
+			changed = setText(&character.Appearance, p.Appearance) || changed
+			changed = setText(&character.Wardrobe, p.Wardrobe) || changed
+			if !changed {
+				continue
+			}
+			if character.ID != 0 {
+				if err := tx.Save(&character).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			// Another attempt of the same script job may add the name first
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "project_id"}, {Name: "name"}},
+				DoNothing: true,
+			}).Create(&character).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SeriesCast lists the characters of a series: every name in its episodes,
+// as the latest episode with the name describes it, with its voice there
+func (s *CharacterService) SeriesCast(ctx context.Context, seriesID uint) ([]models.ProjectCharacter, error) {
+	cast, err := s.seriesCharacters(ctx, seriesID, 0)
+	if err != nil {
+		return nil, err
+	}
+	voices, err := s.seriesVoices(ctx, seriesID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cast {
+		cast[i].VoiceID = voices[cast[i].Name].VoiceID
+	}
+	return cast, nil
+}
+
+// Inherit gives a series episode the characters of the episodes before it,
+// with their look, seed, LoRA, references and voice, so the series keeps
+// its cast. Characters the episode already has are left alone. Inherited
+// characters count as edited, so the episode's script doesn't redescribe
+// them.
+func (s *CharacterService) Inherit(ctx context.Context, project *models.Project) error {
+	if project.SeriesID == nil {
+		return nil
+	}
+	cast, err := s.seriesCharacters(ctx, *project.SeriesID, project.EpisodeNumber)
+	if err != nil || len(cast) == 0 {
+		return err
+	}
+	voices, err := s.seriesVoices(ctx, *project.SeriesID, project.EpisodeNumber)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, c := range cast {
+			character := models.ProjectCharacter{
+				ProjectID:       project.ID,
+				Name:            c.Name,
+				Age:             c.Age,
+				Appearance:      c.Appearance,
+				Wardrobe:        c.Wardrobe,
+				ReferenceImages: c.ReferenceImages,
+				Seed:            c.Seed,
+				LoRA:            c.LoRA,
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&character)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			if err := inheritReferences(tx, *project.SeriesID, project.ID, c.ReferenceImages); err != nil {
+				return err
+			}
+		}
+		for _, v := range voices {
+			mapping := models.VoiceMapping{ProjectID: project.ID, Character: v.Character, VoiceID: v.VoiceID, Speed: v.Speed, Auto: v.Auto}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&mapping).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// inheritReferences records an earlier episode's reference images as assets
+// of the new episode too, since references must be the project's own images.
+// The objects themselves are shared.
+func inheritReferences(tx *gorm.DB, seriesID, projectID uint, urls []string) error {
+	for _, url := range urls {
+		var count int64
+		if err := tx.Model(&models.Asset{}).Where("project_id = ? AND kind = ? AND url = ?", projectID, models.AssetImage, url).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		var asset models.Asset
+		err := tx.Where("kind = ? AND url = ?", models.AssetImage, url).
+			Where("project_id IN (?)", tx.Model(&models.Project{}).Select("id").Where("series_id = ?", seriesID)).
+			Order("id ASC").First(&asset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		id := projectID
+		copied := models.Asset{
+			ProjectID: &id,
+			Kind:      asset.Kind,
+			Key:       asset.Key,
+			URL:       asset.URL,
+			Hash:      asset.Hash,
+			MIMEType:  asset.MIMEType,
+			Size:      asset.Size,
+			SourceURL: asset.SourceURL,
+		}
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seriesCharacters returns the latest entry for each character name in the
+// series' episodes, or in those before episode when it isn't 0
+func (s *CharacterService) seriesCharacters(ctx context.Context, seriesID uint, episode int) ([]models.ProjectCharacter, error) {
+	query := s.db.WithContext(ctx).
+		Joins("JOIN projects ON projects.id = characters.project_id").
+		Where("projects.series_id = ?", seriesID)
+	if episode != 0 {
+		query = query.Where("projects.episode_number < ?", episode)
+	}
+	var characters []models.ProjectCharacter
+	if err := query.Order("projects.episode_number DESC, characters.id ASC").Find(&characters).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	cast := make([]models.ProjectCharacter, 0, len(characters))
+	for _, c := range characters {
+		if !seen[c.Name] {
+			seen[c.Name] = true
+			cast = append(cast, c)
+		}
+	}
+	return cast, nil
+}
+
+// seriesVoices is seriesCharacters for voices, narrator included
+func (s *CharacterService) seriesVoices(ctx context.Context, seriesID uint, episode int) (map[string]models.VoiceMapping, error) {
+	query := s.db.WithContext(ctx).
+		Joins("JOIN projects ON projects.id = voice_mappings.project_id").
+		Where("projects.series_id = ?", seriesID)
+	if episode != 0 {
+		query = query.Where("projects.episode_number < ?", episode)
+	}
+	var mappings []models.VoiceMapping
+	if err := query.Order("projects.episode_number DESC").Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+	voices := map[string]models.VoiceMapping{}
+	for _, m := range mappings {
+		if _, ok := voices[m.Character]; !ok {
+			voices[m.Character] = m
+		}
+	}
+	return voices, nil
+}
+
+// Cast returns the bible entries of the characters in a scene, in the
+// scene's order
+func (s *CharacterService) Cast(ctx context.Context, scene *models.Scene) ([]models.ProjectCharacter, error) {
+	names := make([]string, 0, len(scene.Characters))
+	for _, c := range scene.Characters {
+		if name := strings.TrimSpace(c.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var characters []models.ProjectCharacter
+	if err := s.db.WithContext(ctx).Where("project_id = ? AND name IN ?", scene.ProjectID, names).Find(&characters).Error; err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.ProjectCharacter, len(characters))
+	for _, c := range characters {
+		byName[c.Name] = c
+	}
+	cast := make([]models.ProjectCharacter, 0, len(characters))
+	for _, name := range names {
+		if c, ok := byName[name]; ok {
+			cast = append(cast, c)
+			delete(byName, name)
+		}
+	}
+	return cast, nil
+}
+
+// ImageRequest builds the keyframe request for a scene: the first cast
+// member's seed, every cast member's LoRA and the first reference image of
+// each. It also returns the cast, for KeepSeed.
+func (s *CharacterService) ImageRequest(ctx context.Context, scene *models.Scene) (imagegen.Request, []models.ProjectCharacter, error) {
+	req := imagegen.Request{Prompt: scene.PromptForImage, Title: scene.Title}
+	cast, err := s.Cast(ctx, scene)
+	if err != nil {
+		return req, nil, err
+	}
+	for _, c := range cast {
+		if req.Seed == 0 {
+			req.Seed = c.Seed
+		}
+		if lora := strings.TrimSpace(c.LoRA); lora != "" {
+			req.LoRAs = append(req.LoRAs, lora)
+		}
+		if len(c.ReferenceImages) == 0 {
+			continue
+		}
+		data, err := s.reference(ctx, c.ProjectID, c.ReferenceImages[0])
+		if err != nil {
+			return req, nil, fmt.Errorf("failed to read reference image of %s: %w", c.Name, err)
+		}
+		req.References = append(req.References, data)
+	}
+	return req, cast, nil
+}
+
+// KeepSeed gives cast members without a seed the one their first keyframe
+// used, so later scenes start from the same noise
+func (s *CharacterService) KeepSeed(ctx context.Context, cast []models.ProjectCharacter, seed int64) error {
+	if seed == 0 || len(cast) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(cast))
+	for _, c := range cast {
+		ids = append(ids, c.ID)
+	}
+	return s.db.WithContext(ctx).Model(&models.ProjectCharacter{}).
+		Where("id IN ? AND seed = 0", ids).
+		Update("seed", seed).Error
+}
+
+// reference reads a reference image from media storage. Only the project's
+// own image assets are read, never arbitrary URLs.
+func (s *CharacterService) reference(ctx context.Context, projectID uint, url string) ([]byte, error) {
+	asset, err := s.referenceAsset(ctx, projectID, url)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := s.assets.Store().Get(ctx, asset.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, maxReferenceBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxReferenceBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", url, maxReferenceBytes>>20)
+	}
+	return data, nil
+}
+
+// referenceAsset finds the project's image asset for a reference, given as
+// the asset's URL or ID
+func (s *CharacterService) referenceAsset(ctx context.Context, projectID uint, ref string) (*models.Asset, error) {
+	query := s.db.WithContext(ctx).Where("project_id = ? AND kind = ?", projectID, models.AssetImage)
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("url = ?", ref)
+	}
+	var asset models.Asset
+	err := query.Order("id ASC").First(&asset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s is not an image of this project", ErrInvalidReference, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// setVoice assigns the character's voice, keeping a speed set earlier. An
+// empty voiceID only reads the current voice.
+func (s *CharacterService) setVoice(ctx context.Context, character *models.ProjectCharacter, voiceID string) error {
+	var mapping models.VoiceMapping
+	err := s.db.WithContext(ctx).Where("project_id = ? AND character_name = ?", character.ProjectID, character.Name).First(&mapping).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	character.VoiceID = mapping.VoiceID
+	if voiceID = strings.TrimSpace(voiceID); voiceID == "" {
+		return nil
+	}
+	err = s.speech.SetVoices(ctx, character.ProjectID, []VoiceAssignment{{Character: character.Name, VoiceID: voiceID, Speed: mapping.Speed}})
+	if err != nil {
+		return err
+	}
+	character.VoiceID = voiceID
+	return nil
+}
+
+// withVoices fills in VoiceID from the project's voice mappings
+func (s *CharacterService) withVoices(ctx context.Context, projectID uint, characters []models.ProjectCharacter) error {
+	voices, err := s.speech.Voices(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string, len(voices))
+	for _, v := range voices {
+		byName[v.Character] = v.VoiceID
+	}
+	for i := range characters {
+		characters[i].VoiceID = byName[characters[i].Name]
+	}
+	return nil
+}
+
+func (s *CharacterService) character(ctx context.Context, projectID, characterID uint) (*models.ProjectCharacter, error) {
+	var character models.ProjectCharacter
+	err := s.db.WithContext(ctx).Where("id = ? AND project_id = ?", characterID, projectID).First(&character).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCharacterNotFound
+	}
+	return &character, err
+}
+
+// setText sets a non-empty value and reports whether it changed the field
+func setText(field *string, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" || *field == value {
+		return false
+	}
+	*field = value
+	return true
+}
+
+// applyInput sets the fields given in the input. Reference images are
+// stored as the URLs of the project's assets.
+func (s *CharacterService) applyInput(ctx context.Context, character *models.ProjectCharacter, in CharacterInput) error {
+	if in.Age != "" {
+		character.Age = strings.TrimSpace(in.Age)
+	}
+	if in.Appearance != "" {
+		character.Appearance = strings.TrimSpace(in.Appearance)
+	}
+	if in.Wardrobe != "" {
+		character.Wardrobe = strings.TrimSpace(in.Wardrobe)
+	}
+	if in.ReferenceImages != nil {
+		refs := models.StringArray{}
+		for _, ref := range cleanTags(in.ReferenceImages) {
+			asset, err := s.referenceAsset(ctx, character.ProjectID, ref)
+			if err != nil {
+				return err
+			}
+			refs = append(refs, asset.URL)
+		}
+		character.ReferenceImages = cleanTags(refs)
+	}
+	if in.Seed != nil {
+		character.Seed = *in.Seed
+	}
+	if in.LoRA != nil {
+		character.LoRA = strings.TrimSpace(*in.LoRA)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/events"
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrReviewNotFound is returned for review results that do not exist or
+	// belong to another project
+	ErrReviewNotFound = errors.New("review result not found")
+	// ErrReviewTransition is returned for decisions the result's status does
+	// not allow, such as approving content that already passed
+	ErrReviewTransition = errors.New("review result cannot take this decision")
+	// ErrReviewNoteRequired is returned for rejections without a note and
+	// appeals without a reason
+	ErrReviewNoteRequired = errors.New("a note is required")
+)
+
+// ReviewQueueFilter narrows the human review queue. Without a status it holds
+// the pending and appealed results.
+type ReviewQueueFilter struct {
+	Status    string
+	Stage     string
+	ProjectID uint
+	Limit     int
+	Offset    int
+}
+
+// ModerationService is the human side of content review: admins approve or
+// reject what automated review held back, creators appeal rejections, and
+// every step is written to the review_decisions audit trail. Decisions on a
+// scene's current content release or block it in the pipeline; decisions on
+// content that has since been regenerated are only recorded.
+type ModerationService struct {
+	db       *gorm.DB
+	projects *ProjectService
+	tasks    *TaskService
+	prompts  *PromptService
+	events   *events.Broker
+}
+
+func NewModerationService(db *gorm.DB, projects *ProjectService, tasks *TaskService, prompts *PromptService, broker *events.Broker) *ModerationService {
+	return &ModerationService{db: db, projects: projects, tasks: tasks, prompts: prompts, events: broker}
+}
+
+// Queue lists review results waiting for a human, oldest first, along with
+// the unpaginated total
+func (s *ModerationService) Queue(ctx context.Context, filter ReviewQueueFilter) ([]models.ReviewResult, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.ReviewResult{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	} else {
+		query = query.Where("status IN ?", []string{models.ReviewPending, models.ReviewAppealed})
+	}
+	if filter.Stage != "" {
+		query = query.Where("stage = ?", filter.Stage)
+	}
+	if filter.ProjectID != 0 {
+		query = query.Where("project_id = ?", filter.ProjectID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var results []models.ReviewResult
+	err := query.Order("created_at ASC").Offset(filter.Offset).Find(&results).Error
+	return results, total, err
+}
+
+// Get returns a review result with its audit trail
+func (s *ModerationService) Get(ctx context.Context, reviewID uint) (*models.ReviewResult, error) {
+	var result models.ReviewResult
+	err := s.db.WithContext(ctx).
+		Preload("Decisions", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		First(&result, reviewID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReviewNotFound
+	}
+	return &result, err
+}
+
+// Approve clears held content and lets the pipeline continue with it: an
+// approved prompt is scripted, an approved script gets its image and
+// approved media completes its scene
+func (s *ModerationService) Approve(ctx context.Context, reviewID, adminID uint, note string) (*models.ReviewResult, error) {
+	result, err := s.decide(ctx, reviewID, 0, adminID, models.ReviewActionApprove, strings.TrimSpace(note),
+		[]string{models.ReviewPending, models.ReviewAppealed}, models.ReviewApproved)
+	if err != nil {
+		return nil, err
+	}
+	s.apply(ctx, result)
+	return result, nil
+}
+
+// Reject blocks content. Besides held content, admins may reject content that
+// passed automated review. A rejected scene blocks its project until the
+// scene is regenerated.
+func (s *ModerationService) Reject(ctx context.Context, reviewID, adminID uint, note string) (*models.ReviewResult, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, fmt.Errorf("%w to reject content", ErrReviewNoteRequired)
+	}
+	result, err := s.decide(ctx, reviewID, 0, adminID, models.ReviewActionReject, note,
+		[]string{models.ReviewPending, models.ReviewAppealed, models.ReviewPassed, models.ReviewFlagged}, models.ReviewRejected)
+	if err != nil {
+		return nil, err
+	}
+	s.apply(ctx, result)
+	return result, nil
+}
+
+// Appeal lets a project's owner contest a rejection once; the result goes
+// back to the queue while the content stays blocked
+func (s *ModerationService) Appeal(ctx context.Context, projectID, reviewID, userID uint, reason string) (*models.ReviewResult, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w to appeal", ErrReviewNoteRequired)
+	}
+	return s.decide(ctx, reviewID, projectID, userID, models.ReviewActionAppeal, reason,
+		[]string{models.ReviewRejected}, models.ReviewAppealed)
+}
+
+// decide moves a review result from one of the allowed statuses to the next
+// and writes the audit entry in the same transaction. A non-zero projectID
+// restricts the result to that project.
+func (s *ModerationService) decide(ctx context.Context, reviewID, projectID, actorID uint, action, note string, from []string, to string) (*models.ReviewResult, error) {
+	var result models.ReviewResult
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+		if projectID != 0 {
+			q = q.Where("project_id = ?", projectID)
+		}
+		if err := q.First(&result, reviewID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrReviewNotFound
+			}
+			return err
+		}
+		if !slices.Contains(from, result.Status) {
+			return fmt.Errorf("%w: cannot %s a %s result", ErrReviewTransition, action, result.Status)
+		}
+		if action == models.ReviewActionAppeal && result.AppealedAt != nil {
+			return fmt.Errorf("%w: the result was already appealed", ErrReviewTransition)
+		}
+
+		now := time.Now()
+		decision := &models.ReviewDecision{
+			ReviewID:   result.ID,
+			ProjectID:  result.ProjectID,
+			ActorID:    actorID,
+			Action:     action,
+			FromStatus: result.Status,
+			ToStatus:   to,
+			Note:       note,
+		}
+		result.Status = to
+		if action == models.ReviewActionAppeal {
+			result.Appeal, result.AppealedAt = note, &now
+		} else {
+			result.DecidedBy, result.DecisionNote, result.DecidedAt = &actorID, note, &now
+		}
+		if err := tx.Save(&result).Error; err != nil {
+			return err
+		}
+		return tx.Create(decision).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Emit(ctx, result.ProjectID, events.ReviewDecided, map[string]interface{}{
+		"review_id": result.ID,
+		"scene_id":  result.SceneID,
+		"stage":     result.Stage,
+		"action":    action,
+		"status":    result.Status,
+	})
+	return &result, nil
+}
+
+// apply carries a decision into the pipeline when it is about the content
+// the project or scene still has. Failures are logged: the decision itself
+// is already recorded.
+func (s *ModerationService) apply(ctx context.Context, result *models.ReviewResult) {
+	var err error
+	if result.Stage == models.ReviewStagePrompt {
+		err = s.applyToProject(ctx, result)
+	} else if result.SceneID != nil {
+		err = s.applyToScene(ctx, result)
+	}
+	if err != nil {
+		log.Printf("failed to apply review decision %d: %v", result.ID, err)
+	}
+}
+
+func (s *ModerationService) applyToProject(ctx context.Context, result *models.ReviewResult) error {
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, result.ProjectID).Error; err != nil {
+		return err
+	}
+	if project.Prompt != result.Content {
+		return nil
+	}
+
+	if result.Status == models.ReviewRejected {
+		if err := s.db.WithContext(ctx).Model(&project).Update("status", "blocked").Error; err != nil {
+			return err
+		}
+		s.events.Emit(ctx, project.ID, events.ProjectHeld, map[string]interface{}{
+			"status":    "blocked",
+			"review_id": result.ID,
+			"stage":     result.Stage,
+		})
+		return nil
+	}
+	if project.Status != "review" && project.Status != "blocked" {
+		return nil
+	}
+	if err := s.db.WithContext(ctx).Model(&project).Update("status", "draft").Error; err != nil {
+		return err
+	}
+	_, err := s.tasks.Submit(ctx, TaskTypeScript, &project.ID, nil, models.JSONMap{"prompt": project.Prompt})
+	return err
+}
+
+func (s *ModerationService) applyToScene(ctx context.Context, result *models.ReviewResult) error {
+	var scene models.Scene
+	if err := s.db.WithContext(ctx).Where("id = ? AND project_id = ?", *result.SceneID, result.ProjectID).First(&scene).Error; err != nil {
+		return nil
+	}
+	current := scene.MediaURL == result.MediaURL
+	if result.Stage == models.ReviewStageScript {
+		current = sceneText(&scene) == result.Content
+	}
+	if !current {
+		return nil
+	}
+
+	queueImage := false
+	switch {
+	case result.Status == models.ReviewRejected:
+		scene.Status = "blocked"
+	case scene.Status != "review" && scene.Status != "blocked":
+		return nil
+	case result.Stage == models.ReviewStageScript:
+		scene.Status = "pending"
+		queueImage = true
+	default:
+		scene.Status = "completed"
+	}
+	if err := s.db.WithContext(ctx).Model(&scene).Update("status", scene.Status).Error; err != nil {
+		return err
+	}
+
+	if queueImage {
+		tpl, err := s.prompts.Resolve(ctx, scene.ProjectID, PromptImage)
+		if err != nil {
+			return err
+		}
+		if _, err := s.tasks.SubmitWithPrompt(ctx, TaskTypeImage, &scene.ProjectID, &scene.ID, models.JSONMap{"prompt": scene.PromptForImage}, tpl); err != nil {
+			return err
+		}
+	}
+	if err := s.projects.MarkProjectProcessing(ctx, scene.ProjectID); err != nil {
+		return err
+	}
+	return s.projects.RefreshProjectStatus(ctx, scene.ProjectID)
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/media"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/music"
+	"gorm.io/gorm"
+)
+
+// ErrTrackNotFound is returned for tracks outside the project's library
+var ErrTrackNotFound = errors.New("music track not found")
+
+// MusicTrackInput describes a library track; empty fields are left alone
+// when updating
+type MusicTrackInput struct {
+	Title string   `json:"title"`
+	Genre string   `json:"genre"`
+	Moods []string `json:"moods"`
+}
+
+// MusicService keeps each project's music library and decides which track
+// every scene plays under it
+type MusicService struct {
+	db     *gorm.DB
+	assets *AssetService
+}
+
+func NewMusicService(db *gorm.DB, assets *AssetService) *MusicService {
+	return &MusicService{db: db, assets: assets}
+}
+
+// Tracks lists a project's library
+func (s *MusicService) Tracks(ctx context.Context, projectID uint) ([]models.MusicTrack, error) {
+	var tracks []models.MusicTrack
+	err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("id ASC").Find(&tracks).Error
+	return tracks, err
+}
+
+// AddTrack stores an uploaded track in the project's library. When ffmpeg is
+// installed the upload must be readable audio, and its length is recorded.
+func (s *MusicService) AddTrack(ctx context.Context, projectID uint, r io.Reader, contentType string, in MusicTrackInput) (*models.MusicTrack, error) {
+	tmp, err := os.CreateTemp("", "3kstory-music-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+	if _, err := io.Copy(tmp, r); err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	durationMS := 0
+	if media.Available() == nil {
+		info, err := media.Probe(ctx, tmp.Name())
+		if err != nil || !info.HasAudio {
+			return nil, errors.New("upload is not a readable audio file")
+		}
+		durationMS = int(info.Seconds*1000 + 0.5)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	asset, err := s.assets.Save(ctx, models.AssetMusic, AssetOwner{ProjectID: projectID}, tmp, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store track: %w", err)
+	}
+
+	track := &models.MusicTrack{
+		ProjectID:  projectID,
+		AssetID:    asset.ID,
+		URL:        asset.URL,
+		Title:      strings.TrimSpace(in.Title),
+		Genre:      strings.TrimSpace(in.Genre),
+		Moods:      cleanTags(in.Moods),
+		DurationMS: durationMS,
+	}
+	if err := s.db.WithContext(ctx).Create(track).Error; err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// UpdateTrack retitles or retags a track
+func (s *MusicService) UpdateTrack(ctx context.Context, projectID, trackID uint, in MusicTrackInput) (*models.MusicTrack, error) {
+	track, err := s.track(ctx, projectID, trackID)
+	if err != nil {
+		return nil, err
+	}
+	if in.Title != "" {
+		track.Title = strings.TrimSpace(in.Title)
+	}
+	if in.Genre != "" {
+		track.Genre = strings.TrimSpace(in.Genre)
+	}
+	if in.Moods != nil {
+		track.Moods = cleanTags(in.Moods)
+	}
+	if err := s.db.WithContext(ctx).Save(track).Error; err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// DeleteTrack removes a track from the library. Scenes cued to it go back to
+// automatic matching.
+func (s *MusicService) DeleteTrack(ctx context.Context, projectID, trackID uint) error {
+	track, err := s.track(ctx, projectID, trackID)
+	if err != nil {
+		return err
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Scene{}).Where("music_track_id = ?", track.ID).
+			Updates(map[string]interface{}{"music_cue": models.MusicCueAuto, "music_track_id": nil}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(track).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var asset models.Asset
+	if err := s.db.WithContext(ctx).First(&asset, track.AssetID).Error; err != nil {
+		return nil
+	}
+	return s.assets.Delete(ctx, &asset)
+}
+
+// SetSceneCue picks a scene's music by hand, switches it off or hands it
+// back to automatic matching
+func (s *MusicService) SetSceneCue(ctx context.Context, scene *models.Scene, mode string, trackID *uint) error {
+	switch mode {
+	case models.MusicCueManual:
+		if trackID == nil {
+			return errors.New("track_id is required to pick a track")
+		}
+		if _, err := s.track(ctx, scene.ProjectID, *trackID); err != nil {
+			return err
+		}
+		scene.MusicTrackID = trackID
+	case models.MusicCueNone, models.MusicCueAuto:
+		scene.MusicTrackID = nil
+	default:
+		return fmt.Errorf("unknown music cue mode %q", mode)
+	}
+	scene.MusicCue = mode
+	return s.db.WithContext(ctx).Model(scene).Select("music_cue", "music_track_id").Updates(scene).Error
+}
+
+// MatchScenes picks tracks for the scenes left to automatic matching, from
+// the project's genre and the emotions of each scene's characters, and
+// returns the project's scenes in order
+func (s *MusicService) MatchScenes(ctx context.Context, projectID uint) ([]models.Scene, error) {
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, projectID).Error; err != nil {
+		return nil, err
+	}
+	var scenes []models.Scene
+	if err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("scene_number ASC").Find(&scenes).Error; err != nil {
+		return nil, err
+	}
+	tracks, err := s.Tracks(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]music.Track, 0, len(tracks))
+	for _, t := range tracks {
+		candidates = append(candidates, music.Track{ID: t.ID, Genre: t.Genre, Moods: t.Moods})
+	}
+	emotions := make([][]string, len(scenes))
+	for i, scene := range scenes {
+		for _, c := range scene.Characters {
+			emotions[i] = append(emotions[i], c.Emotion)
+		}
+	}
+	picks := music.Match(candidates, project.Genre, emotions)
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range scenes {
+			scene := &scenes[i]
+			if scene.MusicCue != models.MusicCueAuto && scene.MusicCue != "" {
+				continue
+			}
+			var pick *uint
+			if picks[i] != 0 {
+				id := picks[i]
+				pick = &id
+			}
+			scene.MusicTrackID = pick
+			if err := tx.Model(scene).Update("music_track_id", pick).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return scenes, err
+}
+
+// SceneTracks matches the project's scenes and returns the track each one
+// plays, keyed by scene; scenes without music are missing
+func (s *MusicService) SceneTracks(ctx context.Context, projectID uint) (map[uint]*models.MusicTrack, error) {
+	scenes, err := s.MatchScenes(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	tracks, err := s.Tracks(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.MusicTrack, len(tracks))
+	for i := range tracks {
+		byID[tracks[i].ID] = &tracks[i]
+	}
+	out := map[uint]*models.MusicTrack{}
+	for _, scene := range scenes {
+		if scene.MusicCue == models.MusicCueNone || scene.MusicTrackID == nil {
+			continue
+		}
+		if track, ok := byID[*scene.MusicTrackID]; ok {
+			out[scene.ID] = track
+		}
+	}
+	return out, nil
+}
+
+func (s *MusicService) track(ctx context.Context, projectID, trackID uint) (*models.MusicTrack, error) {
+	var track models.MusicTrack
+	err := s.db.WithContext(ctx).Where("id = ? AND project_id = ?", trackID, projectID).First(&track).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTrackNotFound
+	}
+	return &track, err
+}
+
+// cleanTags trims tags and drops empty and repeated ones
+func cleanTags(tags []string) models.StringArray {
+	out := models.StringArray{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		out = append(out, tag)
+	}
+	return out
+}
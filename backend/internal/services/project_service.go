@@ -2,21 +2,49 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/richard9219/3kstory/internal/events"
 	"github.com/richard9219/3kstory/internal/models"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrProjectNotReady is returned when publishing a project that is still
+	// being generated, failed or is held by content review
+	ErrProjectNotReady = errors.New("project is not ready to publish")
+	// ErrStaleRender is returned when publishing a project whose rendered
+	// episode is missing or predates a content rejection
+	ErrStaleRender = errors.New("project needs a new render before publishing")
+)
+
 type ProjectService struct {
-	db        *gorm.DB
-	aiService *AIService
+	db         *gorm.DB
+	aiService  *AIService
+	tasks      *TaskService
+	events     *events.Broker
+	prompts    *PromptService
+	assets     *AssetService
+	reviews    *ReviewService
+	characters *CharacterService
+	versions   *VersionService
 }
 
-func NewProjectService(db *gorm.DB, aiService *AIService) *ProjectService {
+func NewProjectService(db *gorm.DB, aiService *AIService, tasks *TaskService, broker *events.Broker, prompts *PromptService, assets *AssetService, reviews *ReviewService, characters *CharacterService, versions *VersionService) *ProjectService {
 	return &ProjectService{
-		db:        db,
-		aiService: aiService,
+		db:         db,
+		aiService:  aiService,
+		tasks:      tasks,
+		events:     broker,
+		prompts:    prompts,
+		assets:     assets,
+		reviews:    reviews,
+		characters: characters,
+		versions:   versions,
 	}
 }
 
@@ -35,70 +63,570 @@ func (s *ProjectService) CreateProject(userID uint, prompt, title string) (*mode
 	return project, nil
 }
 
-func (s *ProjectService) GenerateScenes(ctx context.Context, projectID uint) error {
+// GenerateScenes streams the script for a project, storing each scene and
+// queueing its image as soon as the model finishes it. Errors are returned
+// so the worker can retry; MarkProjectFailed runs once the job gives up.
+func (s *ProjectService) GenerateScenes(ctx context.Context, projectID uint, task *models.AITask) error {
 	var project models.Project
-	if err := s.db.First(&project, projectID).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&project, projectID).Error; err != nil {
+		return err
+	}
+
+	// A prompt held by content review stops here, with the project in review
+	// or blocked
+	review, err := s.reviews.ReviewPrompt(ctx, &project)
+	if err != nil {
 		return err
 	}
+	if review != nil && review.Held() {
+		project.Status = heldStatus(review)
+		if err := s.db.WithContext(ctx).Save(&project).Error; err != nil {
+			return err
+		}
+		s.events.Emit(ctx, projectID, events.ProjectHeld, map[string]interface{}{
+			"status":    project.Status,
+			"review_id": review.ID,
+			"stage":     review.Stage,
+		})
+		return nil
+	}
 
+	system, scriptTpl, err := s.scriptPrompt(ctx, &project)
+	if err != nil {
+		return err
+	}
+	recordPrompt(task, scriptTpl)
+	imageTpl, err := s.prompts.Resolve(ctx, projectID, PromptImage)
+	if err != nil {
+		return err
+	}
+
+	// Keep the script being replaced as a version; retries already did
+	if task == nil || task.RetryCount == 0 {
+		if _, err := s.versions.Snapshot(ctx, projectID, models.AuthorUser, "edit", ""); err != nil {
+			return err
+		}
+	}
+
+	// The project stays processing until RefreshProjectStatus sees every
+	// scene finished
 	project.Status = "processing"
-	s.db.Save(&project)
+	if err := s.db.WithContext(ctx).Save(&project).Error; err != nil {
+		return err
+	}
+	s.events.Emit(ctx, projectID, events.ScriptStarted, nil)
 
-	script, err := s.aiService.GenerateScript(ctx, project.Prompt)
+	extracted := 0
+	script, err := s.aiService.StreamScript(ctx, system, project.Prompt, func(header *ScriptResult, detail SceneDetail) error {
+		if s.applyScriptHeader(&project, header) {
+			if err := s.db.WithContext(ctx).Save(&project).Error; err != nil {
+				return err
+			}
+		}
+		// Characters go into the bible before the scenes that show them, so
+		// their image prompts describe them
+		if len(header.Characters) != extracted {
+			if err := s.characters.Extract(ctx, projectID, header.Characters, nil); err != nil {
+				return err
+			}
+			extracted = len(header.Characters)
+		}
+		return s.saveStreamedScene(ctx, &project, imageTpl, detail, task)
+	})
 	if err != nil {
-		project.Status = "failed"
-		s.db.Save(&project)
+		// Scenes received before the stream broke stay; the retry updates them
+		// by scene number
 		return err
 	}
+	if err := s.dropStaleScenes(ctx, projectID, len(script.Scenes)); err != nil {
+		return err
+	}
+
+	s.applyScriptHeader(&project, script)
+	if err := s.db.WithContext(ctx).Save(&project).Error; err != nil {
+		return err
+	}
+	var sceneNames []string
+	for _, scene := range script.Scenes {
+		for _, c := range scene.Characters {
+			sceneNames = append(sceneNames, c.Name)
+		}
+	}
+	if err := s.characters.Extract(ctx, projectID, script.Characters, sceneNames); err != nil {
+		return err
+	}
+	if project.SeriesID != nil {
+		if err := s.saveEpisodeSummary(ctx, &project, script); err != nil {
+			return err
+		}
+	}
+	if _, err := s.versions.Snapshot(ctx, projectID, models.AuthorAI, "generate", ""); err != nil {
+		log.Printf("failed to record version of project %d: %v", projectID, err)
+	}
+	s.events.Emit(ctx, projectID, events.ScriptCompleted, map[string]interface{}{
+		"title":       project.Title,
+		"scene_count": len(script.Scenes),
+	})
+
+	return s.RefreshProjectStatus(ctx, projectID)
+}
+
+// scriptPrompt renders the system prompt for a project's script. A
+// regenerated script keeps the characters the project already has; a series
+// episode gets the episode template with the series outline, the recaps of
+// the episodes before it and the cast it inherits from them.
+func (s *ProjectService) scriptPrompt(ctx context.Context, project *models.Project) (string, *models.PromptTemplate, error) {
+	if project.SeriesID == nil {
+		names, err := s.characters.Names(ctx, project.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		return s.prompts.Prompt(ctx, project.ID, PromptScript, PromptData{
+			Prompt:         project.Prompt,
+			Title:          project.Title,
+			Genre:          project.Genre,
+			Style:          project.Style,
+			TargetDuration: project.TargetDuration,
+			Characters:     names,
+		})
+	}
 
-	project.Genre = script.Genre
-	project.Style = script.Style
-	if project.Title == "" {
-		project.Title = script.Title
+	var series models.Series
+	if err := s.db.WithContext(ctx).First(&series, *project.SeriesID).Error; err != nil {
+		return "", nil, err
+	}
+	if err := s.characters.Inherit(ctx, project); err != nil {
+		return "", nil, err
+	}
+	cast, err := s.characters.Characters(ctx, project.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	var recap []models.Project
+	err = s.db.WithContext(ctx).
+		Where("series_id = ? AND episode_number < ? AND summary <> ''", series.ID, project.EpisodeNumber).
+		Order("episode_number ASC").
+		Find(&recap).Error
+	if err != nil {
+		return "", nil, err
 	}
+	return s.prompts.Prompt(ctx, project.ID, PromptEpisode, PromptData{
+		Prompt:         project.Prompt,
+		Title:          project.Title,
+		Genre:          project.Genre,
+		Style:          project.Style,
+		TargetDuration: project.TargetDuration,
+		Cast:           cast,
+		Series:         series.Title,
+		Outline:        series.Outline,
+		Episode:        project.EpisodeNumber,
+		EpisodeCount:   series.EpisodeCount,
+		Recap:          recap,
+	})
+}
 
-	for _, sceneDetail := range script.Scenes {
-		scene := models.Scene{
-			ProjectID:   projectID,
-			SceneNumber: sceneDetail.SceneNumber,
-			Title:       sceneDetail.Title,
-			Location:    sceneDetail.Location,
-			Dialogue:    sceneDetail.Dialogue,
-			ShotType:    sceneDetail.ShotType,
-			Duration:    sceneDetail.Duration,
-			Status:      "pending",
+// saveEpisodeSummary keeps the episode's summary, made up from its scene
+// titles when the model wrote none, and rebuilds the series recap
+func (s *ProjectService) saveEpisodeSummary(ctx context.Context, project *models.Project, script *ScriptResult) error {
+	summary := strings.TrimSpace(script.Summary)
+	if summary == "" {
+		titles := make([]string, 0, len(script.Scenes))
+		for _, scene := range script.Scenes {
+			titles = append(titles, scene.Title)
 		}
+		summary = strings.Join(titles, "；")
+	}
+	project.Summary = summary
+	if err := s.db.WithContext(ctx).Model(project).Update("summary", summary).Error; err != nil {
+		return err
+	}
+	return updateRecap(s.db.WithContext(ctx), *project.SeriesID)
+}
+
+// applyScriptHeader copies the script's title, genre and style onto the
+// project and reports whether anything changed. Series episodes keep the
+// genre and style of their series.
+func (s *ProjectService) applyScriptHeader(project *models.Project, header *ScriptResult) bool {
+	changed := false
+	if header.Genre != "" && project.Genre != header.Genre && project.SeriesID == nil {
+		project.Genre = header.Genre
+		changed = true
+	}
+	if header.Style != "" && project.Style != header.Style && project.SeriesID == nil {
+		project.Style = header.Style
+		changed = true
+	}
+	if project.Title == "" && header.Title != "" {
+		project.Title = header.Title
+		changed = true
+	}
+	return changed
+}
 
-		var chars models.CharacterArray
-		for _, c := range sceneDetail.Characters {
-			chars = append(chars, models.Character{
-				Name:    c.Name,
-				Emotion: c.Emotion,
-			})
+// saveStreamedScene creates the scene, or updates the one with the same
+// number, and queues its image when the image prompt is new. A scene from an
+// earlier script is kept as a revision first; one written by an earlier
+// attempt of the same task is simply overwritten. Scenes whose script is held
+// by content review get no image.
+func (s *ProjectService) saveStreamedScene(ctx context.Context, project *models.Project, imageTpl *models.PromptTemplate, detail SceneDetail, task *models.AITask) error {
+	var scene models.Scene
+	err := s.db.WithContext(ctx).Where("project_id = ? AND scene_number = ?", project.ID, detail.SceneNumber).First(&scene).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if scene.ID != 0 && (task == nil || scene.UpdatedAt.Before(task.CreatedAt)) {
+		if err := recordRevision(s.db.WithContext(ctx), &scene, models.RevisionScript, ""); err != nil {
+			return err
 		}
-		scene.Characters = chars
+	}
+
+	scene.ProjectID = project.ID
+	scene.SceneNumber = detail.SceneNumber
+	scene.Title = detail.Title
+	scene.Location = detail.Location
+	scene.Dialogue = detail.Dialogue
+	scene.ShotType = detail.ShotType
+	scene.Duration = detail.Duration
 
-		scene.PromptForImage = fmt.Sprintf("%s, %s, %s", sceneDetail.Location, sceneDetail.Title, script.Style)
+	var chars models.CharacterArray
+	for _, c := range detail.Characters {
+		chars = append(chars, models.Character{
+			Name:    c.Name,
+			Emotion: c.Emotion,
+		})
+	}
+	scene.Characters = chars
 
-		if err := s.db.Create(&scene).Error; err != nil {
-			continue
+	if err := s.refreshScene(ctx, project, imageTpl, &scene, false, nil); err != nil {
+		return err
+	}
+	s.events.Emit(ctx, project.ID, events.SceneCreated, map[string]interface{}{
+		"scene_id":     scene.ID,
+		"scene_number": scene.SceneNumber,
+		"title":        scene.Title,
+	})
+	return nil
+}
+
+// RefreshScene saves an edited scene and brings its media in line: the image
+// prompt is rendered again, the script reviewed, and the image queued when
+// the prompt changed or force is set
+func (s *ProjectService) RefreshScene(ctx context.Context, scene *models.Scene, force bool) error {
+	return s.refreshSceneImage(ctx, scene, force, nil)
+}
+
+// RegenerateImage saves the scene and queues a new image even though its
+// prompt is unchanged. The image is drawn from a fresh seed rather than the
+// characters', and instruction, if any, is added to the prompt for this
+// image only.
+func (s *ProjectService) RegenerateImage(ctx context.Context, scene *models.Scene, instruction string) error {
+	return s.refreshSceneImage(ctx, scene, true, models.JSONMap{"variation": true, "instruction": instruction})
+}
+
+func (s *ProjectService) refreshSceneImage(ctx context.Context, scene *models.Scene, force bool, input models.JSONMap) error {
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, scene.ProjectID).Error; err != nil {
+		return err
+	}
+	imageTpl, err := s.prompts.Resolve(ctx, project.ID, PromptImage)
+	if err != nil {
+		return err
+	}
+	return s.refreshScene(ctx, &project, imageTpl, scene, force, input)
+}
+
+// refreshScene saves the scene with its image prompt rendered from the
+// current script and characters. A scene that needs a new image loses its
+// old media and goes back to pending, unless content review holds its
+// script. input is added to the image task's input.
+func (s *ProjectService) refreshScene(ctx context.Context, project *models.Project, imageTpl *models.PromptTemplate, scene *models.Scene, force bool, input models.JSONMap) error {
+	previousPrompt := scene.PromptForImage
+	cast, err := s.characters.Cast(ctx, scene)
+	if err != nil {
+		return err
+	}
+	imagePrompt, err := s.prompts.Render(imageTpl, PromptData{
+		Prompt: project.Prompt,
+		Title:  project.Title,
+		Genre:  project.Genre,
+		Style:  project.Style,
+		Scene:  scene,
+		Cast:   cast,
+	})
+	if err != nil {
+		return err
+	}
+	scene.PromptForImage = imagePrompt
+
+	queueImage := force || scene.ID == 0 || scene.PromptForImage != previousPrompt ||
+		scene.Status == "failed" || scene.Status == "review" || scene.Status == "blocked"
+	if queueImage {
+		scene.Status = "pending"
+		scene.MediaURL = ""
+		scene.MediaAssetID = nil
+		scene.MediaType = "image"
+		scene.PromptForVideo = ""
+	}
+	if err := s.db.WithContext(ctx).Save(scene).Error; err != nil {
+		return err
+	}
+
+	review, err := s.reviews.ReviewScene(ctx, scene)
+	if err != nil {
+		return err
+	}
+	if review != nil && review.Held() {
+		queueImage = false
+		scene.Status = heldStatus(review)
+		if err := s.db.WithContext(ctx).Model(scene).Update("status", scene.Status).Error; err != nil {
+			return err
 		}
+	}
 
-		imageURL, err := s.aiService.GenerateImage(ctx, scene.PromptForImage)
-		if err == nil {
-			scene.MediaURL = imageURL
-			scene.MediaType = "image"
-			scene.Status = "completed"
-			s.db.Save(&scene)
+	if queueImage {
+		taskInput := models.JSONMap{"prompt": scene.PromptForImage}
+		for k, v := range input {
+			taskInput[k] = v
+		}
+		if _, err := s.tasks.SubmitWithPrompt(ctx, TaskTypeImage, &project.ID, &scene.ID, taskInput, imageTpl); err != nil {
+			log.Printf("failed to queue image for scene %d: %v", scene.ID, err)
 		}
 	}
+	return nil
+}
+
+// GenerateSceneImage renders the keyframe image for a single scene with the
+// seed, LoRAs and reference images of its characters. An image held by
+// content review is kept for the reviewer but the scene is not completed.
+// An image for a prompt the scene was edited away from is dropped; the edit
+// queued its own. input is the image task's input, which may ask for a
+// variation from a fresh seed and carry an extra instruction.
+func (s *ProjectService) GenerateSceneImage(ctx context.Context, sceneID uint, input models.JSONMap) error {
+	var scene models.Scene
+	if err := s.db.WithContext(ctx).First(&scene, sceneID).Error; err != nil {
+		return err
+	}
+	if scene.Status == "blocked" {
+		return nil
+	}
+
+	scene.Status = "processing"
+	if err := s.db.WithContext(ctx).Model(&scene).Update("status", scene.Status).Error; err != nil {
+		return err
+	}
+
+	req, cast, err := s.characters.ImageRequest(ctx, &scene)
+	if err != nil {
+		return err
+	}
+	if variation, _ := input["variation"].(bool); variation {
+		req.Seed = 0
+	}
+	if instruction, _ := input["instruction"].(string); strings.TrimSpace(instruction) != "" {
+		req.Prompt += ", " + strings.TrimSpace(instruction)
+	}
+	image, err := s.aiService.GenerateImage(ctx, req)
+	if err != nil {
+		return err
+	}
+	if err := s.characters.KeepSeed(ctx, cast, image.Seed); err != nil {
+		log.Printf("failed to keep seed for scene %d characters: %v", scene.ID, err)
+	}
+	asset, err := s.assets.SaveBytes(ctx, models.AssetImage, AssetOwner{ProjectID: scene.ProjectID, SceneID: scene.ID}, image.Data, image.MIMEType)
+	if err != nil {
+		return fmt.Errorf("failed to store image: %w", err)
+	}
+
+	scene.MediaURL = asset.URL
+	scene.MediaAssetID = &asset.ID
+	scene.MediaType = "image"
+	scene.Status = "completed"
+	review, err := s.reviews.ReviewImage(ctx, &scene, image.Data)
+	if err != nil {
+		return err
+	}
+	if review != nil && review.Held() {
+		scene.Status = heldStatus(review)
+	}
+	// Only the media columns are written, so edits made meanwhile survive
+	result := s.db.WithContext(ctx).Model(&scene).Where("prompt_for_image = ?", scene.PromptForImage).
+		Select("media_url", "media_asset_id", "media_type", "status").
+		Updates(&scene)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	s.events.Emit(ctx, scene.ProjectID, events.ImageReady, map[string]interface{}{
+		"scene_id":     scene.ID,
+		"scene_number": scene.SceneNumber,
+		"media_url":    scene.MediaURL,
+	})
+
+	return s.RefreshProjectStatus(ctx, scene.ProjectID)
+}
+
+// dropStaleScenes deletes the scenes past the end of a regenerated script
+func (s *ProjectService) dropStaleScenes(ctx context.Context, projectID uint, count int) error {
+	var ids []uint
+	err := s.db.WithContext(ctx).Model(&models.Scene{}).
+		Where("project_id = ? AND scene_number > ?", projectID, count).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteScenes(tx, ids)
+	})
+}
+
+// MarkProjectFailed records that script generation gave up
+func (s *ProjectService) MarkProjectFailed(ctx context.Context, projectID uint, cause error) error {
+	if err := s.db.WithContext(ctx).Model(&models.Project{}).Where("id = ?", projectID).Update("status", "failed").Error; err != nil {
+		return err
+	}
+	s.events.Emit(ctx, projectID, events.ProjectFailed, map[string]interface{}{"error": cause.Error()})
+	return nil
+}
+
+// MarkSceneFailed records that a scene's media could not be produced
+func (s *ProjectService) MarkSceneFailed(ctx context.Context, sceneID uint) error {
+	var scene models.Scene
+	if err := s.db.WithContext(ctx).First(&scene, sceneID).Error; err != nil {
+		return err
+	}
+	scene.Status = "failed"
+	if err := s.db.WithContext(ctx).Model(&scene).Update("status", scene.Status).Error; err != nil {
+		return err
+	}
+	return s.RefreshProjectStatus(ctx, scene.ProjectID)
+}
 
-	project.Status = "completed"
-	s.db.Save(&project)
+// MarkProjectProcessing reopens a project while scene media is being produced
+func (s *ProjectService) MarkProjectProcessing(ctx context.Context, projectID uint) error {
+	return s.db.WithContext(ctx).Model(&models.Project{}).Where("id = ?", projectID).Update("status", "processing").Error
+}
+
+// RefreshProjectStatus completes a processing project once none of its scenes
+// are pending or processing. A project with a failed scene is marked failed;
+// one with scenes held by content review is blocked, or in review while a
+// human decides.
+func (s *ProjectService) RefreshProjectStatus(ctx context.Context, projectID uint) error {
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, projectID).Error; err != nil {
+		return err
+	}
+	if project.Status != "processing" {
+		return nil
+	}
 
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	err := s.db.WithContext(ctx).Model(&models.Scene{}).
+		Select("status, count(*) as count").
+		Where("project_id = ?", projectID).
+		Group("status").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	failed, blocked, inReview := false, false, false
+	for _, c := range counts {
+		switch c.Status {
+		case "pending", "processing":
+			return nil
+		case "failed":
+			failed = true
+		case "blocked":
+			blocked = true
+		case "review":
+			inReview = true
+		}
+	}
+
+	eventType := events.ProjectCompleted
+	switch {
+	case failed:
+		project.Status = "failed"
+		eventType = events.ProjectFailed
+	case blocked:
+		project.Status = "blocked"
+		eventType = events.ProjectHeld
+	case inReview:
+		project.Status = "review"
+		eventType = events.ProjectHeld
+	default:
+		project.Status = "completed"
+	}
+	if err := s.db.WithContext(ctx).Save(&project).Error; err != nil {
+		return err
+	}
+	s.events.Emit(ctx, projectID, eventType, map[string]interface{}{"status": project.Status})
 	return nil
 }
 
+// Publish makes a finished project public. Every scene must be clear of
+// content review and the rendered episode must be newer than the last
+// rejection, so rejected content never goes out in an old render.
+func (s *ProjectService) Publish(ctx context.Context, projectID uint) (*models.Project, error) {
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, projectID).Error; err != nil {
+		return nil, err
+	}
+	if project.Status == "published" {
+		return &project, nil
+	}
+
+	var held []int
+	err := s.db.WithContext(ctx).Model(&models.Scene{}).
+		Where("project_id = ? AND status IN ?", projectID, []string{"review", "blocked"}).
+		Order("scene_number ASC").
+		Pluck("scene_number", &held).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(held) > 0 {
+		return nil, &HeldScenesError{SceneNumbers: held}
+	}
+	if project.Status != "completed" {
+		return nil, fmt.Errorf("%w: project is %s", ErrProjectNotReady, project.Status)
+	}
+
+	var render models.AITask
+	err = s.db.WithContext(ctx).
+		Where("project_id = ? AND task_type = ? AND status = ?", projectID, TaskTypeRender, TaskCompleted).
+		Order("completed_at DESC").
+		First(&render).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || project.VideoURL == "" {
+		return nil, fmt.Errorf("%w: the project has not been rendered", ErrStaleRender)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lastRejection *time.Time
+	err = s.db.WithContext(ctx).Model(&models.ReviewResult{}).
+		Where("project_id = ? AND status IN ?", projectID, []string{models.ReviewRejected, models.ReviewAppealed}).
+		Select("MAX(COALESCE(decided_at, created_at))").
+		Scan(&lastRejection).Error
+	if err != nil {
+		return nil, err
+	}
+	if lastRejection != nil && !render.CreatedAt.After(*lastRejection) {
+		return nil, fmt.Errorf("%w: content was rejected after the last render", ErrStaleRender)
+	}
+
+	project.Status = "published"
+	if err := s.db.WithContext(ctx).Model(&project).Update("status", project.Status).Error; err != nil {
+		return nil, err
+	}
+	s.events.Emit(ctx, projectID, events.ProjectPublished, map[string]interface{}{"video_url": project.VideoURL})
+	return &project, nil
+}
+
 func (s *ProjectService) GetProjectWithScenes(projectID uint) (*models.Project, error) {
 	var project models.Project
 	err := s.db.Preload("Scenes").Preload("User").First(&project, projectID).Error
@@ -0,0 +1,186 @@
+package services
+
+// Prompt template names
+const (
+	PromptScript      = "script"
+	PromptEpisode     = "episode"
+	PromptScene       = "scene"
+	PromptImage       = "image"
+	PromptVideo       = "video"
+	PromptReview      = "review"
+	PromptTranslation = "translation"
+)
+
+// defaultPrompts are seeded as version 1 of each template and used as-is when
+// the database has no active version
+var defaultPrompts = map[string]string{
+	PromptScript: `你是一个专业的短剧编剧和分镜导演。你必须只输出严格JSON，不要输出任何解释、Markdown、代码块标记。
+
+JSON Schema（必须完全符合）：
+{
+  "title": "string",
+  "genre": "string",
+  "style": "string",
+  "characters": [{"name": "string", "age": "string", "appearance": "string", "wardrobe": "string"}],
+  "scenes": [
+    {
+      "scene_number": 1,
+      "title": "string",
+      "location": "string",
+      "characters": [{"name": "string", "emotion": "string"}],
+      "dialogue": "string",
+      "shot_type": "string",
+      "duration": 10
+    }
+  ]
+}
+
+characters 列出所有出场角色，appearance 写具体的外貌（发型、脸型、体型等），wardrobe 写服装，供每个镜头保持角色形象一致；scenes 中的角色名必须与 characters 中的 name 完全一致。
+{{- if or .Genre .Style .TargetDuration .Characters}}
+
+创作要求：
+{{- if .Genre}}
+- 类型：{{.Genre}}
+{{- end}}
+{{- if .Style}}
+- 风格：{{.Style}}
+{{- end}}
+{{- if .TargetDuration}}
+- 总时长约 {{.TargetDuration}} 秒，所有场景的 duration 之和应接近该值
+{{- end}}
+{{- if .Characters}}
+- 角色：{{join .Characters "、"}}
+{{- end}}
+{{- end}}`,
+
+	PromptEpisode: `你是一个专业的短剧编剧和分镜导演，正在编写一部多集短剧中的一集。你必须只输出严格JSON，不要输出任何解释、Markdown、代码块标记。
+
+JSON Schema（必须完全符合）：
+{
+  "title": "string",
+  "genre": "string",
+  "style": "string",
+  "summary": "string",
+  "characters": [{"name": "string", "age": "string", "appearance": "string", "wardrobe": "string"}],
+  "scenes": [
+    {
+      "scene_number": 1,
+      "title": "string",
+      "location": "string",
+      "characters": [{"name": "string", "emotion": "string"}],
+      "dialogue": "string",
+      "shot_type": "string",
+      "duration": 10
+    }
+  ]
+}
+
+title 写本集标题；summary 用两三句话概括本集发生的事，作为之后各集的前情提要。characters 列出本集所有出场角色，appearance 写具体的外貌（发型、脸型、体型等），wardrobe 写服装；scenes 中的角色名必须与 characters 中的 name 完全一致。
+
+剧集：{{.Series}}
+{{- if .Outline}}
+剧集大纲：
+{{.Outline}}
+{{- end}}
+本集：第{{.Episode}}集{{if .EpisodeCount}}（共{{.EpisodeCount}}集）{{end}}
+{{- if .Recap}}
+
+前情提要：
+{{- range .Recap}}
+- 第{{.EpisodeNumber}}集「{{.Title}}」：{{.Summary}}
+{{- end}}
+{{- end}}
+{{- if .Cast}}
+
+已有角色（沿用原名，外貌与服装保持不变）：
+{{- range .Cast}}
+- {{describe .}}
+{{- end}}
+{{- end}}
+{{- if or .Genre .Style .TargetDuration}}
+
+创作要求：
+{{- if .Genre}}
+- 类型：{{.Genre}}
+{{- end}}
+{{- if .Style}}
+- 风格：{{.Style}}
+{{- end}}
+{{- if .TargetDuration}}
+- 本集总时长约 {{.TargetDuration}} 秒，所有场景的 duration 之和应接近该值
+{{- end}}
+{{- end}}
+
+本集剧情紧接前情提要，按大纲推进主线，不要重复已经发生的情节{{if and .EpisodeCount (lt .Episode .EpisodeCount)}}，结尾留下悬念{{end}}。`,
+
+	PromptScene: `你是一个专业的短剧编剧和分镜导演。请重写剧本中的一个场景。你必须只输出严格JSON，不要输出任何解释、Markdown、代码块标记。
+
+JSON Schema（必须完全符合）：
+{"scene_number": {{.Scene.SceneNumber}}, "title": "string", "location": "string", "characters": [{"name": "string", "emotion": "string"}], "dialogue": "string", "shot_type": "string", "duration": 10}
+
+剧情：{{.Prompt}}
+{{- if .Title}}
+标题：{{.Title}}
+{{- end}}
+{{- if .Genre}}
+类型：{{.Genre}}
+{{- end}}
+{{- if .Style}}
+风格：{{.Style}}
+{{- end}}
+{{- if .Characters}}
+角色：{{join .Characters "、"}}
+{{- end}}
+{{- if .Cast}}
+
+出场角色设定：
+{{- range .Cast}}
+- {{describe .}}
+{{- end}}
+{{- end}}
+{{- with .Previous}}
+
+上一场（第{{.SceneNumber}}场「{{.Title}}」，{{.Location}}）：
+{{.Dialogue}}
+{{- end}}
+
+当前场景（第{{.Scene.SceneNumber}}场）：
+标题：{{.Scene.Title}}
+地点：{{.Scene.Location}}
+镜头：{{.Scene.ShotType}}
+时长：{{.Scene.Duration}}秒
+台词：
+{{.Scene.Dialogue}}
+{{- with .Next}}
+
+下一场（第{{.SceneNumber}}场「{{.Title}}」，{{.Location}}）：
+{{.Dialogue}}
+{{- end}}
+
+{{if eq .Target "dialogue"}}只重写 dialogue，其余字段与当前场景保持一致{{else}}重写整个场景{{end}}，与上下场衔接连贯，台词按"角色名：台词"逐行书写，角色名必须与已有角色一致。`,
+
+	PromptImage: `{{.Scene.Location}}, {{.Scene.Title}}{{range .Cast}}, {{describe .}}{{end}}, {{.Style}}`,
+
+	PromptVideo: `{{.Prompt}}{{range .Cast}}, {{describe .}}{{end}}`,
+
+	PromptReview: `你是内容安全审核员。请审核以下内容是否包含色情、暴力、政治敏感、违法或侵权信息。
+只输出JSON：{"approved": true|false, "risk_level": "LOW|MEDIUM|HIGH", "categories": ["sexual|violence|political|illegal|infringement"], "reasons": ["string"]}
+
+待审核内容：
+{{.Content}}`,
+
+	PromptTranslation: `请将以下内容翻译为{{if .Language}}{{.Language}}{{else}}英文{{end}}，保持语气和格式，只输出译文：
+
+{{.Content}}`,
+}
+
+// promptDescriptions label the seeded defaults
+var promptDescriptions = map[string]string{
+	PromptScript:      "Built-in storyboard script system prompt",
+	PromptEpisode:     "Built-in series episode script system prompt",
+	PromptScene:       "Built-in single scene rewrite system prompt",
+	PromptImage:       "Built-in scene keyframe image prompt",
+	PromptVideo:       "Built-in scene video prompt",
+	PromptReview:      "Built-in content review prompt",
+	PromptTranslation: "Built-in translation prompt",
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownPrompt  = errors.New("unknown prompt name")
+	ErrInvalidPrompt  = errors.New("invalid prompt template")
+	ErrPromptInUse    = errors.New("prompt template is active or pinned by a project")
+	ErrPromptNotFound = errors.New("prompt template not found")
+)
+
+// PromptData holds the variables prompt templates can use
+type PromptData struct {
+	Prompt         string                    // the user's idea or request
+	Title          string                    // project title
+	Genre          string                    // e.g. 都市, 古装
+	Style          string                    // visual style
+	TargetDuration int                       // seconds
+	Characters     []string                  // character names
+	Scene          *models.Scene             // the scene an image or video prompt is for
+	Cast           []models.ProjectCharacter // character bible entries of everyone in Scene, or of the whole cast for episodes
+	Previous       *models.Scene             // the scene before Scene, for rewrites
+	Next           *models.Scene             // the scene after Scene, for rewrites
+	Target         string                    // what a rewrite replaces: dialogue or all
+	Content        string                    // text to review or translate
+	Language       string                    // translation target language
+	Series         string                    // series title, for episodes
+	Outline        string                    // series outline
+	Episode        int                       // the episode being written
+	EpisodeCount   int                       // planned episodes in the season
+	Recap          []models.Project          // earlier episodes with their summaries
+}
+
+var promptFuncs = template.FuncMap{
+	"join":     strings.Join,
+	"describe": describeCharacter,
+}
+
+// describeCharacter is a character's canonical look, e.g.
+// "林晓: 25岁, 齐肩黑发, 白衬衫"
+func describeCharacter(c models.ProjectCharacter) string {
+	parts := make([]string, 0, 3)
+	if c.Age != "" {
+		parts = append(parts, c.Age)
+	}
+	for _, part := range []string{c.Appearance, c.Wardrobe} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return c.Name
+	}
+	return c.Name + ": " + strings.Join(parts, ", ")
+}
+
+// PromptService stores versioned prompt templates and renders them
+type PromptService struct {
+	db *gorm.DB
+
+	// Versions are immutable, so parsed templates can be cached by ID
+	mu     sync.RWMutex
+	parsed map[string]*template.Template
+}
+
+func NewPromptService(db *gorm.DB) *PromptService {
+	return &PromptService{db: db, parsed: map[string]*template.Template{}}
+}
+
+// PromptNames lists the known template names
+func PromptNames() []string {
+	names := make([]string, 0, len(defaultPrompts))
+	for name := range defaultPrompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func validPromptName(name string) bool {
+	_, ok := defaultPrompts[name]
+	return ok
+}
+
+// SeedDefaults stores each built-in template as a version the first time
+// this build sees its body, so changed built-ins reach existing databases.
+// Built-in versions have no CreatedBy. A new one is only activated where the
+// active version is still a built-in; templates an admin activated are kept.
+func (s *PromptService) SeedDefaults(ctx context.Context) error {
+	for _, name := range PromptNames() {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&models.PromptTemplate{}).Where("name = ? AND body = ? AND created_by IS NULL", name, defaultPrompts[name]).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			var latest int
+			if err := tx.Model(&models.PromptTemplate{}).Where("name = ?", name).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+				return err
+			}
+			var active models.PromptTemplate
+			err := tx.Where("name = ? AND is_active = ?", name, true).Order("version DESC").First(&active).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			builtIn := err != nil || active.CreatedBy == nil
+
+			tpl := &models.PromptTemplate{
+				Name:        name,
+				Version:     latest + 1,
+				Body:        defaultPrompts[name],
+				Description: promptDescriptions[name],
+			}
+			if err := tx.Create(tpl).Error; err != nil {
+				return err
+			}
+			if builtIn {
+				return activateTemplate(tx, tpl)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to seed %s prompt: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Resolve picks the template a project uses: its pinned version, else the
+// active version, else the built-in default (which has ID 0)
+func (s *PromptService) Resolve(ctx context.Context, projectID uint, name string) (*models.PromptTemplate, error) {
+	if !validPromptName(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+
+	if projectID != 0 {
+		var pin models.ProjectPromptPin
+		err := s.db.WithContext(ctx).Preload("Template").Where("project_id = ? AND name = ?", projectID, name).First(&pin).Error
+		if err == nil {
+			return &pin.Template, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	var tpl models.PromptTemplate
+	err := s.db.WithContext(ctx).Where("name = ? AND is_active = ?", name, true).Order("version DESC").First(&tpl).Error
+	if err == nil {
+		return &tpl, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &models.PromptTemplate{Name: name, Body: defaultPrompts[name]}, nil
+}
+
+// Render executes a template with the given variables. Templates that don't
+// place the cast themselves get every cast member's description appended, so
+// older image and video templates keep characters consistent too.
+func (s *PromptService) Render(tpl *models.PromptTemplate, data PromptData) (string, error) {
+	key := fmt.Sprintf("%d", tpl.ID)
+	if tpl.ID == 0 {
+		key = "default:" + tpl.Name
+	}
+
+	s.mu.RLock()
+	t, ok := s.parsed[key]
+	s.mu.RUnlock()
+	if !ok {
+		var err error
+		if t, err = parsePrompt(tpl.Name, tpl.Body); err != nil {
+			return "", err
+		}
+		s.mu.Lock()
+		s.parsed[key] = t
+		s.mu.Unlock()
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt v%d: %w", tpl.Name, tpl.Version, err)
+	}
+	text := strings.TrimSpace(buf.String())
+	if !strings.Contains(tpl.Body, ".Cast") {
+		for _, c := range data.Cast {
+			text += ", " + describeCharacter(c)
+		}
+	}
+	return text, nil
+}
+
+// Prompt resolves and renders in one step
+func (s *PromptService) Prompt(ctx context.Context, projectID uint, name string, data PromptData) (string, *models.PromptTemplate, error) {
+	tpl, err := s.Resolve(ctx, projectID, name)
+	if err != nil {
+		return "", nil, err
+	}
+	text, err := s.Render(tpl, data)
+	return text, tpl, err
+}
+
+func parsePrompt(name, body string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(promptFuncs).Option("missingkey=zero").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrInvalidPrompt, name, err)
+	}
+	return t, nil
+}
+
+// checkPrompt rejects templates that don't parse or fail on sample data
+func checkPrompt(name, body string) error {
+	t, err := parsePrompt(name, body)
+	if err != nil {
+		return err
+	}
+	sample := PromptData{
+		Prompt:         "sample",
+		Genre:          "sample",
+		Style:          "sample",
+		TargetDuration: 30,
+		Characters:     []string{"sample"},
+		Scene:          &models.Scene{SceneNumber: 1, Title: "sample", Location: "sample"},
+		Cast:           []models.ProjectCharacter{{Name: "sample", Appearance: "sample"}},
+		Previous:       &models.Scene{SceneNumber: 1, Title: "sample", Dialogue: "sample"},
+		Next:           &models.Scene{SceneNumber: 2, Title: "sample", Dialogue: "sample"},
+		Target:         "all",
+		Content:        "sample",
+		Series:         "sample",
+		Outline:        "sample",
+		Episode:        2,
+		EpisodeCount:   3,
+		Recap:          []models.Project{{Title: "sample", EpisodeNumber: 1, Summary: "sample"}},
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, sample); err != nil {
+		return fmt.Errorf("%w %s: %v", ErrInvalidPrompt, name, err)
+	}
+	return nil
+}
+
+// ListTemplates returns every version, newest first, optionally for one name
+func (s *PromptService) ListTemplates(ctx context.Context, name string) ([]models.PromptTemplate, error) {
+	query := s.db.WithContext(ctx).Order("name ASC, version DESC")
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	var templates []models.PromptTemplate
+	err := query.Find(&templates).Error
+	return templates, err
+}
+
+// GetTemplate retrieves one template version
+func (s *PromptService) GetTemplate(ctx context.Context, id uint) (*models.PromptTemplate, error) {
+	var tpl models.PromptTemplate
+	if err := s.db.WithContext(ctx).First(&tpl, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPromptNotFound
+		}
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// CreateVersion stores a new version of a template, optionally activating it
+func (s *PromptService) CreateVersion(ctx context.Context, name, body, description string, activate bool, userID uint) (*models.PromptTemplate, error) {
+	if !validPromptName(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+	if err := checkPrompt(name, body); err != nil {
+		return nil, err
+	}
+
+	tpl := &models.PromptTemplate{Name: name, Body: body, Description: description, CreatedBy: &userID}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.PromptTemplate{}).Where("name = ?", name).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		tpl.Version = latest + 1
+		if err := tx.Create(tpl).Error; err != nil {
+			return err
+		}
+		if activate {
+			return activateTemplate(tx, tpl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// Activate makes a version the default for projects without a pin
+func (s *PromptService) Activate(ctx context.Context, id uint) (*models.PromptTemplate, error) {
+	tpl, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return activateTemplate(tx, tpl)
+	})
+	return tpl, err
+}
+
+func activateTemplate(tx *gorm.DB, tpl *models.PromptTemplate) error {
+	if err := tx.Model(&models.PromptTemplate{}).Where("name = ? AND id <> ?", tpl.Name, tpl.ID).Update("is_active", false).Error; err != nil {
+		return err
+	}
+	tpl.IsActive = true
+	return tx.Model(tpl).Update("is_active", true).Error
+}
+
+// DeleteTemplate removes a version that is neither active nor pinned
+func (s *PromptService) DeleteTemplate(ctx context.Context, id uint) error {
+	tpl, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+	var pins int64
+	if err := s.db.WithContext(ctx).Model(&models.ProjectPromptPin{}).Where("template_id = ?", id).Count(&pins).Error; err != nil {
+		return err
+	}
+	if tpl.IsActive || pins > 0 {
+		return ErrPromptInUse
+	}
+	return s.db.WithContext(ctx).Delete(tpl).Error
+}
+
+// ProjectPrompt is the template a project resolves for one name
+type ProjectPrompt struct {
+	Name     string                 `json:"name"`
+	Pinned   bool                   `json:"pinned"`
+	Template *models.PromptTemplate `json:"template"`
+}
+
+// ProjectPrompts reports which version of every template a project uses
+func (s *PromptService) ProjectPrompts(ctx context.Context, projectID uint) ([]ProjectPrompt, error) {
+	var pins []models.ProjectPromptPin
+	if err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Find(&pins).Error; err != nil {
+		return nil, err
+	}
+	pinned := map[string]bool{}
+	for _, pin := range pins {
+		pinned[pin.Name] = true
+	}
+
+	var out []ProjectPrompt
+	for _, name := range PromptNames() {
+		tpl, err := s.Resolve(ctx, projectID, name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ProjectPrompt{Name: name, Pinned: pinned[name], Template: tpl})
+	}
+	return out, nil
+}
+
+// Pin fixes a project to one version of a template
+func (s *PromptService) Pin(ctx context.Context, projectID uint, name string, version int) (*models.ProjectPromptPin, error) {
+	if !validPromptName(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+	var tpl models.PromptTemplate
+	if err := s.db.WithContext(ctx).Where("name = ? AND version = ?", name, version).First(&tpl).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPromptNotFound
+		}
+		return nil, err
+	}
+
+	var pin models.ProjectPromptPin
+	err := s.db.WithContext(ctx).Where("project_id = ? AND name = ?", projectID, name).First(&pin).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	pin.ProjectID = projectID
+	pin.Name = name
+	pin.TemplateID = tpl.ID
+	if err := s.db.WithContext(ctx).Save(&pin).Error; err != nil {
+		return nil, err
+	}
+	pin.Template = tpl
+	return &pin, nil
+}
+
+// Unpin returns a project to the active version of a template
+func (s *PromptService) Unpin(ctx context.Context, projectID uint, name string) error {
+	return s.db.WithContext(ctx).Where("project_id = ? AND name = ?", projectID, name).Delete(&models.ProjectPromptPin{}).Error
+}
+
+// recordPrompt notes on the task which template version produced its prompt
+func recordPrompt(task *models.AITask, tpl *models.PromptTemplate) {
+	if task == nil || tpl == nil {
+		return
+	}
+	task.PromptTemplateID = nil
+	if tpl.ID != 0 {
+		id := tpl.ID
+		task.PromptTemplateID = &id
+	}
+	task.PromptVersion = tpl.Version
+}
@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/events"
+	"github.com/richard9219/3kstory/internal/media"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/subtitle"
+	"gorm.io/gorm"
+)
+
+// ErrRenderInProgress is returned when a project already has a render queued
+// or running
+var ErrRenderInProgress = errors.New("a render is already in progress for this project")
+
+// MissingMediaError lists the scenes that have neither an image nor a video
+type MissingMediaError struct {
+	SceneNumbers []int
+}
+
+func (e *MissingMediaError) Error() string {
+	nums := make([]string, 0, len(e.SceneNumbers))
+	for _, n := range e.SceneNumbers {
+		nums = append(nums, fmt.Sprint(n))
+	}
+	return "scenes without media: " + strings.Join(nums, ", ")
+}
+
+// HeldScenesError lists the scenes content review holds back
+type HeldScenesError struct {
+	SceneNumbers []int
+}
+
+func (e *HeldScenesError) Error() string {
+	nums := make([]string, 0, len(e.SceneNumbers))
+	for _, n := range e.SceneNumbers {
+		nums = append(nums, fmt.Sprint(n))
+	}
+	return "scenes held by content review: " + strings.Join(nums, ", ")
+}
+
+// RenderOptions are the choices a render request can make
+type RenderOptions struct {
+	Transition media.Transition
+	// TransitionMS is how long scenes overlap for crossfades and fades
+	TransitionMS int
+	AspectRatio  string
+	Subtitles    subtitle.Mode
+}
+
+// Share of the progress bar for each stage; normalizing the clips takes most
+// of the time
+const (
+	renderNormalizeShare = 0.7
+	renderAssembleShare  = 0.1
+	renderMixShare       = 0.08
+	renderSubtitleShare  = 0.07
+)
+
+// RenderService assembles a project's scenes into the finished episode: one
+// MP4 stored as an export asset
+type RenderService struct {
+	cfg       *config.Config
+	db        *gorm.DB
+	tasks     *TaskService
+	assets    *AssetService
+	subtitles *SubtitleService
+	music     *MusicService
+	events    *events.Broker
+}
+
+func NewRenderService(cfg *config.Config, db *gorm.DB, tasks *TaskService, assets *AssetService, subtitles *SubtitleService, music *MusicService, broker *events.Broker) *RenderService {
+	return &RenderService{cfg: cfg, db: db, tasks: tasks, assets: assets, subtitles: subtitles, music: music, events: broker}
+}
+
+// Options validates a render request, filling in the configured defaults
+func (s *RenderService) Options(transition string, transitionMS *int, aspectRatio, subtitles string) (RenderOptions, error) {
+	if transition == "" {
+		transition = s.cfg.Render.Transition
+	}
+	t, err := media.ParseTransition(transition)
+	if err != nil {
+		return RenderOptions{}, err
+	}
+	if subtitles == "" {
+		subtitles = s.cfg.Render.Subtitles
+	}
+	mode, err := subtitle.ParseMode(subtitles)
+	if err != nil {
+		return RenderOptions{}, err
+	}
+	opts := RenderOptions{Transition: t, TransitionMS: s.cfg.Render.TransitionMS, AspectRatio: "16:9", Subtitles: mode}
+	if transitionMS != nil {
+		if *transitionMS < 0 || *transitionMS > 5000 {
+			return RenderOptions{}, fmt.Errorf("transition_ms must be between 0 and 5000")
+		}
+		opts.TransitionMS = *transitionMS
+	}
+	switch aspectRatio {
+	case "", "16:9":
+	case "9:16":
+		opts.AspectRatio = aspectRatio
+	default:
+		return RenderOptions{}, fmt.Errorf("aspect_ratio must be 16:9 or 9:16")
+	}
+	return opts, nil
+}
+
+// Submit queues a render of the project once every scene has media and none
+// is held by content review
+func (s *RenderService) Submit(ctx context.Context, projectID uint, opts RenderOptions) (*models.AITask, error) {
+	var running models.AITask
+	err := s.db.WithContext(ctx).
+		Where("project_id = ? AND task_type = ? AND status IN ?", projectID, TaskTypeRender, []string{TaskPending, TaskProcessing}).
+		First(&running).Error
+	if err == nil {
+		return &running, ErrRenderInProgress
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	scenes, err := s.scenes(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(scenes) == 0 {
+		return nil, errors.New("project has no scenes")
+	}
+	var missing, held []int
+	for _, scene := range scenes {
+		switch {
+		case scene.Status == "review" || scene.Status == "blocked":
+			held = append(held, scene.SceneNumber)
+		case scene.MediaURL == "":
+			missing = append(missing, scene.SceneNumber)
+		}
+	}
+	if len(held) > 0 {
+		return nil, &HeldScenesError{SceneNumbers: held}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingMediaError{SceneNumbers: missing}
+	}
+
+	return s.tasks.Submit(ctx, TaskTypeRender, &projectID, nil, models.JSONMap{
+		"transition":    string(opts.Transition),
+		"transition_ms": opts.TransitionMS,
+		"aspect_ratio":  opts.AspectRatio,
+		"subtitles":     string(opts.Subtitles),
+	})
+}
+
+// RenderProject normalizes every scene's clip, joins them with the requested
+// transition and stores the result as the project's video, replacing the
+// previous render. Stills become Ken Burns clips of the scene's duration,
+// dialogue tracks are mixed in, music plays under them and subtitles are
+// burned in or added as a track. Progress is written to the task and
+// streamed as render.progress events.
+func (s *RenderService) RenderProject(ctx context.Context, projectID uint, task *models.AITask) error {
+	if err := media.Available(); err != nil {
+		return err
+	}
+	opts, err := s.Options(inputString(task, "transition"), inputInt(task, "transition_ms"), inputString(task, "aspect_ratio"), inputString(task, "subtitles"))
+	if err != nil {
+		return err
+	}
+	scenes, err := s.scenes(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if len(scenes) == 0 {
+		return errors.New("project has no scenes")
+	}
+
+	if s.cfg.Render.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.cfg.Render.Timeout)*time.Second)
+		defer cancel()
+	}
+	dir, err := os.MkdirTemp("", "3kstory-render-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	report := s.progressReporter(ctx, projectID, task)
+	format := renderFormat(opts.AspectRatio, s.cfg.Render.FPS)
+
+	planned := 0.0
+	for _, scene := range scenes {
+		planned += sceneSeconds(scene)
+	}
+	clips := make([]string, 0, len(scenes))
+	lengths := make([]float64, 0, len(scenes))
+	done := 0.0
+	for i, scene := range scenes {
+		src, err := s.fetchMedia(ctx, scene.MediaURL, dir)
+		if err != nil {
+			return fmt.Errorf("scene %d: %w", scene.SceneNumber, err)
+		}
+		clip := media.Clip{
+			Path:    src,
+			Still:   scene.MediaType != "video",
+			Seconds: sceneSeconds(scene),
+			Motion:  media.MotionForShot(scene.ShotType),
+		}
+		if scene.AudioURL != "" {
+			clip.Audio, err = s.fetchMedia(ctx, scene.AudioURL, dir)
+			if err != nil {
+				return fmt.Errorf("scene %d dialogue: %w", scene.SceneNumber, err)
+			}
+			clip.AudioSeconds = float64(scene.AudioDurationMS) / 1000
+		}
+		out := filepath.Join(dir, fmt.Sprintf("clip-%03d.mp4", i))
+		weight := sceneSeconds(scene)
+		length, err := media.Normalize(ctx, clip, format, out, func(p float64) {
+			report("normalize", renderNormalizeShare*(done+p*weight)/planned)
+		})
+		if err != nil {
+			return fmt.Errorf("scene %d: %w", scene.SceneNumber, err)
+		}
+		os.Remove(src)
+		if clip.Audio != "" {
+			os.Remove(clip.Audio)
+		}
+		done += weight
+		clips = append(clips, out)
+		lengths = append(lengths, length)
+	}
+
+	episode := filepath.Join(dir, "episode.mp4")
+	offsets, overlap := media.Timeline(lengths, opts.Transition, float64(opts.TransitionMS)/1000)
+	err = media.Assemble(ctx, clips, lengths, opts.Transition, overlap, episode, func(p float64) {
+		report("assemble", renderNormalizeShare+renderAssembleShare*p)
+	})
+	if err != nil {
+		return err
+	}
+	episode, err = s.mixAudio(ctx, projectID, episode, scenes, offsets, lengths, dir, func(p float64) {
+		report("mix", renderNormalizeShare+renderAssembleShare+renderMixShare*p)
+	})
+	if err != nil {
+		return err
+	}
+	if opts.Subtitles != subtitle.ModeNone {
+		episode, err = s.addSubtitles(ctx, episode, scenes, offsets, lengths, opts, format, func(p float64) {
+			report("subtitles", renderNormalizeShare+renderAssembleShare+renderMixShare+renderSubtitleShare*p)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	report("upload", renderNormalizeShare+renderAssembleShare+renderMixShare+renderSubtitleShare)
+
+	f, err := os.Open(episode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	asset, err := s.assets.Save(ctx, models.AssetExport, AssetOwner{ProjectID: projectID}, f, "video/mp4")
+	if err != nil {
+		return fmt.Errorf("failed to store episode: %w", err)
+	}
+	if err := s.replaceProjectVideo(ctx, projectID, asset); err != nil {
+		return err
+	}
+
+	// Where every scene ended up, so subtitles can be downloaded in sync
+	timeline := make([]map[string]interface{}, 0, len(scenes))
+	for i, scene := range scenes {
+		timeline = append(timeline, map[string]interface{}{"scene_id": scene.ID, "start": offsets[i], "seconds": lengths[i]})
+	}
+	task.OutputData = models.JSONMap{
+		"progress":  100,
+		"stage":     "completed",
+		"asset_id":  asset.ID,
+		"video_url": asset.URL,
+		"timeline":  timeline,
+		"overlap":   overlap,
+	}
+	s.events.Emit(ctx, projectID, events.RenderReady, map[string]interface{}{
+		"task_id":   task.ID,
+		"asset_id":  asset.ID,
+		"video_url": asset.URL,
+	})
+	return nil
+}
+
+// mixAudio lays each scene's music under the episode, one cue for every run
+// of scenes sharing a track, then normalizes the loudness of the whole mix
+func (s *RenderService) mixAudio(ctx context.Context, projectID uint, episode string, scenes []models.Scene, offsets, lengths []float64, dir string, onProgress func(float64)) (string, error) {
+	tracks, err := s.music.SceneTracks(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	var cues []media.MusicCue
+	files := map[uint]string{}
+	for i := 0; i < len(scenes); {
+		track := tracks[scenes[i].ID]
+		j := i + 1
+		for j < len(scenes) && track != nil && tracks[scenes[j].ID] == track {
+			j++
+		}
+		if track != nil {
+			path, ok := files[track.ID]
+			if !ok {
+				if path, err = s.fetchMedia(ctx, track.URL, dir); err != nil {
+					return "", fmt.Errorf("music track %d: %w", track.ID, err)
+				}
+				files[track.ID] = path
+			}
+			cues = append(cues, media.MusicCue{Path: path, Start: offsets[i], Seconds: offsets[j-1] + lengths[j-1] - offsets[i]})
+		}
+		i = j
+	}
+
+	last := len(lengths) - 1
+	out := strings.TrimSuffix(episode, ".mp4") + "-mixed.mp4"
+	mix := media.MixOptions{MusicDB: s.cfg.Render.MusicVolume, LUFS: s.cfg.Render.Loudness}
+	if err := media.MixAudio(ctx, episode, cues, mix, offsets[last]+lengths[last], out, onProgress); err != nil {
+		return "", fmt.Errorf("failed to mix audio: %w", err)
+	}
+	os.Remove(episode)
+	return out, nil
+}
+
+// addSubtitles times the dialogue of every scene across the episode and
+// burns it in or adds it as a track. Episodes without dialogue are returned
+// as they are.
+func (s *RenderService) addSubtitles(ctx context.Context, episode string, scenes []models.Scene, offsets, lengths []float64, opts RenderOptions, f media.Format, onProgress func(float64)) (string, error) {
+	cues, err := s.subtitles.EpisodeCues(ctx, scenes, offsets, lengths)
+	if err != nil {
+		return "", err
+	}
+	if len(cues) == 0 {
+		return episode, nil
+	}
+
+	burn := opts.Subtitles == subtitle.ModeBurn
+	subs, data := episode+".srt", subtitle.SRT(cues)
+	if burn {
+		subs, data = episode+".ass", subtitle.ASS(cues, subtitle.Style{Font: s.cfg.Render.SubtitleFont, Width: f.Width, Height: f.Height})
+	}
+	if err := os.WriteFile(subs, data, 0o644); err != nil {
+		return "", err
+	}
+	last := len(lengths) - 1
+	out := strings.TrimSuffix(episode, ".mp4") + "-subtitled.mp4"
+	if err := media.AddSubtitles(ctx, episode, subs, burn, s.cfg.Render.SubtitleFontsDir, offsets[last]+lengths[last], out, onProgress); err != nil {
+		return "", fmt.Errorf("failed to add subtitles: %w", err)
+	}
+	os.Remove(episode)
+	return out, nil
+}
+
+// MarkRenderFailed reports a render that gave up
+func (s *RenderService) MarkRenderFailed(ctx context.Context, projectID uint, task *models.AITask, cause error) {
+	s.events.Emit(ctx, projectID, events.RenderFailed, map[string]interface{}{
+		"task_id": task.ID,
+		"error":   cause.Error(),
+	})
+}
+
+// replaceProjectVideo points the project at the new render and drops the
+// export it replaces
+func (s *RenderService) replaceProjectVideo(ctx context.Context, projectID uint, asset *models.Asset) error {
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, projectID).Error; err != nil {
+		return err
+	}
+	previous := project.VideoAssetID
+	err := s.db.WithContext(ctx).Model(&project).Updates(map[string]interface{}{
+		"video_url":      asset.URL,
+		"video_asset_id": asset.ID,
+	}).Error
+	if err != nil {
+		return err
+	}
+	if previous == nil || *previous == asset.ID {
+		return nil
+	}
+	var old models.Asset
+	if err := s.db.WithContext(ctx).First(&old, *previous).Error; err != nil {
+		return nil
+	}
+	if err := s.assets.Delete(ctx, &old); err != nil {
+		log.Printf("failed to delete previous render of project %d: %v", projectID, err)
+	}
+	return nil
+}
+
+// progressReporter records progress on the task and as events, once per
+// whole percent
+func (s *RenderService) progressReporter(ctx context.Context, projectID uint, task *models.AITask) func(stage string, fraction float64) {
+	last, lastStage := -1, ""
+	return func(stage string, fraction float64) {
+		percent := int(fraction * 100)
+		if percent > 99 {
+			percent = 99
+		}
+		if percent <= last && stage == lastStage {
+			return
+		}
+		last, lastStage = percent, stage
+		task.OutputData = models.JSONMap{"progress": percent, "stage": stage}
+		if err := s.db.WithContext(ctx).Model(&models.AITask{}).Where("id = ?", task.ID).Update("output_data", task.OutputData).Error; err != nil {
+			log.Printf("failed to record progress of render task %d: %v", task.ID, err)
+		}
+		s.events.Emit(ctx, projectID, events.RenderProgress, map[string]interface{}{
+			"task_id":  task.ID,
+			"stage":    stage,
+			"progress": percent,
+		})
+	}
+}
+
+func (s *RenderService) scenes(ctx context.Context, projectID uint) ([]models.Scene, error) {
+	var scenes []models.Scene
+	err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("scene_number ASC").Find(&scenes).Error
+	return scenes, err
+}
+
+// fetchMedia copies a scene's media into dir for ffmpeg
+func (s *RenderService) fetchMedia(ctx context.Context, url, dir string) (string, error) {
+	body, err := s.assets.Fetch(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	f, err := os.CreateTemp(dir, "src-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	return f.Name(), f.Close()
+}
+
+func renderFormat(aspectRatio string, fps int) media.Format {
+	if fps <= 0 {
+		fps = 30
+	}
+	if aspectRatio == "9:16" {
+		return media.Format{Width: 720, Height: 1280, FPS: fps}
+	}
+	return media.Format{Width: 1280, Height: 720, FPS: fps}
+}
+
+// sceneSeconds is how long a still scene is shown
+func sceneSeconds(scene models.Scene) float64 {
+	if scene.Duration <= 0 {
+		return 5
+	}
+	return float64(scene.Duration)
+}
+
+func inputString(task *models.AITask, key string) string {
+	v, _ := task.InputData[key].(string)
+	return v
+}
+
+// inputInt reads an optional number from the task input; JSON numbers decode
+// as float64
+func inputInt(task *models.AITask, key string) *int {
+	switch v := task.InputData[key].(type) {
+	case float64:
+		n := int(v)
+		return &n
+	case int:
+		return &v
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/events"
+	"github.com/richard9219/3kstory/internal/health"
+	"github.com/richard9219/3kstory/internal/jsonschema"
+	"github.com/richard9219/3kstory/internal/llm"
+	"github.com/richard9219/3kstory/internal/media"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/moderation"
+	"gorm.io/gorm"
+)
+
+// What REVIEW_HIGH_RISK does with high-risk content
+const (
+	ReviewHighRiskQueue = "queue"
+	ReviewHighRiskBlock = "block"
+)
+
+// categoryReviewError marks media that could not be reviewed at all
+const categoryReviewError = "review_error"
+
+// reviewAnswer is the shape text models are asked for; it matches the
+// review prompt
+type reviewAnswer struct {
+	Approved   bool     `json:"approved" jsonschema:"required"`
+	RiskLevel  string   `json:"risk_level" jsonschema:"required,description=LOW|MEDIUM|HIGH"`
+	Categories []string `json:"categories"`
+	Reasons    []string `json:"reasons"`
+}
+
+var reviewSchema = jsonschema.MustFor(reviewAnswer{})
+
+// ReviewService moderates content at each stage of generation: the project
+// prompt before the script is written, every scene's script before its image
+// is queued, and generated images and videos. Text goes to the review
+// service when AI_REVIEW_SERVICE_URL is set and to the text models otherwise;
+// media needs the review service. Every verdict is stored as a
+// review_results row. High-risk content is queued for a human reviewer or
+// blocked, and verdicts that cannot be read count as high risk.
+type ReviewService struct {
+	cfg      *config.Config
+	db       *gorm.DB
+	ai       *AIService
+	prompts  *PromptService
+	assets   *AssetService
+	reviewer moderation.Reviewer
+	health   *health.Monitor
+	events   *events.Broker
+}
+
+func NewReviewService(cfg *config.Config, db *gorm.DB, ai *AIService, prompts *PromptService, assets *AssetService, monitor *health.Monitor, broker *events.Broker) *ReviewService {
+	switch cfg.Review.HighRisk {
+	case ReviewHighRiskQueue, ReviewHighRiskBlock:
+	default:
+		log.Fatalf("Invalid REVIEW_HIGH_RISK %q (want queue or block)", cfg.Review.HighRisk)
+	}
+
+	s := &ReviewService{cfg: cfg, db: db, ai: ai, prompts: prompts, assets: assets, health: monitor, events: broker}
+	if cfg.AI.ReviewServiceURL != "" {
+		s.reviewer = moderation.NewHTTPReviewer(cfg.AI.ReviewServiceURL, time.Duration(cfg.Review.Timeout)*time.Second)
+		if monitor != nil {
+			monitor.Register(s.reviewer.Name(), health.KindReview, s.reviewer.Health)
+		}
+	} else if cfg.Review.Enabled {
+		log.Printf("AI_REVIEW_SERVICE_URL is not set: text is reviewed by the text models, images and videos are not reviewed")
+	}
+	return s
+}
+
+// reviewSubject is one piece of content to moderate
+type reviewSubject struct {
+	projectID uint
+	sceneID   *uint
+	stage     string
+	text      string
+	mediaURL  string
+	images    [][]byte
+}
+
+// ReviewPrompt checks a project's prompt before anything is generated from
+// it. A nil result means review is off.
+func (s *ReviewService) ReviewPrompt(ctx context.Context, project *models.Project) (*models.ReviewResult, error) {
+	return s.check(ctx, reviewSubject{
+		projectID: project.ID,
+		stage:     models.ReviewStagePrompt,
+		text:      project.Prompt,
+	})
+}
+
+// ReviewScene checks a scene's script: its title, location, characters and
+// dialogue
+func (s *ReviewService) ReviewScene(ctx context.Context, scene *models.Scene) (*models.ReviewResult, error) {
+	return s.check(ctx, reviewSubject{
+		projectID: scene.ProjectID,
+		sceneID:   &scene.ID,
+		stage:     models.ReviewStageScript,
+		text:      sceneText(scene),
+	})
+}
+
+// ReviewImage checks a scene's generated keyframe; data is the image file
+func (s *ReviewService) ReviewImage(ctx context.Context, scene *models.Scene, data []byte) (*models.ReviewResult, error) {
+	if s.reviewer == nil {
+		return nil, nil
+	}
+	return s.check(ctx, reviewSubject{
+		projectID: scene.ProjectID,
+		sceneID:   &scene.ID,
+		stage:     models.ReviewStageImage,
+		text:      scene.PromptForImage,
+		mediaURL:  scene.MediaURL,
+		images:    [][]byte{data},
+	})
+}
+
+// ReviewVideo checks a scene's generated clip by sampling REVIEW_VIDEO_FRAMES
+// frames from it with ffmpeg. It fails closed: a clip that cannot be
+// downloaded, sampled or reviewed goes to the human review queue.
+func (s *ReviewService) ReviewVideo(ctx context.Context, scene *models.Scene) *models.ReviewResult {
+	if !s.cfg.Review.Enabled || s.reviewer == nil {
+		return nil
+	}
+	subject := reviewSubject{
+		projectID: scene.ProjectID,
+		sceneID:   &scene.ID,
+		stage:     models.ReviewStageVideo,
+		text:      scene.PromptForVideo,
+		mediaURL:  scene.MediaURL,
+	}
+	if previous := s.previous(ctx, subject); previous != nil {
+		return previous
+	}
+
+	frames, err := s.sampleVideo(ctx, scene.MediaURL)
+	var result *models.ReviewResult
+	if err == nil {
+		subject.images = frames
+		result, err = s.check(ctx, subject)
+	}
+	if err != nil {
+		log.Printf("video review of scene %d failed, holding it: %v", scene.ID, err)
+		result, err = s.record(ctx, subject, &moderation.Verdict{
+			RiskLevel:  moderation.RiskHigh,
+			Categories: []string{categoryReviewError},
+			Reason:     err.Error(),
+			Reviewer:   s.reviewer.Name(),
+		})
+		if err != nil {
+			log.Printf("failed to record video review of scene %d: %v", scene.ID, err)
+			return &models.ReviewResult{Status: models.ReviewPending}
+		}
+	}
+	return result
+}
+
+// check reviews the subject and stores the verdict. Content already reviewed
+// at the same stage keeps its earlier verdict, so retries and reruns do not
+// review it again. Errors reaching the reviewer are returned for the job to
+// retry.
+func (s *ReviewService) check(ctx context.Context, subject reviewSubject) (*models.ReviewResult, error) {
+	if !s.cfg.Review.Enabled {
+		return nil, nil
+	}
+	if previous := s.previous(ctx, subject); previous != nil {
+		return previous, nil
+	}
+
+	var verdict *moderation.Verdict
+	var err error
+	if s.reviewer != nil {
+		verdict, err = s.reviewRemote(ctx, subject)
+	} else {
+		verdict, err = s.reviewText(ctx, subject)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s review failed: %w", subject.stage, err)
+	}
+	return s.record(ctx, subject, verdict)
+}
+
+// previous returns the latest verdict on the same content, if any
+func (s *ReviewService) previous(ctx context.Context, subject reviewSubject) *models.ReviewResult {
+	q := s.db.WithContext(ctx).Where("project_id = ? AND stage = ? AND content = ? AND media_url = ?",
+		subject.projectID, subject.stage, subject.text, subject.mediaURL)
+	if subject.sceneID != nil {
+		q = q.Where("scene_id = ?", *subject.sceneID)
+	} else {
+		q = q.Where("scene_id IS NULL")
+	}
+	var result models.ReviewResult
+	if err := q.Order("id DESC").First(&result).Error; err != nil {
+		return nil
+	}
+	return &result
+}
+
+func (s *ReviewService) reviewRemote(ctx context.Context, subject reviewSubject) (*moderation.Verdict, error) {
+	name := s.reviewer.Name()
+	if s.health != nil && !s.health.Allow(name) {
+		return nil, fmt.Errorf("%s: %w", name, ErrProviderUnavailable)
+	}
+	start := time.Now()
+	verdict, err := s.reviewer.Review(ctx, moderation.Request{
+		Stage:  subject.stage,
+		Text:   subject.text,
+		Images: subject.images,
+	})
+	if s.health != nil {
+		s.health.Record(name, err, time.Since(start))
+	}
+	return verdict, err
+}
+
+// reviewText asks the routed text models, with the project's review prompt
+func (s *ReviewService) reviewText(ctx context.Context, subject reviewSubject) (*moderation.Verdict, error) {
+	prompt, _, err := s.prompts.Prompt(ctx, subject.projectID, PromptReview, PromptData{Content: subject.text})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.ai.TextRouter().Chat(ctx, llm.ChatRequest{
+		Messages:    []llm.Message{{Role: "user", Content: prompt}},
+		Temperature: 0,
+		JSONSchema:  reviewSchema,
+		SchemaName:  "content_review",
+	})
+	if err != nil {
+		return nil, err
+	}
+	reviewer := "llm:" + resp.Provider
+	verdict, err := moderation.ParseVerdict(resp.Content)
+	if err != nil {
+		return moderation.Unparseable(reviewer, err), nil
+	}
+	verdict.Reviewer = reviewer
+	return verdict, nil
+}
+
+// record stores a verdict and announces it
+func (s *ReviewService) record(ctx context.Context, subject reviewSubject, verdict *moderation.Verdict) (*models.ReviewResult, error) {
+	result := &models.ReviewResult{
+		ProjectID:  subject.projectID,
+		SceneID:    subject.sceneID,
+		Stage:      subject.stage,
+		Content:    subject.text,
+		MediaURL:   subject.mediaURL,
+		Reviewer:   verdict.Reviewer,
+		Approved:   verdict.Approved,
+		RiskLevel:  verdict.RiskLevel,
+		Categories: models.StringArray(verdict.Categories),
+		Reason:     verdict.Reason,
+		Status:     s.status(verdict),
+	}
+	if err := s.db.WithContext(ctx).Create(result).Error; err != nil {
+		return nil, fmt.Errorf("failed to store review result: %w", err)
+	}
+	s.events.Emit(ctx, subject.projectID, events.ReviewVerdict, map[string]interface{}{
+		"review_id":  result.ID,
+		"scene_id":   result.SceneID,
+		"stage":      result.Stage,
+		"status":     result.Status,
+		"risk_level": result.RiskLevel,
+		"categories": result.Categories,
+	})
+	return result, nil
+}
+
+// status turns a verdict into a review status. Only clear high-risk
+// verdicts are blocked outright; content the reviewer could not judge always
+// goes to a human.
+func (s *ReviewService) status(v *moderation.Verdict) string {
+	unjudged := false
+	for _, c := range v.Categories {
+		if c == moderation.CategoryUnparseable || c == categoryReviewError {
+			unjudged = true
+		}
+	}
+	switch {
+	case v.Held() && v.RiskLevel == moderation.RiskHigh && !unjudged && s.cfg.Review.HighRisk == ReviewHighRiskBlock:
+		return models.ReviewRejected
+	case v.Held():
+		return models.ReviewPending
+	case v.RiskLevel == moderation.RiskMedium:
+		return models.ReviewFlagged
+	default:
+		return models.ReviewPassed
+	}
+}
+
+// sampleVideo downloads a clip and returns frames spread over it
+func (s *ReviewService) sampleVideo(ctx context.Context, url string) ([][]byte, error) {
+	if err := media.Available(); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "3kstory-review-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	body, err := s.assets.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	src := filepath.Join(dir, "clip")
+	f, err := os.Create(src)
+	if err == nil {
+		_, err = io.Copy(f, body)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+
+	info, err := media.Probe(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := media.Keyframes(ctx, src, info.Seconds, s.cfg.Review.VideoFrames, dir)
+	if err != nil {
+		return nil, err
+	}
+	frames := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, data)
+	}
+	return frames, nil
+}
+
+// heldStatus is the status of a scene or project whose content is held:
+// "blocked" when it was rejected, even under appeal, and "review" while a
+// human decides
+func heldStatus(result *models.ReviewResult) string {
+	if result.Status == models.ReviewRejected || result.Status == models.ReviewAppealed {
+		return "blocked"
+	}
+	return "review"
+}
+
+// sceneText is what a scene's script review reads
+func sceneText(scene *models.Scene) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n", scene.Title, scene.Location)
+	for _, c := range scene.Characters {
+		fmt.Fprintf(&b, "%s（%s）\n", c.Name, c.Emotion)
+	}
+	b.WriteString(scene.Dialogue)
+	return b.String()
+}
+
+// ProjectReviews lists a project's review results, newest first, optionally
+// only those with the given status
+func (s *ReviewService) ProjectReviews(ctx context.Context, projectID uint, status string) ([]models.ReviewResult, error) {
+	q := s.db.WithContext(ctx).Where("project_id = ?", projectID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var results []models.ReviewResult
+	err := q.Order("id DESC").Find(&results).Error
+	return results, err
+}
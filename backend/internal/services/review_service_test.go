@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/moderation"
+)
+
+func TestReviewStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		verdict  *moderation.Verdict
+		highRisk string
+		want     string
+	}{
+		{name: "low risk", verdict: &moderation.Verdict{Approved: true, RiskLevel: moderation.RiskLow}, highRisk: ReviewHighRiskBlock, want: models.ReviewPassed},
+		{name: "medium risk", verdict: &moderation.Verdict{Approved: true, RiskLevel: moderation.RiskMedium}, highRisk: ReviewHighRiskBlock, want: models.ReviewFlagged},
+		{name: "not approved", verdict: &moderation.Verdict{Approved: false, RiskLevel: moderation.RiskMedium}, highRisk: ReviewHighRiskBlock, want: models.ReviewPending},
+		{name: "high risk queued", verdict: &moderation.Verdict{Approved: false, RiskLevel: moderation.RiskHigh}, highRisk: ReviewHighRiskQueue, want: models.ReviewPending},
+		{name: "high risk blocked", verdict: &moderation.Verdict{Approved: false, RiskLevel: moderation.RiskHigh}, highRisk: ReviewHighRiskBlock, want: models.ReviewRejected},
+		{name: "approved high risk blocked", verdict: &moderation.Verdict{Approved: true, RiskLevel: moderation.RiskHigh}, highRisk: ReviewHighRiskBlock, want: models.ReviewRejected},
+		{name: "unparseable blocked", verdict: moderation.Unparseable("test", moderation.ErrUnparseable), highRisk: ReviewHighRiskBlock, want: models.ReviewPending},
+		{name: "unparseable queued", verdict: moderation.Unparseable("test", moderation.ErrUnparseable), highRisk: ReviewHighRiskQueue, want: models.ReviewPending},
+		{
+			name:     "review error blocked",
+			verdict:  &moderation.Verdict{Approved: false, RiskLevel: moderation.RiskHigh, Categories: []string{categoryReviewError}},
+			highRisk: ReviewHighRiskBlock,
+			want:     models.ReviewPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ReviewService{cfg: &config.Config{Review: config.ReviewConfig{HighRisk: tt.highRisk}}}
+			if got := s.status(tt.verdict); got != tt.want {
+				t.Fatalf("status() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// A reviewer answer that cannot be parsed waits for a human even when
+// high-risk content is blocked outright
+func TestReviewStatusUnparseableAnswer(t *testing.T) {
+	s := &ReviewService{cfg: &config.Config{Review: config.ReviewConfig{HighRisk: ReviewHighRiskBlock}}}
+	for _, raw := range []string{`{"risk_level": "LOW"}`, `{"approved": "yes"}`, `{"approved": true, "risk_level": "EXTREME"}`, "no verdict"} {
+		_, err := moderation.ParseVerdict(raw)
+		if !errors.Is(err, moderation.ErrUnparseable) {
+			t.Fatalf("ParseVerdict(%q) error = %v, want %v", raw, err, moderation.ErrUnparseable)
+		}
+		if got := s.status(moderation.Unparseable("test", err)); got != models.ReviewPending {
+			t.Fatalf("status() for %q = %q, want %q", raw, got, models.ReviewPending)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/media"
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+)
+
+// What Regenerate redoes
+const (
+	RegenerateDialogue = "dialogue"
+	RegenerateImage    = "image"
+	RegenerateVideo    = "video"
+	RegenerateAll      = "all"
+)
+
+// defaultRewriteRequest is sent when the user gave no instruction
+const defaultRewriteRequest = "请重写这个场景。"
+
+// RegenerateInput picks what the AI redoes for a scene
+type RegenerateInput struct {
+	Target string `json:"target" binding:"required,oneof=dialogue image video all"`
+	// Instruction steers the result, e.g. "make it more dramatic"
+	Instruction string `json:"instruction" binding:"max=1000"`
+	// Provider is the video backend for the video target; by default the
+	// one that made the scene's last video
+	Provider string `json:"provider" binding:"omitempty,oneof=runway pika local"`
+}
+
+// Regenerate has the AI redo part of a scene and keeps the scene as it was
+// as a revision. dialogue and all rewrite the script on the worker pool with
+// the neighbouring scenes as context, all also drawing a new image; image
+// and video queue new media right away. The task is returned for the
+// rewrite and video targets; the image is queued like any edited scene's.
+func (s *SceneService) Regenerate(ctx context.Context, projectID, sceneID uint, in RegenerateInput) (*models.Scene, *models.AITask, error) {
+	scene, err := s.editable(ctx, projectID, sceneID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if scene.Status == "pending" {
+		return nil, nil, fmt.Errorf("%w: scene %d", ErrScenesBusy, scene.SceneNumber)
+	}
+	instruction := strings.TrimSpace(in.Instruction)
+
+	switch in.Target {
+	case RegenerateDialogue, RegenerateAll:
+		task, err := s.queueRewrite(ctx, scene, in.Target, instruction)
+		return scene, task, err
+	case RegenerateImage:
+		if err := recordRevision(s.db.WithContext(ctx), scene, in.Target, instruction); err != nil {
+			return nil, nil, err
+		}
+		if err := s.projects.RegenerateImage(ctx, scene, instruction); err != nil {
+			return nil, nil, err
+		}
+		s.finish(ctx, projectID, models.AuthorAI, "regenerate", scene.ID)
+		return scene, nil, nil
+	case RegenerateVideo:
+		task, err := s.queueVideo(ctx, scene, instruction, in.Provider)
+		return scene, task, err
+	}
+	return nil, nil, fmt.Errorf("%w: unknown target %q", ErrInvalidSceneEdit, in.Target)
+}
+
+// queueRewrite marks the scene processing, so it isn't edited while the
+// model rewrites it, and queues the rewrite. The scene's status is kept in
+// the task to be restored afterwards.
+func (s *SceneService) queueRewrite(ctx context.Context, scene *models.Scene, target, instruction string) (*models.AITask, error) {
+	status := scene.Status
+	if err := s.db.WithContext(ctx).Model(scene).Update("status", "processing").Error; err != nil {
+		return nil, err
+	}
+	task, err := s.tasks.Submit(ctx, TaskTypeRewrite, &scene.ProjectID, &scene.ID, models.JSONMap{
+		"target":      target,
+		"instruction": instruction,
+		"status":      status,
+	})
+	if err != nil && task == nil {
+		s.db.WithContext(ctx).Model(scene).Update("status", status)
+		return nil, err
+	}
+	if err := s.projects.MarkProjectProcessing(ctx, scene.ProjectID); err != nil {
+		log.Printf("failed to reopen project %d for rewrite: %v", scene.ProjectID, err)
+	}
+	return task, nil
+}
+
+// RewriteScene runs a queued rewrite: the model gets the project, the
+// character bible and the scenes before and after this one, and returns the
+// scene with new dialogue, or entirely new for the all target. The old
+// version is kept as a revision; changed dialogue drops the scene's speech
+// and a new image is queued when the image prompt changed. The template
+// version used is recorded on task.
+func (s *SceneService) RewriteScene(ctx context.Context, sceneID uint, task *models.AITask) error {
+	var scene models.Scene
+	err := s.db.WithContext(ctx).First(&scene, sceneID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted while queued
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, scene.ProjectID).Error; err != nil {
+		return err
+	}
+	target, _ := task.InputData["target"].(string)
+	instruction, _ := task.InputData["instruction"].(string)
+	status, _ := task.InputData["status"].(string)
+	if status == "" {
+		status = "failed"
+	}
+
+	var neighbours []models.Scene
+	err = s.db.WithContext(ctx).
+		Where("project_id = ? AND scene_number IN ?", scene.ProjectID, []int{scene.SceneNumber - 1, scene.SceneNumber + 1}).
+		Find(&neighbours).Error
+	if err != nil {
+		return err
+	}
+	names, err := s.characters.Names(ctx, scene.ProjectID)
+	if err != nil {
+		return err
+	}
+	cast, err := s.characters.Cast(ctx, &scene)
+	if err != nil {
+		return err
+	}
+	data := PromptData{
+		Prompt:     project.Prompt,
+		Title:      project.Title,
+		Genre:      project.Genre,
+		Style:      project.Style,
+		Characters: names,
+		Scene:      &scene,
+		Cast:       cast,
+		Target:     target,
+	}
+	for i := range neighbours {
+		if neighbours[i].SceneNumber < scene.SceneNumber {
+			data.Previous = &neighbours[i]
+		} else {
+			data.Next = &neighbours[i]
+		}
+	}
+	system, tpl, err := s.prompts.Prompt(ctx, scene.ProjectID, PromptScene, data)
+	if err != nil {
+		return err
+	}
+	recordPrompt(task, tpl)
+
+	request := instruction
+	if request == "" {
+		request = defaultRewriteRequest
+	}
+	detail, err := s.aiService.RewriteScene(ctx, system, request)
+	if err != nil {
+		return err
+	}
+
+	if err := recordRevision(s.db.WithContext(ctx), &scene, target, instruction); err != nil {
+		return err
+	}
+	if dialogue := strings.TrimSpace(detail.Dialogue); dialogue != scene.Dialogue {
+		s.dropSpeech(ctx, &scene)
+		scene.Dialogue = dialogue
+	}
+	if target == RegenerateAll {
+		scene.Title = detail.Title
+		scene.Location = detail.Location
+		scene.ShotType = detail.ShotType
+		scene.Duration = detail.Duration
+		chars := make(models.CharacterArray, 0, len(detail.Characters))
+		for _, c := range detail.Characters {
+			chars = append(chars, models.Character{Name: c.Name, Emotion: c.Emotion})
+		}
+		scene.Characters = chars
+	}
+	scene.Status = status
+	if err := s.projects.RefreshScene(ctx, &scene, target == RegenerateAll); err != nil {
+		return err
+	}
+	s.finish(ctx, scene.ProjectID, models.AuthorAI, "regenerate", scene.ID)
+	return nil
+}
+
+// MarkRewriteFailed gives a scene whose rewrite gave up its status back; its
+// script and media were never touched
+func (s *SceneService) MarkRewriteFailed(ctx context.Context, sceneID uint, input models.JSONMap) error {
+	status, _ := input["status"].(string)
+	if status == "" {
+		status = "failed"
+	}
+	var scene models.Scene
+	if err := s.db.WithContext(ctx).First(&scene, sceneID).Error; err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Model(&scene).Where("status = ?", "processing").Update("status", status).Error; err != nil {
+		return err
+	}
+	return s.projects.RefreshProjectStatus(ctx, scene.ProjectID)
+}
+
+// queueVideo queues a new clip for the scene with the settings of its last
+// one, adding instruction to that prompt. A scene without a video gets one
+// from its keyframe, described by the video template.
+func (s *SceneService) queueVideo(ctx context.Context, scene *models.Scene, instruction, provider string) (*models.AITask, error) {
+	var last models.VideoTask
+	err := s.db.WithContext(ctx).Where("project_id = ? AND scene_id = ?", scene.ProjectID, scene.ID).Order("id DESC").First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	video := &models.VideoTask{
+		ProjectID:   scene.ProjectID,
+		SceneID:     scene.ID,
+		Provider:    provider,
+		Prompt:      last.Prompt,
+		ImageURL:    last.ImageURL,
+		Duration:    last.Duration,
+		AspectRatio: last.AspectRatio,
+		Motion:      last.Motion,
+		Status:      models.VideoTaskPending,
+	}
+	if video.Provider == "" {
+		video.Provider = last.Provider
+	}
+	if video.Provider == "" {
+		video.Provider = string(ProviderLocal)
+	}
+	if scene.MediaType == "image" && scene.MediaURL != "" {
+		video.ImageURL = scene.MediaURL
+	}
+	var tpl *models.PromptTemplate
+	if last.ID == 0 {
+		var project models.Project
+		if err := s.db.WithContext(ctx).First(&project, scene.ProjectID).Error; err != nil {
+			return nil, err
+		}
+		cast, err := s.characters.Cast(ctx, scene)
+		if err != nil {
+			return nil, err
+		}
+		video.Prompt, tpl, err = s.prompts.Prompt(ctx, scene.ProjectID, PromptVideo, PromptData{
+			Prompt: joinText(scene.Title, scene.Description),
+			Title:  project.Title,
+			Genre:  project.Genre,
+			Style:  project.Style,
+			Scene:  scene,
+			Cast:   cast,
+		})
+		if err != nil {
+			return nil, err
+		}
+		video.Duration = scene.Duration
+		video.AspectRatio = "16:9"
+		video.Motion = string(media.MotionForShot(scene.ShotType))
+	}
+	if instruction != "" {
+		video.Prompt += ", " + instruction
+	}
+
+	if err := recordRevision(s.db.WithContext(ctx), scene, RegenerateVideo, instruction); err != nil {
+		return nil, err
+	}
+	if err := s.videos.SaveVideoTask(ctx, video); err != nil {
+		return nil, err
+	}
+	task, err := s.tasks.SubmitWithPrompt(ctx, TaskTypeVideo, &scene.ProjectID, &scene.ID, models.JSONMap{"video_task_id": video.ID}, tpl)
+	if err != nil && task == nil {
+		video.Status = models.VideoTaskFailed
+		video.ErrorMessage = err.Error()
+		if saveErr := s.videos.SaveVideoTask(ctx, video); saveErr != nil {
+			log.Printf("failed to mark video task %d failed: %v", video.ID, saveErr)
+		}
+		return nil, err
+	}
+	s.finish(ctx, scene.ProjectID, models.AuthorAI, "regenerate", scene.ID)
+	return task, nil
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrRevisionNotFound is returned for revisions of another scene
+var ErrRevisionNotFound = errors.New("scene revision not found")
+
+// recordRevision keeps the scene's script and media before the AI replaces
+// them
+func recordRevision(db *gorm.DB, scene *models.Scene, reason, instruction string) error {
+	return db.Create(&models.SceneRevision{
+		SceneID:        scene.ID,
+		ProjectID:      scene.ProjectID,
+		Reason:         reason,
+		Instruction:    instruction,
+		Title:          scene.Title,
+		Description:    scene.Description,
+		Location:       scene.Location,
+		Characters:     scene.Characters,
+		Dialogue:       scene.Dialogue,
+		ShotType:       scene.ShotType,
+		Duration:       scene.Duration,
+		MediaType:      scene.MediaType,
+		MediaURL:       scene.MediaURL,
+		MediaAssetID:   scene.MediaAssetID,
+		PromptForImage: scene.PromptForImage,
+		PromptForVideo: scene.PromptForVideo,
+		Status:         scene.Status,
+	}).Error
+}
+
+// deleteScenes removes scenes with their revisions and dialogue lines
+func deleteScenes(tx *gorm.DB, ids []uint) error {
+	if err := tx.Where("scene_id IN ?", ids).Delete(&models.SceneRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("scene_id IN ?", ids).Delete(&models.DialogueLine{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Scene{}, ids).Error
+}
+
+// Revisions returns a scene's earlier versions, newest first
+func (s *SceneService) Revisions(ctx context.Context, projectID, sceneID uint) ([]models.SceneRevision, error) {
+	if _, err := s.Scene(ctx, projectID, sceneID); err != nil {
+		return nil, err
+	}
+	var revisions []models.SceneRevision
+	err := s.db.WithContext(ctx).Where("scene_id = ?", sceneID).Order("id DESC").Find(&revisions).Error
+	return revisions, err
+}
+
+// Restore rolls a scene back to a revision, keeping the current version as a
+// revision of its own. The revision's media comes back too when it had
+// finished, its asset still exists and the image prompt still renders the
+// same; otherwise a new image is queued.
+func (s *SceneService) Restore(ctx context.Context, projectID, sceneID, revisionID uint) (*models.Scene, error) {
+	scene, err := s.editable(ctx, projectID, sceneID)
+	if err != nil {
+		return nil, err
+	}
+	var revision models.SceneRevision
+	err = s.db.WithContext(ctx).Where("id = ? AND scene_id = ?", revisionID, sceneID).First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recordRevision(s.db.WithContext(ctx), scene, models.RevisionRestore, ""); err != nil {
+		return nil, err
+	}
+
+	if scene.Dialogue != revision.Dialogue {
+		s.dropSpeech(ctx, scene)
+	}
+	scene.Title = revision.Title
+	scene.Description = revision.Description
+	scene.Location = revision.Location
+	scene.Characters = revision.Characters
+	scene.Dialogue = revision.Dialogue
+	scene.ShotType = revision.ShotType
+	scene.Duration = revision.Duration
+
+	force := true
+	if revision.Status == "completed" && revision.MediaURL != "" && s.assetExists(ctx, revision.MediaAssetID) {
+		scene.MediaType = revision.MediaType
+		scene.MediaURL = revision.MediaURL
+		scene.MediaAssetID = revision.MediaAssetID
+		scene.PromptForImage = revision.PromptForImage
+		scene.PromptForVideo = revision.PromptForVideo
+		scene.Status = revision.Status
+		force = false
+	}
+	if err := s.db.WithContext(ctx).Save(scene).Error; err != nil {
+		return nil, err
+	}
+
+	s.refresh(ctx, scene, force)
+	s.finish(ctx, projectID, models.AuthorUser, "restore", scene.ID)
+	return scene, nil
+}
+
+func (s *SceneService) assetExists(ctx context.Context, id *uint) bool {
+	if id == nil {
+		return false
+	}
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Asset{}).Where("id = ?", *id).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// RestoreVersion puts a project's script back as it was in a version. The
+// storyboard as it is now is recorded first, and the restored script becomes
+// a new version, so restoring can itself be undone. Scenes deleted since are
+// recreated under their old IDs. A scene gets its media from the version
+// when that had finished and the asset still exists, keeps its current
+// media otherwise, and gets a new image whenever its image prompt changed.
+func (s *SceneService) RestoreVersion(ctx context.Context, projectID uint, number int) ([]models.Scene, error) {
+	if err := s.checkIdle(ctx, projectID); err != nil {
+		return nil, err
+	}
+	version, err := s.versions.Version(ctx, projectID, number)
+	if err != nil {
+		return nil, err
+	}
+	if version.Version != number {
+		return nil, ErrVersionNotFound
+	}
+	if _, err := s.versions.Snapshot(ctx, projectID, models.AuthorUser, "edit", ""); err != nil {
+		return nil, err
+	}
+
+	current, err := s.Scenes(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Scene, len(current))
+	for i := range current {
+		if current[i].Status == "processing" {
+			return nil, fmt.Errorf("%w: scene %d", ErrScenesBusy, current[i].SceneNumber)
+		}
+		byID[current[i].ID] = &current[i]
+	}
+
+	type restoredScene struct {
+		scene   *models.Scene
+		created bool
+		force   bool
+	}
+	restored := make([]restoredScene, 0, len(version.Scenes))
+	for _, snap := range version.Scenes {
+		scene, exists := byID[snap.ID]
+		if exists {
+			delete(byID, snap.ID)
+			if scene.Dialogue != snap.Dialogue {
+				s.dropSpeech(ctx, scene)
+			}
+		} else {
+			scene = &models.Scene{ID: snap.ID, ProjectID: projectID, MediaType: "image", Status: "pending"}
+		}
+		scene.SceneNumber = snap.SceneNumber
+		scene.Title = snap.Title
+		scene.Description = snap.Description
+		scene.Location = snap.Location
+		scene.Characters = snap.Characters
+		scene.Dialogue = snap.Dialogue
+		scene.ShotType = snap.ShotType
+		scene.Duration = snap.Duration
+		scene.MusicCue = snap.MusicCue
+		scene.MusicTrackID = snap.MusicTrackID
+		if snap.MusicTrackID != nil && !s.trackExists(ctx, projectID, *snap.MusicTrackID) {
+			scene.MusicCue = models.MusicCueAuto
+			scene.MusicTrackID = nil
+		}
+
+		hasMedia := exists
+		if snap.Status == "completed" && snap.MediaURL != "" && s.assetExists(ctx, snap.MediaAssetID) {
+			scene.MediaType = snap.MediaType
+			scene.MediaURL = snap.MediaURL
+			scene.MediaAssetID = snap.MediaAssetID
+			scene.PromptForImage = snap.PromptForImage
+			scene.PromptForVideo = snap.PromptForVideo
+			scene.Status = snap.Status
+			hasMedia = true
+		}
+		restored = append(restored, restoredScene{scene: scene, created: !exists, force: !hasMedia})
+	}
+	removed := make([]uint, 0, len(byID))
+	for id, scene := range byID {
+		s.dropSpeech(ctx, scene)
+		removed = append(removed, id)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Project{}).Where("id = ?", projectID).Updates(map[string]interface{}{
+			"title":           version.Title,
+			"description":     version.Description,
+			"prompt":          version.Prompt,
+			"genre":           version.Genre,
+			"style":           version.Style,
+			"target_duration": version.TargetDuration,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			if err := deleteScenes(tx, removed); err != nil {
+				return err
+			}
+		}
+		for _, r := range restored {
+			save := tx.Save
+			if r.created {
+				save = tx.Create
+			}
+			if err := save(r.scene).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(restored))
+	for _, r := range restored {
+		s.refresh(ctx, r.scene, r.force)
+		ids = append(ids, r.scene.ID)
+	}
+	s.finish(ctx, projectID, models.AuthorUser, "rollback", ids...)
+	return s.Scenes(ctx, projectID)
+}
+
+func (s *SceneService) trackExists(ctx context.Context, projectID, trackID uint) bool {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.MusicTrack{}).Where("id = ? AND project_id = ?", trackID, projectID).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/events"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/tts"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSceneNotFound is returned for scenes outside the project
+	ErrSceneNotFound = errors.New("scene not found")
+	// ErrScenesBusy is returned while the script is being generated or the
+	// project rendered, or when the scene's media is being produced
+	ErrScenesBusy = errors.New("scenes are being generated or rendered")
+	// ErrInvalidSceneEdit is returned for edits the storyboard can't take,
+	// such as merging scenes that are not adjacent
+	ErrInvalidSceneEdit = errors.New("invalid scene edit")
+)
+
+// SceneInput describes a scene's script; empty fields are left alone when
+// updating
+type SceneInput struct {
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Location    string             `json:"location"`
+	Characters  []models.Character `json:"characters"`
+	Dialogue    string             `json:"dialogue"`
+	ShotType    string             `json:"shot_type"`
+	Duration    int                `json:"duration" binding:"omitempty,min=1,max=60"`
+}
+
+// SceneService edits a project's storyboard. Every operation renumbers the
+// scenes in one transaction; scenes whose script changed lose their media and
+// are queued for a new image, and the project's render is dropped.
+type SceneService struct {
+	db         *gorm.DB
+	aiService  *AIService
+	tasks      *TaskService
+	prompts    *PromptService
+	projects   *ProjectService
+	videos     *VideoService
+	characters *CharacterService
+	speech     *SpeechService
+	versions   *VersionService
+	events     *events.Broker
+}
+
+func NewSceneService(db *gorm.DB, aiService *AIService, tasks *TaskService, prompts *PromptService, projects *ProjectService, videos *VideoService, characters *CharacterService, speech *SpeechService, versions *VersionService, broker *events.Broker) *SceneService {
+	return &SceneService{
+		db:         db,
+		aiService:  aiService,
+		tasks:      tasks,
+		prompts:    prompts,
+		projects:   projects,
+		videos:     videos,
+		characters: characters,
+		speech:     speech,
+		versions:   versions,
+		events:     broker,
+	}
+}
+
+// Scenes returns a project's scenes in order
+func (s *SceneService) Scenes(ctx context.Context, projectID uint) ([]models.Scene, error) {
+	var scenes []models.Scene
+	err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("scene_number ASC").Find(&scenes).Error
+	return scenes, err
+}
+
+// Scene returns one scene of a project
+func (s *SceneService) Scene(ctx context.Context, projectID, sceneID uint) (*models.Scene, error) {
+	var scene models.Scene
+	err := s.db.WithContext(ctx).Where("id = ? AND project_id = ?", sceneID, projectID).First(&scene).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSceneNotFound
+	}
+	return &scene, err
+}
+
+// Insert adds a scene at position, moving the scenes from there on back by
+// one. A position outside the storyboard appends the scene.
+func (s *SceneService) Insert(ctx context.Context, projectID uint, position int, in SceneInput) (*models.Scene, error) {
+	if strings.TrimSpace(in.Title) == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidSceneEdit)
+	}
+	if err := s.checkIdle(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	scene := &models.Scene{ProjectID: projectID, Duration: 5, MediaType: "image", MusicCue: models.MusicCueAuto, Status: "pending"}
+	applySceneInput(scene, in)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Scene{}).Where("project_id = ?", projectID).Count(&count).Error; err != nil {
+			return err
+		}
+		if position < 1 || position > int(count)+1 {
+			position = int(count) + 1
+		}
+		if err := shiftScenes(tx, projectID, position, 1); err != nil {
+			return err
+		}
+		scene.SceneNumber = position
+		return tx.Create(scene).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.refresh(ctx, scene, true)
+	s.finish(ctx, projectID, models.AuthorUser, "insert", scene.ID)
+	return scene, nil
+}
+
+// Update edits a scene's script. A new image is queued when the image prompt
+// changes, and changed dialogue drops the scene's speech.
+func (s *SceneService) Update(ctx context.Context, projectID, sceneID uint, in SceneInput) (*models.Scene, error) {
+	scene, err := s.editable(ctx, projectID, sceneID)
+	if err != nil {
+		return nil, err
+	}
+	before := *scene
+	applySceneInput(scene, in)
+	if sameScript(&before, scene) {
+		return scene, nil
+	}
+	if scene.Dialogue != before.Dialogue {
+		s.dropSpeech(ctx, scene)
+	}
+	if err := s.db.WithContext(ctx).Save(scene).Error; err != nil {
+		return nil, err
+	}
+
+	s.refresh(ctx, scene, false)
+	s.finish(ctx, projectID, models.AuthorUser, "update", scene.ID)
+	return scene, nil
+}
+
+// Delete removes a scene and closes the gap in the numbering
+func (s *SceneService) Delete(ctx context.Context, projectID, sceneID uint) error {
+	if err := s.checkIdle(ctx, projectID); err != nil {
+		return err
+	}
+	scene, err := s.Scene(ctx, projectID, sceneID)
+	if err != nil {
+		return err
+	}
+	s.dropSpeech(ctx, scene)
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteScenes(tx, []uint{scene.ID}); err != nil {
+			return err
+		}
+		return shiftScenes(tx, projectID, scene.SceneNumber+1, -1)
+	})
+	if err != nil {
+		return err
+	}
+	s.finish(ctx, projectID, models.AuthorUser, "delete", scene.ID)
+	return nil
+}
+
+// Reorder renumbers the scenes in the given order, which must list every
+// scene of the project exactly once. Scene media stays valid.
+func (s *SceneService) Reorder(ctx context.Context, projectID uint, sceneIDs []uint) ([]models.Scene, error) {
+	if err := s.checkIdle(ctx, projectID); err != nil {
+		return nil, err
+	}
+	scenes, err := s.Scenes(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[uint]bool, len(scenes))
+	for _, scene := range scenes {
+		current[scene.ID] = true
+	}
+	if len(sceneIDs) != len(scenes) {
+		return nil, fmt.Errorf("%w: scene_ids must list all %d scenes", ErrInvalidSceneEdit, len(scenes))
+	}
+	for _, id := range sceneIDs {
+		if !current[id] {
+			return nil, fmt.Errorf("%w: scene %d is missing, repeated or not in this project", ErrInvalidSceneEdit, id)
+		}
+		delete(current, id)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, id := range sceneIDs {
+			if err := tx.Model(&models.Scene{}).Where("id = ?", id).Update("scene_number", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.finish(ctx, projectID, models.AuthorUser, "reorder", sceneIDs...)
+	return s.Scenes(ctx, projectID)
+}
+
+// Split divides a scene in two after its first `at` dialogue turns; 0 splits
+// it in the middle. Both halves keep the scene's setting and characters,
+// share its duration by dialogue and get new images. Scenes shorter than two
+// seconds cannot be split.
+func (s *SceneService) Split(ctx context.Context, projectID, sceneID uint, at int) ([]models.Scene, error) {
+	first, err := s.editable(ctx, projectID, sceneID)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, c := range first.Characters {
+		names = append(names, c.Name)
+	}
+	turns := tts.Turns(first.Dialogue, names)
+	if len(turns) < 2 {
+		return nil, fmt.Errorf("%w: a scene needs at least two dialogue turns to split", ErrInvalidSceneEdit)
+	}
+	if at == 0 {
+		at = (len(turns) + 1) / 2
+	}
+	if at < 1 || at >= len(turns) {
+		return nil, fmt.Errorf("%w: at must be between 1 and %d", ErrInvalidSceneEdit, len(turns)-1)
+	}
+	if first.Duration < 2 {
+		return nil, fmt.Errorf("%w: a scene needs at least two seconds to split", ErrInvalidSceneEdit)
+	}
+	s.dropSpeech(ctx, first)
+
+	duration := first.Duration
+	firstDuration := splitDuration(duration, at, len(turns))
+	second := &models.Scene{
+		ProjectID:    projectID,
+		SceneNumber:  first.SceneNumber + 1,
+		Title:        first.Title,
+		Description:  first.Description,
+		Location:     first.Location,
+		Characters:   first.Characters,
+		Dialogue:     strings.Join(turns[at:], "\n"),
+		ShotType:     first.ShotType,
+		Duration:     duration - firstDuration,
+		MusicCue:     first.MusicCue,
+		MusicTrackID: first.MusicTrackID,
+		MediaType:    "image",
+		Status:       "pending",
+	}
+	first.Dialogue = strings.Join(turns[:at], "\n")
+	first.Duration = firstDuration
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := shiftScenes(tx, projectID, second.SceneNumber, 1); err != nil {
+			return err
+		}
+		if err := tx.Model(first).Select("dialogue", "duration").Updates(first).Error; err != nil {
+			return err
+		}
+		return tx.Create(second).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.refresh(ctx, first, true)
+	s.refresh(ctx, second, true)
+	s.finish(ctx, projectID, models.AuthorUser, "split", first.ID, second.ID)
+	return s.Scenes(ctx, projectID)
+}
+
+// splitDuration shares a scene's seconds by dialogue turns, leaving at least
+// one second on each side of the split
+func splitDuration(duration, at, turns int) int {
+	first := int(math.Round(float64(duration) * float64(at) / float64(turns)))
+	return min(max(first, 1), duration-1)
+}
+
+// Merge joins adjacent scenes into the first of them: dialogue and
+// descriptions are concatenated, durations added and characters combined.
+// The merged scene gets a new image.
+func (s *SceneService) Merge(ctx context.Context, projectID uint, sceneIDs []uint) ([]models.Scene, error) {
+	if len(sceneIDs) < 2 {
+		return nil, fmt.Errorf("%w: merging needs at least two scenes", ErrInvalidSceneEdit)
+	}
+	if err := s.checkIdle(ctx, projectID); err != nil {
+		return nil, err
+	}
+	var scenes []models.Scene
+	if err := s.db.WithContext(ctx).Where("project_id = ? AND id IN ?", projectID, sceneIDs).Find(&scenes).Error; err != nil {
+		return nil, err
+	}
+	if len(scenes) != len(sceneIDs) {
+		return nil, ErrSceneNotFound
+	}
+	sort.Slice(scenes, func(i, j int) bool { return scenes[i].SceneNumber < scenes[j].SceneNumber })
+	for i, scene := range scenes {
+		if scene.Status == "processing" {
+			return nil, fmt.Errorf("%w: scene %d", ErrScenesBusy, scene.SceneNumber)
+		}
+		if i > 0 && scene.SceneNumber != scenes[i-1].SceneNumber+1 {
+			return nil, fmt.Errorf("%w: scenes %d and %d are not adjacent", ErrInvalidSceneEdit, scenes[i-1].SceneNumber, scene.SceneNumber)
+		}
+	}
+
+	merged := &scenes[0]
+	seen := map[string]bool{}
+	for _, c := range merged.Characters {
+		seen[c.Name] = true
+	}
+	for _, scene := range scenes[1:] {
+		merged.Description = joinText(merged.Description, scene.Description)
+		merged.Dialogue = joinText(merged.Dialogue, scene.Dialogue)
+		merged.Duration += scene.Duration
+		for _, c := range scene.Characters {
+			if !seen[c.Name] {
+				seen[c.Name] = true
+				merged.Characters = append(merged.Characters, c)
+			}
+		}
+	}
+	for i := range scenes {
+		s.dropSpeech(ctx, &scenes[i])
+	}
+
+	last := scenes[len(scenes)-1].SceneNumber
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(merged).Select("description", "dialogue", "duration", "characters").Updates(merged).Error; err != nil {
+			return err
+		}
+		removed := make([]uint, 0, len(scenes)-1)
+		for _, scene := range scenes[1:] {
+			removed = append(removed, scene.ID)
+		}
+		if err := deleteScenes(tx, removed); err != nil {
+			return err
+		}
+		return shiftScenes(tx, projectID, last+1, -(len(scenes) - 1))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.refresh(ctx, merged, true)
+	s.finish(ctx, projectID, models.AuthorUser, "merge", sceneIDs...)
+	return s.Scenes(ctx, projectID)
+}
+
+// editable loads a scene for an edit that replaces its media
+func (s *SceneService) editable(ctx context.Context, projectID, sceneID uint) (*models.Scene, error) {
+	if err := s.checkIdle(ctx, projectID); err != nil {
+		return nil, err
+	}
+	scene, err := s.Scene(ctx, projectID, sceneID)
+	if err != nil {
+		return nil, err
+	}
+	if scene.Status == "processing" {
+		return nil, fmt.Errorf("%w: scene %d", ErrScenesBusy, scene.SceneNumber)
+	}
+	return scene, nil
+}
+
+// checkIdle refuses edits while a script job could write scenes or a render
+// could read them
+func (s *SceneService) checkIdle(ctx context.Context, projectID uint) error {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.AITask{}).
+		Where("project_id = ? AND task_type IN ? AND status IN ?", projectID,
+			[]string{TaskTypeScript, TaskTypeRender}, []string{TaskPending, TaskProcessing}).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrScenesBusy
+	}
+	return nil
+}
+
+// refresh queues the scene's new media. Callers store the edit first, so a
+// failure here only marks the scene failed for the user to retry.
+func (s *SceneService) refresh(ctx context.Context, scene *models.Scene, force bool) {
+	if err := s.projects.RefreshScene(ctx, scene, force); err != nil {
+		log.Printf("failed to refresh edited scene %d: %v", scene.ID, err)
+		if err := s.projects.MarkSceneFailed(ctx, scene.ID); err != nil {
+			log.Printf("failed to mark scene %d failed: %v", scene.ID, err)
+		}
+	}
+}
+
+// dropSpeech removes a scene's dialogue audio, which no longer matches
+func (s *SceneService) dropSpeech(ctx context.Context, scene *models.Scene) {
+	if scene.AudioURL == "" {
+		return
+	}
+	if err := s.speech.replaceTrack(ctx, scene, nil, nil); err != nil {
+		log.Printf("failed to drop speech of scene %d: %v", scene.ID, err)
+	}
+}
+
+// finish drops the project's render, which no longer matches the
+// storyboard, lets the project complete again once the edited scenes have
+// their media and records the new script as a version by author
+func (s *SceneService) finish(ctx context.Context, projectID uint, author, operation string, sceneIDs ...uint) {
+	err := s.db.WithContext(ctx).Model(&models.Project{}).Where("id = ?", projectID).
+		Updates(map[string]interface{}{"video_url": "", "video_asset_id": nil, "status": "processing"}).Error
+	if err == nil {
+		err = s.projects.RefreshProjectStatus(ctx, projectID)
+	}
+	if err != nil {
+		log.Printf("failed to refresh project %d after scene %s: %v", projectID, operation, err)
+	}
+	s.events.Emit(ctx, projectID, events.ScenesEdited, map[string]interface{}{
+		"operation": operation,
+		"scene_ids": sceneIDs,
+	})
+	if _, err := s.versions.Snapshot(ctx, projectID, author, operation, ""); err != nil {
+		log.Printf("failed to record version of project %d after scene %s: %v", projectID, operation, err)
+	}
+}
+
+// shiftScenes moves the scenes numbered from `from` on by delta
+func shiftScenes(tx *gorm.DB, projectID uint, from, delta int) error {
+	return tx.Model(&models.Scene{}).
+		Where("project_id = ? AND scene_number >= ?", projectID, from).
+		Update("scene_number", gorm.Expr("scene_number + ?", delta)).Error
+}
+
+func applySceneInput(scene *models.Scene, in SceneInput) {
+	if in.Title != "" {
+		scene.Title = strings.TrimSpace(in.Title)
+	}
+	if in.Description != "" {
+		scene.Description = strings.TrimSpace(in.Description)
+	}
+	if in.Location != "" {
+		scene.Location = strings.TrimSpace(in.Location)
+	}
+	if in.Characters != nil {
+		scene.Characters = in.Characters
+	}
+	if in.Dialogue != "" {
+		scene.Dialogue = strings.TrimSpace(in.Dialogue)
+	}
+	if in.ShotType != "" {
+		scene.ShotType = strings.TrimSpace(in.ShotType)
+	}
+	if in.Duration != 0 {
+		scene.Duration = in.Duration
+	}
+}
+
+// sameScript reports whether an edit left the scene's script as it was
+func sameScript(a, b *models.Scene) bool {
+	if a.Title != b.Title || a.Description != b.Description || a.Location != b.Location ||
+		a.Dialogue != b.Dialogue || a.ShotType != b.ShotType || a.Duration != b.Duration ||
+		len(a.Characters) != len(b.Characters) {
+		return false
+	}
+	for i := range a.Characters {
+		if a.Characters[i] != b.Characters[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func joinText(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "\n" + b
+}
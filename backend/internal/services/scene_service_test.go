@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+func TestSplitDuration(t *testing.T) {
+	tests := []struct {
+		duration, at, turns int
+		want                int
+	}{
+		{duration: 10, at: 1, turns: 2, want: 5},
+		{duration: 10, at: 1, turns: 3, want: 3},
+		{duration: 10, at: 2, turns: 3, want: 7},
+		{duration: 5, at: 1, turns: 10, want: 1},
+		{duration: 5, at: 9, turns: 10, want: 4},
+		{duration: 2, at: 1, turns: 2, want: 1},
+		{duration: 2, at: 1, turns: 5, want: 1},
+		{duration: 2, at: 4, turns: 5, want: 1},
+	}
+	for _, tt := range tests {
+		first := splitDuration(tt.duration, tt.at, tt.turns)
+		if first != tt.want {
+			t.Errorf("splitDuration(%d, %d, %d) = %d, want %d", tt.duration, tt.at, tt.turns, first, tt.want)
+		}
+		if second := tt.duration - first; second < 1 {
+			t.Errorf("splitDuration(%d, %d, %d) leaves %d seconds for the second half", tt.duration, tt.at, tt.turns, second)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// scriptStreamParser scans a streamed script JSON document and reports each
+// top-level field and each element of the "scenes" array as soon as it is
+// complete. It only tracks nesting and string state, so it never needs the
+// whole document; anything before the opening brace (a preface or a code
+// fence) is skipped.
+type scriptStreamParser struct {
+	buf []byte
+	pos int
+
+	started  bool
+	done     bool
+	inString bool
+	escaped  bool
+	stack    []byte
+
+	key        string
+	expectKey  bool
+	keyStart   int
+	valueStart int
+	elemStart  int
+	sceneIndex int
+
+	onField func(key string, raw []byte) error
+	onScene func(index int, raw []byte) error
+}
+
+// Write feeds the next chunk of model output into the parser
+func (p *scriptStreamParser) Write(chunk string) error {
+	p.buf = append(p.buf, chunk...)
+
+	for ; p.pos < len(p.buf) && !p.done; p.pos++ {
+		c := p.buf[p.pos]
+
+		if !p.started {
+			if c == '{' {
+				p.started = true
+				p.stack = append(p.stack, c)
+				p.expectKey = true
+			}
+			continue
+		}
+
+		if p.inString {
+			switch {
+			case p.escaped:
+				p.escaped = false
+			case c == '\\':
+				p.escaped = true
+			case c == '"':
+				p.inString = false
+				if len(p.stack) == 1 && p.expectKey {
+					p.key = ""
+					json.Unmarshal(p.buf[p.keyStart:p.pos+1], &p.key)
+				}
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			p.inString = true
+			if len(p.stack) == 1 && p.expectKey {
+				p.keyStart = p.pos
+			}
+		case ':':
+			if len(p.stack) == 1 {
+				p.expectKey = false
+				p.valueStart = p.pos + 1
+			}
+		case '{', '[':
+			if c == '{' && p.inScenes() {
+				p.elemStart = p.pos
+			}
+			p.stack = append(p.stack, c)
+		case '}', ']':
+			if len(p.stack) == 0 {
+				continue
+			}
+			p.stack = p.stack[:len(p.stack)-1]
+			if len(p.stack) == 0 {
+				p.done = true
+				if err := p.endField(p.pos); err != nil {
+					return err
+				}
+				continue
+			}
+			if c == '}' && p.inScenes() {
+				index := p.sceneIndex
+				p.sceneIndex++
+				if err := p.onScene(index, p.buf[p.elemStart:p.pos+1]); err != nil {
+					return err
+				}
+			}
+		case ',':
+			if len(p.stack) == 1 {
+				if err := p.endField(p.pos); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Done reports whether the root object has been closed
+func (p *scriptStreamParser) Done() bool {
+	return p.done
+}
+
+// inScenes reports whether the parser sits directly inside the scenes array
+func (p *scriptStreamParser) inScenes() bool {
+	return len(p.stack) == 2 && p.stack[1] == '[' && p.key == "scenes"
+}
+
+func (p *scriptStreamParser) endField(end int) error {
+	if p.expectKey {
+		return nil
+	}
+	p.expectKey = true
+
+	raw := bytes.TrimSpace(p.buf[p.valueStart:end])
+	if p.key == "scenes" || len(raw) == 0 || p.onField == nil {
+		return nil
+	}
+	return p.onField(p.key, raw)
+}
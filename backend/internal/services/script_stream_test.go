@@ -0,0 +1,126 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const streamDoc = "好的：\n```json\n" + `{
+  "title": "夜班 {特别篇}",
+  "genre": "都市",
+  "scenes": [
+    {"title": "开场", "dialogue": "林晓：\"你好\\再见\" {笑}", "characters": [{"name": "林晓"}]},
+    {"title": "结尾, [完]", "dialogue": "路人：\u4f60好", "duration": 5}
+  ],
+  "summary": "两人相遇"
+}` + "\n```"
+
+type streamEvent struct {
+	kind string // field or scene
+	key  string // field name, or scene index
+	raw  string
+}
+
+func parseChunks(t *testing.T, chunks []string) ([]streamEvent, bool) {
+	t.Helper()
+	var events []streamEvent
+	p := &scriptStreamParser{
+		onField: func(key string, raw []byte) error {
+			events = append(events, streamEvent{"field", key, string(raw)})
+			return nil
+		},
+		onScene: func(index int, raw []byte) error {
+			events = append(events, streamEvent{"scene", string(rune('0' + index)), string(raw)})
+			return nil
+		},
+	}
+	for _, chunk := range chunks {
+		if err := p.Write(chunk); err != nil {
+			t.Fatalf("Write(%q): %v", chunk, err)
+		}
+	}
+	return events, p.Done()
+}
+
+func TestScriptStreamParser(t *testing.T) {
+	want := []streamEvent{
+		{"field", "title", `"夜班 {特别篇}"`},
+		{"field", "genre", `"都市"`},
+		{"scene", "0", `{"title": "开场", "dialogue": "林晓：\"你好\\再见\" {笑}", "characters": [{"name": "林晓"}]}`},
+		{"scene", "1", `{"title": "结尾, [完]", "dialogue": "路人：\u4f60好", "duration": 5}`},
+		{"field", "summary", `"两人相遇"`},
+	}
+	split := func(marker string, offset int) []string {
+		i := strings.Index(streamDoc, marker)
+		if i < 0 {
+			t.Fatalf("marker %q not in document", marker)
+		}
+		return []string{streamDoc[:i+offset], streamDoc[i+offset:]}
+	}
+
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		{name: "whole", chunks: []string{streamDoc}},
+		{name: "mid key", chunks: split(`"genre"`, 3)},
+		{name: "mid string", chunks: split("特别篇", 3)},
+		{name: "mid string brace", chunks: split("{特别篇}", 1)},
+		{name: "mid escape quote", chunks: split(`\"你好`, 1)},
+		{name: "mid escape backslash", chunks: split(`\\再见`, 1)},
+		{name: "mid unicode escape", chunks: split(`\u4f60`, 3)},
+		{name: "mid multibyte rune", chunks: split("开场", 1)},
+		{name: "mid scene", chunks: split(`"characters"`, 0)},
+		{name: "mid nested object", chunks: split(`{"name"`, 1)},
+		{name: "between scenes", chunks: split("},\n    {", 2)},
+		{name: "comma inside string", chunks: split("结尾, [完]", len("结尾,"))},
+		{name: "before closing brace", chunks: split("}\n```", 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, done := parseChunks(t, tt.chunks)
+			if !done {
+				t.Fatal("parser not done after the closing brace")
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("events = %q\nwant %q", got, want)
+			}
+		})
+	}
+
+	t.Run("every split point", func(t *testing.T) {
+		for i := 0; i <= len(streamDoc); i++ {
+			got, _ := parseChunks(t, []string{streamDoc[:i], streamDoc[i:]})
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("split at %d (%q): events = %q", i, streamDoc[max(0, i-10):i], got)
+			}
+		}
+	})
+
+	t.Run("byte by byte", func(t *testing.T) {
+		chunks := make([]string, 0, len(streamDoc))
+		for i := 0; i < len(streamDoc); i++ {
+			chunks = append(chunks, streamDoc[i:i+1])
+		}
+		got, _ := parseChunks(t, chunks)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("events = %q", got)
+		}
+	})
+}
+
+func TestScriptStreamParserIncomplete(t *testing.T) {
+	// A scene is only reported once its closing brace arrives
+	got, done := parseChunks(t, []string{`{"title": "夜班", "scenes": [{"title": "开场"}, {"title": "结`})
+	want := []streamEvent{
+		{"field", "title", `"夜班"`},
+		{"scene", "0", `{"title": "开场"}`},
+	}
+	if done {
+		t.Fatal("parser done before the closing brace")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+}
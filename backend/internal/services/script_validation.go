@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/jsonschema"
+)
+
+// Schemas derived from the script types; their jsonschema tags hold the constraints
+var (
+	scriptSchema = jsonschema.MustFor(ScriptResult{})
+	sceneSchema  = jsonschema.MustFor(SceneDetail{})
+)
+
+// ScriptValidationError lists what was still wrong with the script after the
+// last repair attempt
+type ScriptValidationError struct {
+	Problems []string
+}
+
+func (e *ScriptValidationError) Error() string {
+	return fmt.Sprintf("script failed validation: %s", strings.Join(e.Problems, "; "))
+}
+
+// validateScript checks the script against its schema and the rules a schema
+// can't express, such as scene numbering
+func validateScript(result *ScriptResult) []string {
+	problems := validateAgainst(scriptSchema, result)
+	for i, scene := range result.Scenes {
+		if scene.SceneNumber != i+1 {
+			problems = append(problems, fmt.Sprintf("scenes[%d].scene_number: expected %d, got %d (scenes must be numbered 1, 2, 3... in order)", i, i+1, scene.SceneNumber))
+		}
+	}
+	return problems
+}
+
+// validateScene checks a single streamed scene before it is stored
+func validateScene(scene SceneDetail) []string {
+	return validateAgainst(sceneSchema, scene)
+}
+
+// validateAgainst runs the schema over the normalized value, so lenient
+// decoding (e.g. "10秒" for a duration) has already been applied
+func validateAgainst(schema *jsonschema.Schema, v interface{}) []string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return []string{err.Error()}
+	}
+
+	var problems []string
+	for _, e := range schema.Validate(generic) {
+		problems = append(problems, e.Error())
+	}
+	return problems
+}
+
+// scriptRepairPrompt asks the model to fix exactly what failed validation
+func scriptRepairPrompt(problems []string) string {
+	var b strings.Builder
+	b.WriteString("你上一次输出的JSON未通过校验，错误如下：\n")
+	for _, p := range problems {
+		b.WriteString("- ")
+		b.WriteString(p)
+		b.WriteString("\n")
+	}
+	b.WriteString("请修正以上所有错误，重新输出完整的JSON。只输出JSON，不要输出任何解释、Markdown、代码块标记。")
+	return b.String()
+}
+
+// UnmarshalJSON accepts numbers written as strings, e.g. "duration": "10秒"
+func (d *SceneDetail) UnmarshalJSON(data []byte) error {
+	type plain SceneDetail
+	aux := struct {
+		*plain
+		SceneNumber flexInt `json:"scene_number"`
+		Duration    flexInt `json:"duration"`
+	}{plain: (*plain)(d)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.SceneNumber = int(aux.SceneNumber)
+	d.Duration = int(aux.Duration)
+	return nil
+}
+
+// UnmarshalJSON also accepts a bare name, e.g. "characters": ["林晓"]
+func (c *CharDetail) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = CharDetail{Name: name}
+		return nil
+	}
+	type plain CharDetail
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+// UnmarshalJSON also accepts a numeric age, e.g. "age": 25
+func (c *CharacterProfile) UnmarshalJSON(data []byte) error {
+	type plain CharacterProfile
+	aux := struct {
+		*plain
+		Age interface{} `json:"age"`
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	switch age := aux.Age.(type) {
+	case string:
+		c.Age = age
+	case float64:
+		c.Age = strconv.FormatFloat(age, 'f', -1, 64)
+	}
+	return nil
+}
+
+var leadingNumber = regexp.MustCompile(`-?\d+(\.\d+)?`)
+
+// flexInt decodes integers the model wrote as floats or strings
+type flexInt int
+
+func (n *flexInt) UnmarshalJSON(data []byte) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err == nil {
+		*n = flexInt(math.Round(f))
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("expected a number, got %s", data)
+	}
+	// Unparseable strings decode to 0 and are reported by validation
+	*n = 0
+	if m := leadingNumber.FindString(s); m != "" {
+		if f, err := strconv.ParseFloat(m, 64); err == nil {
+			*n = flexInt(math.Round(f))
+		}
+	}
+	return nil
+}
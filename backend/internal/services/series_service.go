@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrSeriesNotFound is returned for series the user doesn't have
+	ErrSeriesNotFound = errors.New("series not found")
+	// ErrSeriesBusy is returned when an episode of the series is still being
+	// written: the next one needs its summary
+	ErrSeriesBusy = errors.New("an episode of the series is being written")
+	// ErrInvalidSeason is returned for season requests that can't be run
+	ErrInvalidSeason = errors.New("invalid season request")
+)
+
+// defaultEpisodeDuration is the target length of an episode in seconds
+const defaultEpisodeDuration = 120
+
+// SeriesInput describes a series; empty fields are left alone when updating
+type SeriesInput struct {
+	Title           string `json:"title" binding:"max=200"`
+	Outline         string `json:"outline" binding:"max=10000"`
+	Genre           string `json:"genre" binding:"max=50"`
+	Style           string `json:"style" binding:"max=50"`
+	EpisodeCount    int    `json:"episode_count" binding:"omitempty,min=1,max=100"`
+	EpisodeDuration int    `json:"episode_duration" binding:"omitempty,min=10,max=600"`
+}
+
+// SeriesService keeps multi-episode series and writes their episodes in
+// order, each from the series outline and the recaps of the ones before
+type SeriesService struct {
+	db       *gorm.DB
+	tasks    *TaskService
+	projects *ProjectService
+}
+
+func NewSeriesService(db *gorm.DB, tasks *TaskService, projects *ProjectService) *SeriesService {
+	return &SeriesService{db: db, tasks: tasks, projects: projects}
+}
+
+// seasonInput is what a season task was asked for
+type seasonInput struct {
+	Episodes int `json:"episodes"`
+	// Directions steer the episodes in order, e.g. "马斯克创办Zip2"
+	Directions []string `json:"directions"`
+}
+
+// seasonProgress is kept in a season task's output, so a retried attempt
+// picks up where the last one failed
+type seasonProgress struct {
+	EpisodeIDs []uint `json:"episode_ids"`
+	Completed  int    `json:"completed"`
+	// HeldEpisode is the episode content review stopped the season at
+	HeldEpisode int `json:"held_episode,omitempty"`
+}
+
+// Create starts a series
+func (s *SeriesService) Create(ctx context.Context, userID uint, in SeriesInput) (*models.Series, error) {
+	series := &models.Series{UserID: userID, EpisodeDuration: defaultEpisodeDuration}
+	applySeriesInput(series, in)
+	if series.Title == "" {
+		return nil, errors.New("title is required")
+	}
+	if err := s.db.WithContext(ctx).Create(series).Error; err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// List returns the user's series, newest first
+func (s *SeriesService) List(ctx context.Context, userID uint) ([]models.Series, error) {
+	var series []models.Series
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&series).Error
+	return series, err
+}
+
+// Series returns one of the user's series with its episodes in order
+func (s *SeriesService) Series(ctx context.Context, userID, seriesID uint) (*models.Series, error) {
+	var series models.Series
+	err := s.db.WithContext(ctx).
+		Preload("Episodes", func(db *gorm.DB) *gorm.DB { return db.Order("episode_number ASC") }).
+		Where("id = ? AND user_id = ?", seriesID, userID).
+		First(&series).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSeriesNotFound
+	}
+	return &series, err
+}
+
+// Update edits a series. A new genre, style or episode duration applies to
+// episodes created afterwards.
+func (s *SeriesService) Update(ctx context.Context, userID, seriesID uint, in SeriesInput) (*models.Series, error) {
+	series, err := s.Series(ctx, userID, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	applySeriesInput(series, in)
+	if err := s.db.WithContext(ctx).Omit("Episodes").Save(series).Error; err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// Delete removes a series; its episodes stay as standalone projects
+func (s *SeriesService) Delete(ctx context.Context, userID, seriesID uint) error {
+	series, err := s.Series(ctx, userID, seriesID)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Project{}).Where("series_id = ?", series.ID).
+			Updates(map[string]interface{}{"series_id": nil, "episode_number": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&models.Series{}, series.ID).Error
+	})
+}
+
+// NextEpisode creates episode N+1 and queues its script. direction steers
+// the episode; without one the model follows the outline.
+func (s *SeriesService) NextEpisode(ctx context.Context, seriesID uint, direction string) (*models.Project, *models.AITask, error) {
+	if err := s.checkIdle(ctx, seriesID); err != nil {
+		return nil, nil, err
+	}
+	episode, err := s.newEpisode(ctx, seriesID, direction)
+	if err != nil {
+		return nil, nil, err
+	}
+	task, err := s.tasks.Submit(ctx, TaskTypeScript, &episode.ID, nil, models.JSONMap{"prompt": episode.Prompt})
+	if err != nil && task == nil {
+		return nil, nil, err
+	}
+	return episode, task, nil
+}
+
+// QueueSeason queues a task that writes the next episodes one after
+// another; by default the rest of the planned season
+func (s *SeriesService) QueueSeason(ctx context.Context, seriesID uint, episodes int, directions []string) (*models.AITask, error) {
+	if err := s.checkIdle(ctx, seriesID); err != nil {
+		return nil, err
+	}
+	if episodes == 0 {
+		var series models.Series
+		if err := s.db.WithContext(ctx).First(&series, seriesID).Error; err != nil {
+			return nil, err
+		}
+		var written int64
+		if err := s.db.WithContext(ctx).Model(&models.Project{}).Where("series_id = ?", seriesID).Count(&written).Error; err != nil {
+			return nil, err
+		}
+		episodes = series.EpisodeCount - int(written)
+		if episodes <= 0 {
+			return nil, fmt.Errorf("%w: the planned %d episodes exist, give the number of episodes to add", ErrInvalidSeason, series.EpisodeCount)
+		}
+	}
+	if len(directions) > episodes {
+		return nil, fmt.Errorf("%w: %d directions for %d episodes", ErrInvalidSeason, len(directions), episodes)
+	}
+
+	input, err := toJSONMap(seasonInput{Episodes: episodes, Directions: directions})
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.tasks.SubmitForSeries(ctx, TaskTypeSeason, seriesID, input)
+	if err != nil && task == nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// GenerateSeason runs a season task: each episode is created and its script
+// written before the next, so every episode has the recaps of the ones
+// before it. Scene media is queued as each script streams in. A retried
+// attempt rewrites the episode that failed; an episode held by content
+// review ends the season there.
+func (s *SeriesService) GenerateSeason(ctx context.Context, task *models.AITask) error {
+	var in seasonInput
+	if err := fromJSONMap(task.InputData, &in); err != nil {
+		return err
+	}
+	var progress seasonProgress
+	if err := fromJSONMap(task.OutputData, &progress); err != nil {
+		return err
+	}
+
+	for progress.Completed < in.Episodes {
+		var episode models.Project
+		if progress.Completed < len(progress.EpisodeIDs) {
+			if err := s.db.WithContext(ctx).First(&episode, progress.EpisodeIDs[progress.Completed]).Error; err != nil {
+				return err
+			}
+		} else {
+			direction := ""
+			if progress.Completed < len(in.Directions) {
+				direction = in.Directions[progress.Completed]
+			}
+			created, err := s.newEpisode(ctx, *task.SeriesID, direction)
+			if err != nil {
+				return err
+			}
+			episode = *created
+			progress.EpisodeIDs = append(progress.EpisodeIDs, episode.ID)
+			if err := s.saveProgress(ctx, task, progress); err != nil {
+				return err
+			}
+		}
+
+		if err := s.projects.GenerateScenes(ctx, episode.ID, task); err != nil {
+			return err
+		}
+		if err := s.db.WithContext(ctx).First(&episode, episode.ID).Error; err != nil {
+			return err
+		}
+		if episode.Status == "review" || episode.Status == "blocked" {
+			progress.HeldEpisode = episode.EpisodeNumber
+			return s.saveProgress(ctx, task, progress)
+		}
+		progress.Completed++
+		if err := s.saveProgress(ctx, task, progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarkSeasonFailed records that a season gave up at the episode it was
+// writing; the episodes before it are kept
+func (s *SeriesService) MarkSeasonFailed(ctx context.Context, task *models.AITask, cause error) error {
+	var progress seasonProgress
+	if err := fromJSONMap(task.OutputData, &progress); err != nil {
+		return err
+	}
+	if progress.Completed < len(progress.EpisodeIDs) {
+		return s.projects.MarkProjectFailed(ctx, progress.EpisodeIDs[progress.Completed], cause)
+	}
+	return nil
+}
+
+// checkIdle refuses new episodes while a season runs or an episode's script
+// is queued
+func (s *SeriesService) checkIdle(ctx context.Context, seriesID uint) error {
+	episodes := s.db.Model(&models.Project{}).Select("id").Where("series_id = ?", seriesID)
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.AITask{}).
+		Where("status IN ?", []string{TaskPending, TaskProcessing}).
+		Where("(task_type = ? AND series_id = ?) OR (task_type = ? AND project_id IN (?))", TaskTypeSeason, seriesID, TaskTypeScript, episodes).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSeriesBusy
+	}
+	return nil
+}
+
+// newEpisode creates the series' next episode with the series' genre,
+// style and episode duration
+func (s *SeriesService) newEpisode(ctx context.Context, seriesID uint, direction string) (*models.Project, error) {
+	var episode *models.Project
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the series numbers its episodes one at a time
+		var series models.Series
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&series, seriesID).Error; err != nil {
+			return err
+		}
+		var last int
+		err := tx.Model(&models.Project{}).Where("series_id = ?", seriesID).
+			Select("COALESCE(MAX(episode_number), 0)").Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		episode = &models.Project{
+			UserID:         series.UserID,
+			SeriesID:       &series.ID,
+			EpisodeNumber:  last + 1,
+			Prompt:         episodePrompt(last+1, direction),
+			Genre:          series.Genre,
+			Style:          series.Style,
+			TargetDuration: series.EpisodeDuration,
+			Status:         "draft",
+		}
+		return tx.Create(episode).Error
+	})
+	return episode, err
+}
+
+func (s *SeriesService) saveProgress(ctx context.Context, task *models.AITask, progress seasonProgress) error {
+	output, err := toJSONMap(progress)
+	if err != nil {
+		return err
+	}
+	task.OutputData = output
+	return s.db.WithContext(ctx).Model(&models.AITask{}).Where("id = ?", task.ID).Update("output_data", task.OutputData).Error
+}
+
+// updateRecap rebuilds a series' "previously on" recap from the summaries
+// of its episodes
+func updateRecap(db *gorm.DB, seriesID uint) error {
+	var episodes []models.Project
+	err := db.Select("episode_number", "title", "summary").
+		Where("series_id = ? AND summary <> ''", seriesID).
+		Order("episode_number ASC").
+		Find(&episodes).Error
+	if err != nil {
+		return err
+	}
+	lines := make([]string, 0, len(episodes))
+	for _, e := range episodes {
+		lines = append(lines, fmt.Sprintf("第%d集「%s」：%s", e.EpisodeNumber, e.Title, e.Summary))
+	}
+	return db.Model(&models.Series{}).Where("id = ?", seriesID).Update("summary", strings.Join(lines, "\n")).Error
+}
+
+// episodePrompt is the request an episode's script is written from
+func episodePrompt(number int, direction string) string {
+	if direction = strings.TrimSpace(direction); direction != "" {
+		return direction
+	}
+	return fmt.Sprintf("请按剧集大纲编写第%d集。", number)
+}
+
+func applySeriesInput(series *models.Series, in SeriesInput) {
+	if title := strings.TrimSpace(in.Title); title != "" {
+		series.Title = title
+	}
+	if outline := strings.TrimSpace(in.Outline); outline != "" {
+		series.Outline = outline
+	}
+	if in.Genre != "" {
+		series.Genre = strings.TrimSpace(in.Genre)
+	}
+	if in.Style != "" {
+		series.Style = strings.TrimSpace(in.Style)
+	}
+	if in.EpisodeCount != 0 {
+		series.EpisodeCount = in.EpisodeCount
+	}
+	if in.EpisodeDuration != 0 {
+		series.EpisodeDuration = in.EpisodeDuration
+	}
+}
+
+// toJSONMap and fromJSONMap move typed task input and output in and out of
+// their JSON columns
+func toJSONMap(v interface{}) (models.JSONMap, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m models.JSONMap
+	return m, json.Unmarshal(data, &m)
+}
+
+func fromJSONMap(m models.JSONMap, v interface{}) error {
+	if m == nil {
+		return nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/events"
+	"github.com/richard9219/3kstory/internal/health"
+	"github.com/richard9219/3kstory/internal/queue"
+	"github.com/richard9219/3kstory/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Services bundles the long-lived service instances shared by the HTTP API and
+// the job workers, so both sides see the same state.
+type Services struct {
+	AI         *AIService
+	Project    *ProjectService
+	Video      *VideoService
+	Task       *TaskService
+	Events     *events.Broker
+	Health     *health.Monitor
+	Prompt     *PromptService
+	Assets     *AssetService
+	Render     *RenderService
+	Speech     *SpeechService
+	Subtitles  *SubtitleService
+	Music      *MusicService
+	Review     *ReviewService
+	Moderation *ModerationService
+	Characters *CharacterService
+	Scenes     *SceneService
+	Versions   *VersionService
+	Series     *SeriesService
+}
+
+func New(db *gorm.DB, rdb *redis.Client, cfg *config.Config) *Services {
+	q := queue.New(rdb, queue.Options{
+		Name:              cfg.Worker.QueueName,
+		VisibilityTimeout: time.Duration(cfg.Worker.VisibilityTimeout) * time.Second,
+		MaxAttempts:       cfg.Worker.MaxAttempts,
+		BaseBackoff:       time.Duration(cfg.Worker.RetryBackoff) * time.Second,
+	})
+
+	monitor := health.NewMonitor(health.BreakerConfig{
+		FailureThreshold: cfg.Health.FailureThreshold,
+		ErrorRate:        float64(cfg.Health.ErrorRatePercent) / 100,
+		Window:           cfg.Health.Window,
+		MinSamples:       cfg.Health.MinSamples,
+		OpenTimeout:      time.Duration(cfg.Health.OpenTimeout) * time.Second,
+	}, time.Duration(cfg.Health.ProbeInterval)*time.Second)
+
+	broker := events.NewBroker(rdb)
+	taskService := NewTaskService(db, q)
+	store, err := storage.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Invalid storage configuration: %v", err)
+	}
+	assetService := NewAssetService(db, store, time.Duration(cfg.Storage.PresignExpiry)*time.Second)
+	aiService := NewAIService(cfg, monitor)
+	promptService := NewPromptService(db)
+	if err := promptService.SeedDefaults(context.Background()); err != nil {
+		log.Printf("Failed to seed prompt templates: %v", err)
+	}
+	reviewService := NewReviewService(cfg, db, aiService, promptService, assetService, monitor, broker)
+	speechService := NewSpeechService(cfg, db, aiService, taskService, assetService, broker)
+	characterService := NewCharacterService(db, assetService, speechService)
+	versionService := NewVersionService(db)
+	projectService := NewProjectService(db, aiService, taskService, broker, promptService, assetService, reviewService, characterService, versionService)
+	videoService := NewVideoService(cfg, db, projectService, broker, monitor, assetService, reviewService)
+	subtitleService := NewSubtitleService(cfg, db)
+	musicService := NewMusicService(db, assetService)
+
+	return &Services{
+		AI:         aiService,
+		Project:    projectService,
+		Video:      videoService,
+		Task:       taskService,
+		Events:     broker,
+		Health:     monitor,
+		Prompt:     promptService,
+		Assets:     assetService,
+		Render:     NewRenderService(cfg, db, taskService, assetService, subtitleService, musicService, broker),
+		Speech:     speechService,
+		Characters: characterService,
+		Scenes:     NewSceneService(db, aiService, taskService, promptService, projectService, videoService, characterService, speechService, versionService, broker),
+		Versions:   versionService,
+		Series:     NewSeriesService(db, taskService, projectService),
+		Subtitles:  subtitleService,
+		Music:      musicService,
+		Review:     reviewService,
+		Moderation: NewModerationService(db, projectService, taskService, promptService, broker),
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/events"
+	"github.com/richard9219/3kstory/internal/media"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/tts"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Pauses on a scene's dialogue track, in milliseconds
+const (
+	dialogueLeadMS = 300
+	dialogueGapMS  = 250
+	dialogueTailMS = 400
+)
+
+// VoiceAssignment sets the voice of one character
+type VoiceAssignment struct {
+	Character string  `json:"character" binding:"required"`
+	VoiceID   string  `json:"voice_id" binding:"required"`
+	Speed     float64 `json:"speed"`
+}
+
+// SpeechService turns scene dialogue into audio: one clip per line in the
+// speaker's voice, laid out on a scene track whose timings are kept as
+// models.DialogueLine rows for subtitles
+type SpeechService struct {
+	cfg    *config.Config
+	db     *gorm.DB
+	ai     *AIService
+	tasks  *TaskService
+	assets *AssetService
+	events *events.Broker
+}
+
+func NewSpeechService(cfg *config.Config, db *gorm.DB, ai *AIService, tasks *TaskService, assets *AssetService, broker *events.Broker) *SpeechService {
+	return &SpeechService{cfg: cfg, db: db, ai: ai, tasks: tasks, assets: assets, events: broker}
+}
+
+// VoicePool lists the voices characters are given automatically
+func (s *SpeechService) VoicePool() []string {
+	return tts.VoicePool(s.ai.SpeechProvider(), s.cfg.AI.TTSVoices)
+}
+
+// Voices returns a project's character voices
+func (s *SpeechService) Voices(ctx context.Context, projectID uint) ([]models.VoiceMapping, error) {
+	var voices []models.VoiceMapping
+	err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("character_name ASC").Find(&voices).Error
+	return voices, err
+}
+
+// SetVoices assigns voices to characters, replacing earlier assignments
+func (s *SpeechService) SetVoices(ctx context.Context, projectID uint, assignments []VoiceAssignment) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, a := range assignments {
+			character := strings.TrimSpace(a.Character)
+			if character == "" || strings.TrimSpace(a.VoiceID) == "" {
+				return errors.New("character and voice_id are required")
+			}
+			if a.Speed < 0 || a.Speed > 3 {
+				return fmt.Errorf("speed for %s must be between 0 and 3", character)
+			}
+			mapping := models.VoiceMapping{
+				ProjectID: projectID,
+				Character: character,
+				VoiceID:   strings.TrimSpace(a.VoiceID),
+				Speed:     a.Speed,
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "project_id"}, {Name: "character_name"}},
+				DoUpdates: clause.AssignmentColumns([]string{"voice_id", "speed", "auto", "updated_at"}),
+			}).Create(&mapping).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Submit queues speech for the given scenes, or for every scene with
+// dialogue when sceneIDs is empty
+func (s *SpeechService) Submit(ctx context.Context, projectID uint, sceneIDs []uint) ([]*models.AITask, error) {
+	query := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("scene_number ASC")
+	if len(sceneIDs) > 0 {
+		query = query.Where("id IN ?", sceneIDs)
+	} else {
+		query = query.Where("dialogue <> ''")
+	}
+	var scenes []models.Scene
+	if err := query.Find(&scenes).Error; err != nil {
+		return nil, err
+	}
+	if len(sceneIDs) > 0 && len(scenes) != len(sceneIDs) {
+		return nil, errors.New("scene does not belong to this project")
+	}
+
+	tasks := make([]*models.AITask, 0, len(scenes))
+	for _, scene := range scenes {
+		sceneID := scene.ID
+		task, err := s.tasks.Submit(ctx, TaskTypeSpeech, &projectID, &sceneID, models.JSONMap{"dialogue": scene.Dialogue})
+		if task == nil {
+			return tasks, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// SceneDialogue returns a scene's synthesized lines in order
+func (s *SpeechService) SceneDialogue(ctx context.Context, sceneID uint) ([]models.DialogueLine, error) {
+	var lines []models.DialogueLine
+	err := s.db.WithContext(ctx).Where("scene_id = ?", sceneID).Order("line_number ASC").Find(&lines).Error
+	return lines, err
+}
+
+// SynthesizeScene speaks every line of a scene's dialogue and stores the
+// scene track. Lines follow each other with short pauses; their offsets on
+// the track are recorded for subtitles. A scene without dialogue loses its
+// track.
+func (s *SpeechService) SynthesizeScene(ctx context.Context, sceneID uint) error {
+	if err := media.Available(); err != nil {
+		return err
+	}
+	var scene models.Scene
+	if err := s.db.WithContext(ctx).First(&scene, sceneID).Error; err != nil {
+		return err
+	}
+
+	known := make([]string, 0, len(scene.Characters))
+	for _, c := range scene.Characters {
+		known = append(known, c.Name)
+	}
+	split := tts.SplitDialogue(scene.Dialogue, known)
+	if len(split) == 0 {
+		return s.replaceTrack(ctx, &scene, nil, nil)
+	}
+
+	speakers := make([]string, 0, len(split))
+	for _, line := range split {
+		speakers = append(speakers, line.Speaker)
+	}
+	voices, err := s.assignVoices(ctx, scene.ProjectID, speakers)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "3kstory-speech-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	owner := AssetOwner{ProjectID: scene.ProjectID, SceneID: scene.ID}
+	lines := make([]models.DialogueLine, 0, len(split))
+	parts := make([]media.TrackPart, 0, len(split))
+	cursor := dialogueLeadMS
+	for i, line := range split {
+		voice := voices[line.Speaker]
+		speech, err := s.ai.SynthesizeSpeech(ctx, tts.Request{Text: line.Text, Voice: voice.VoiceID, Speed: voice.Speed})
+		if err != nil {
+			return fmt.Errorf("line %d (%s): %w", i+1, line.Speaker, err)
+		}
+		asset, err := s.assets.SaveBytes(ctx, models.AssetAudio, owner, speech.Data, speech.MIMEType)
+		if err != nil {
+			return fmt.Errorf("failed to store line %d: %w", i+1, err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("line-%03d%s", i, filepath.Ext(asset.Key)))
+		if err := os.WriteFile(path, speech.Data, 0o644); err != nil {
+			return err
+		}
+		info, err := media.Probe(ctx, path)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		durationMS := int(info.Seconds*1000 + 0.5)
+		assetID := asset.ID
+		lines = append(lines, models.DialogueLine{
+			ProjectID:    scene.ProjectID,
+			SceneID:      scene.ID,
+			LineNumber:   i + 1,
+			Speaker:      line.Speaker,
+			Text:         line.Text,
+			VoiceID:      voice.VoiceID,
+			AudioURL:     asset.URL,
+			AudioAssetID: &assetID,
+			StartMS:      cursor,
+			EndMS:        cursor + durationMS,
+		})
+		parts = append(parts, media.TrackPart{Path: path, Start: float64(cursor) / 1000})
+		cursor += durationMS + dialogueGapMS
+	}
+
+	totalMS := lines[len(lines)-1].EndMS + dialogueTailMS
+	trackPath := filepath.Join(dir, "track.m4a")
+	if err := media.DialogueTrack(ctx, parts, float64(totalMS)/1000, trackPath); err != nil {
+		return err
+	}
+	f, err := os.Open(trackPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	track, err := s.assets.Save(ctx, models.AssetAudio, owner, f, "audio/mp4")
+	if err != nil {
+		return fmt.Errorf("failed to store dialogue track: %w", err)
+	}
+	scene.AudioDurationMS = totalMS
+	if err := s.replaceTrack(ctx, &scene, track, lines); err != nil {
+		return err
+	}
+
+	s.events.Emit(ctx, scene.ProjectID, events.AudioReady, map[string]interface{}{
+		"scene_id":     scene.ID,
+		"scene_number": scene.SceneNumber,
+		"audio_url":    scene.AudioURL,
+		"duration_ms":  totalMS,
+		"lines":        len(lines),
+	})
+	return nil
+}
+
+// replaceTrack swaps a scene's dialogue lines and track, then releases the
+// audio the old ones used
+func (s *SpeechService) replaceTrack(ctx context.Context, scene *models.Scene, track *models.Asset, lines []models.DialogueLine) error {
+	keep := map[uint]bool{}
+	if track != nil {
+		keep[track.ID] = true
+		scene.AudioURL = track.URL
+		scene.AudioAssetID = &track.ID
+	} else {
+		scene.AudioURL = ""
+		scene.AudioAssetID = nil
+		scene.AudioDurationMS = 0
+	}
+	for _, line := range lines {
+		if line.AudioAssetID != nil {
+			keep[*line.AudioAssetID] = true
+		}
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("scene_id = ?", scene.ID).Delete(&models.DialogueLine{}).Error; err != nil {
+			return err
+		}
+		if len(lines) > 0 {
+			if err := tx.Create(&lines).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(scene).Select("audio_url", "audio_asset_id", "audio_duration_ms").Updates(scene).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var stale []models.Asset
+	if err := s.db.WithContext(ctx).Where("scene_id = ? AND kind = ?", scene.ID, models.AssetAudio).Find(&stale).Error; err != nil {
+		return err
+	}
+	for i := range stale {
+		if keep[stale[i].ID] {
+			continue
+		}
+		if err := s.assets.Delete(ctx, &stale[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignVoices resolves every speaker's voice. Speakers without one are
+// given a voice from the pool and keep it: the narrator gets the first, and
+// characters the least used of the rest.
+func (s *SpeechService) assignVoices(ctx context.Context, projectID uint, speakers []string) (map[string]models.VoiceMapping, error) {
+	mappings, err := s.Voices(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	voices := make(map[string]models.VoiceMapping, len(mappings))
+	used := map[string]int{}
+	for _, m := range mappings {
+		voices[m.Character] = m
+		used[m.VoiceID]++
+	}
+
+	pool := s.VoicePool()
+	for _, speaker := range speakers {
+		if _, ok := voices[speaker]; ok {
+			continue
+		}
+		if len(pool) == 0 {
+			// The speech service picks its own default voice
+			voices[speaker] = models.VoiceMapping{Character: speaker}
+			continue
+		}
+		voice := pool[0]
+		if speaker != tts.Narrator && len(pool) > 1 {
+			voice = leastUsed(pool[1:], used)
+		}
+		mapping := models.VoiceMapping{ProjectID: projectID, Character: speaker, VoiceID: voice, Auto: true}
+		// Another scene of the project may assign the same speaker concurrently
+		if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&mapping).Error; err != nil {
+			return nil, err
+		}
+		if err := s.db.WithContext(ctx).Where("project_id = ? AND character_name = ?", projectID, speaker).First(&mapping).Error; err != nil {
+			return nil, err
+		}
+		voices[speaker] = mapping
+		used[mapping.VoiceID]++
+	}
+	return voices, nil
+}
+
+func leastUsed(pool []string, used map[string]int) string {
+	best := pool[0]
+	for _, v := range pool[1:] {
+		if used[v] < used[best] {
+			best = v
+		}
+	}
+	return best
+}
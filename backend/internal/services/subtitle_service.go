@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/media"
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/subtitle"
+	"gorm.io/gorm"
+)
+
+// SubtitleService times scene dialogue across an episode. Scenes with a
+// dialogue track use the timings of the speech; the rest spread their
+// dialogue over the scene.
+type SubtitleService struct {
+	cfg *config.Config
+	db  *gorm.DB
+}
+
+func NewSubtitleService(cfg *config.Config, db *gorm.DB) *SubtitleService {
+	return &SubtitleService{cfg: cfg, db: db}
+}
+
+// SceneCues returns a scene's subtitles relative to the start of the scene
+func (s *SubtitleService) SceneCues(ctx context.Context, scene models.Scene, seconds float64) ([]subtitle.Cue, error) {
+	if scene.AudioURL != "" {
+		var lines []models.DialogueLine
+		if err := s.db.WithContext(ctx).Where("scene_id = ?", scene.ID).Order("line_number ASC").Find(&lines).Error; err != nil {
+			return nil, err
+		}
+		if len(lines) > 0 {
+			cues := make([]subtitle.Cue, 0, len(lines))
+			for _, line := range lines {
+				cues = append(cues, subtitle.Cue{
+					Start:   float64(line.StartMS) / 1000,
+					End:     float64(line.EndMS) / 1000,
+					Speaker: line.Speaker,
+					Text:    line.Text,
+				})
+			}
+			return cues, nil
+		}
+	}
+
+	known := make([]string, 0, len(scene.Characters))
+	for _, c := range scene.Characters {
+		known = append(known, c.Name)
+	}
+	return subtitle.FromDialogue(scene.Dialogue, known, seconds), nil
+}
+
+// EpisodeCues places each scene's subtitles at its offset in the episode.
+// A scene's subtitles end where the next scene starts.
+func (s *SubtitleService) EpisodeCues(ctx context.Context, scenes []models.Scene, offsets, seconds []float64) ([]subtitle.Cue, error) {
+	if len(offsets) != len(scenes) || len(seconds) != len(scenes) {
+		return nil, errors.New("every scene needs an offset and a length")
+	}
+	var cues []subtitle.Cue
+	for i, scene := range scenes {
+		sceneCues, err := s.SceneCues(ctx, scene, seconds[i])
+		if err != nil {
+			return nil, err
+		}
+		limit := offsets[i] + seconds[i]
+		if i+1 < len(scenes) {
+			limit = min(limit, offsets[i+1])
+		}
+		cues = append(cues, subtitle.Shift(sceneCues, offsets[i], limit)...)
+	}
+	return cues, nil
+}
+
+// ProjectCues times a project's subtitles the way its last render placed
+// the scenes. Scenes that were not part of it are estimated like the next
+// render would lay them out.
+func (s *SubtitleService) ProjectCues(ctx context.Context, projectID uint) ([]subtitle.Cue, error) {
+	var scenes []models.Scene
+	if err := s.db.WithContext(ctx).Where("project_id = ?", projectID).Order("scene_number ASC").Find(&scenes).Error; err != nil {
+		return nil, err
+	}
+	if len(scenes) == 0 {
+		return nil, nil
+	}
+
+	rendered, overlap := s.lastTimeline(ctx, projectID)
+	seconds := make([]float64, len(scenes))
+	for i, scene := range scenes {
+		seconds[i] = sceneLength(scene)
+		// Only the render knows how long a video clip turned out
+		if length, ok := rendered[scene.ID]; ok && scene.MediaType == "video" {
+			seconds[i] = length
+		}
+	}
+	offsets, _ := media.Timeline(seconds, media.TransitionCrossfade, overlap)
+	return s.EpisodeCues(ctx, scenes, offsets, seconds)
+}
+
+// lastTimeline reads the scene lengths and transition overlap recorded by
+// the project's last finished render, or the configured overlap when there
+// is none
+func (s *SubtitleService) lastTimeline(ctx context.Context, projectID uint) (map[uint]float64, float64) {
+	overlap := 0.0
+	if t, err := media.ParseTransition(s.cfg.Render.Transition); err == nil && t != media.TransitionCut {
+		overlap = float64(s.cfg.Render.TransitionMS) / 1000
+	}
+
+	var task models.AITask
+	err := s.db.WithContext(ctx).
+		Where("project_id = ? AND task_type = ? AND status = ?", projectID, TaskTypeRender, TaskCompleted).
+		Order("id DESC").First(&task).Error
+	if err != nil {
+		return nil, overlap
+	}
+	if v, ok := task.OutputData["overlap"].(float64); ok {
+		overlap = v
+	}
+	lengths := map[uint]float64{}
+	entries, _ := task.OutputData["timeline"].([]interface{})
+	for _, e := range entries {
+		entry, _ := e.(map[string]interface{})
+		id, _ := entry["scene_id"].(float64)
+		length, _ := entry["seconds"].(float64)
+		if id > 0 && length > 0 {
+			lengths[uint(id)] = length
+		}
+	}
+	return lengths, overlap
+}
+
+// sceneLength estimates how long a scene runs in the episode: stills are
+// held for the scene's duration or until the dialogue ends
+func sceneLength(scene models.Scene) float64 {
+	return max(sceneSeconds(scene), float64(scene.AudioDurationMS)/1000)
+}
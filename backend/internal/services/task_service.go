@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/queue"
+	"gorm.io/gorm"
+)
+
+// AI task types handled by the worker pool
+const (
+	TaskTypeScript  = "script"
+	TaskTypeRewrite = "rewrite"
+	TaskTypeImage   = "image"
+	TaskTypeVideo   = "video"
+	TaskTypeRender  = "render"
+	TaskTypeSpeech  = "speech"
+	TaskTypeSeason  = "season"
+)
+
+// AITask statuses
+const (
+	TaskPending    = "pending"
+	TaskProcessing = "processing"
+	TaskCompleted  = "completed"
+	TaskFailed     = "failed"
+)
+
+// TaskService records AI work as models.AITask rows and hands it to the queue
+type TaskService struct {
+	db    *gorm.DB
+	queue *queue.Queue
+}
+
+func NewTaskService(db *gorm.DB, q *queue.Queue) *TaskService {
+	return &TaskService{db: db, queue: q}
+}
+
+// Queue exposes the underlying job queue to the worker pool
+func (s *TaskService) Queue() *queue.Queue {
+	return s.queue
+}
+
+// Submit creates a pending AITask and enqueues it for the workers
+func (s *TaskService) Submit(ctx context.Context, taskType string, projectID, sceneID *uint, input models.JSONMap) (*models.AITask, error) {
+	return s.SubmitWithPrompt(ctx, taskType, projectID, sceneID, input, nil)
+}
+
+// SubmitWithPrompt is Submit for tasks whose prompt was rendered from a
+// template; the template version is recorded on the task
+func (s *TaskService) SubmitWithPrompt(ctx context.Context, taskType string, projectID, sceneID *uint, input models.JSONMap, tpl *models.PromptTemplate) (*models.AITask, error) {
+	task := &models.AITask{
+		ProjectID: projectID,
+		SceneID:   sceneID,
+		TaskType:  taskType,
+		InputData: input,
+		Status:    TaskPending,
+	}
+	recordPrompt(task, tpl)
+	return s.submit(ctx, task)
+}
+
+// SubmitForSeries is Submit for tasks that work on a whole series
+func (s *TaskService) SubmitForSeries(ctx context.Context, taskType string, seriesID uint, input models.JSONMap) (*models.AITask, error) {
+	task := &models.AITask{
+		SeriesID:  &seriesID,
+		TaskType:  taskType,
+		InputData: input,
+		Status:    TaskPending,
+	}
+	return s.submit(ctx, task)
+}
+
+func (s *TaskService) submit(ctx context.Context, task *models.AITask) (*models.AITask, error) {
+	if err := s.db.WithContext(ctx).Create(task).Error; err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
+	// The row is the source of truth: if Redis is unavailable here, Recover
+	// picks the task up again when the worker pool starts.
+	if _, err := s.queue.Enqueue(ctx, task.TaskType, task.ID); err != nil {
+		return task, fmt.Errorf("failed to enqueue task %d: %w", task.ID, err)
+	}
+	return task, nil
+}
+
+// GetTask retrieves an AI task by ID
+func (s *TaskService) GetTask(ctx context.Context, taskID uint) (*models.AITask, error) {
+	var task models.AITask
+	if err := s.db.WithContext(ctx).First(&task, taskID).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// MarkStarted records the start of an attempt
+func (s *TaskService) MarkStarted(ctx context.Context, task *models.AITask, attempt int) error {
+	now := time.Now()
+	task.Status = TaskProcessing
+	task.StartedAt = &now
+	task.CompletedAt = nil
+	task.RetryCount = attempt - 1
+	return s.db.WithContext(ctx).Save(task).Error
+}
+
+// MarkCompleted records a successful attempt
+func (s *TaskService) MarkCompleted(ctx context.Context, task *models.AITask) error {
+	s.finish(task)
+	task.Status = TaskCompleted
+	task.ErrorMessage = ""
+	return s.db.WithContext(ctx).Save(task).Error
+}
+
+// MarkAttemptFailed records a failed attempt; the task goes back to pending
+// while retries remain and to failed once the job is dead-lettered.
+func (s *TaskService) MarkAttemptFailed(ctx context.Context, task *models.AITask, cause error, final bool) error {
+	s.finish(task)
+	task.ErrorMessage = cause.Error()
+	task.RetryCount++
+	if final {
+		task.Status = TaskFailed
+	} else {
+		task.Status = TaskPending
+	}
+	return s.db.WithContext(ctx).Save(task).Error
+}
+
+func (s *TaskService) finish(task *models.AITask) {
+	now := time.Now()
+	task.CompletedAt = &now
+	if task.StartedAt != nil {
+		task.DurationMs = int(now.Sub(*task.StartedAt).Milliseconds())
+	}
+}
+
+// Recover re-enqueues unfinished tasks that have no job in Redis, e.g. because
+// Redis lost data or the enqueue after Submit failed. Jobs that are merely
+// leased by a crashed worker come back through the visibility timeout instead.
+func (s *TaskService) Recover(ctx context.Context) (int, error) {
+	var tasks []models.AITask
+	err := s.db.WithContext(ctx).
+		Where("status IN ?", []string{TaskPending, TaskProcessing}).
+		Order("id ASC").
+		Find(&tasks).Error
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, task := range tasks {
+		queued, err := s.queue.Has(ctx, task.ID)
+		if err != nil {
+			return recovered, err
+		}
+		if queued {
+			continue
+		}
+		if _, err := s.queue.Enqueue(ctx, task.TaskType, task.ID); err != nil {
+			return recovered, err
+		}
+		log.Printf("re-enqueued %s task %d", task.TaskType, task.ID)
+		recovered++
+	}
+	return recovered, nil
+}
+
+// ProjectOwnedBy reports whether the project belongs to the user
+func (s *TaskService) ProjectOwnedBy(ctx context.Context, projectID, userID uint) bool {
+	var count int64
+	s.db.WithContext(ctx).Model(&models.Project{}).Where("id = ? AND user_id = ?", projectID, userID).Count(&count)
+	return count > 0
+}
+
+// SeriesOwnedBy reports whether the series belongs to the user
+func (s *TaskService) SeriesOwnedBy(ctx context.Context, seriesID, userID uint) bool {
+	var count int64
+	s.db.WithContext(ctx).Model(&models.Series{}).Where("id = ? AND user_id = ?", seriesID, userID).Count(&count)
+	return count > 0
+}
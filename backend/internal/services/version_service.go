@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionNotFound is returned for versions the project doesn't have
+var ErrVersionNotFound = errors.New("project version not found")
+
+// Kinds of SceneChange
+const (
+	SceneAdded   = "added"
+	SceneRemoved = "removed"
+	SceneChanged = "changed"
+	SceneMoved   = "moved"
+)
+
+// VersionService keeps the history of a project's script as immutable
+// snapshots and compares them
+type VersionService struct {
+	db *gorm.DB
+}
+
+func NewVersionService(db *gorm.DB) *VersionService {
+	return &VersionService{db: db}
+}
+
+// FieldChange is one field that differs between two versions
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// SceneChange is how one scene differs between two versions. Scenes are
+// matched by ID, so a scene that only moved is reported as moved.
+type SceneChange struct {
+	SceneID    uint          `json:"scene_id"`
+	Change     string        `json:"change"`
+	FromNumber int           `json:"from_number,omitempty"`
+	ToNumber   int           `json:"to_number,omitempty"`
+	Title      string        `json:"title"`
+	Fields     []FieldChange `json:"fields,omitempty"`
+}
+
+// VersionDiff lists what changed from one version to another; unchanged
+// scenes are left out
+type VersionDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Project []FieldChange `json:"project"`
+	Scenes  []SceneChange `json:"scenes"`
+}
+
+// Snapshot records the project's script as a new version, unless it is the
+// same as the latest version's, which is returned instead. A note makes a
+// checkpoint that is recorded either way. A project without scenes has no
+// script yet and gets no version.
+func (s *VersionService) Snapshot(ctx context.Context, projectID uint, author, reason, note string) (*models.ProjectVersion, error) {
+	var version *models.ProjectVersion
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the project numbers its versions one at a time
+		var project models.Project
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+			return err
+		}
+		var scenes []models.Scene
+		if err := tx.Where("project_id = ?", projectID).Order("scene_number ASC").Find(&scenes).Error; err != nil {
+			return err
+		}
+		if len(scenes) == 0 {
+			return nil
+		}
+
+		var latest models.ProjectVersion
+		err := tx.Omit("scenes").Where("project_id = ?", projectID).Order("version DESC").First(&latest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		next := newVersion(&project, scenes)
+		if note == "" && latest.ID != 0 && latest.Checksum == next.Checksum {
+			version = &latest
+			return nil
+		}
+
+		next.Version = latest.Version + 1
+		next.Author = author
+		next.Reason = reason
+		next.Note = note
+		if author == models.AuthorUser {
+			next.AuthorID = &project.UserID
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		version = next
+		return nil
+	})
+	return version, err
+}
+
+// Versions lists a project's versions, newest first, without their scenes
+func (s *VersionService) Versions(ctx context.Context, projectID uint) ([]models.ProjectVersion, error) {
+	var versions []models.ProjectVersion
+	err := s.db.WithContext(ctx).Omit("scenes").Where("project_id = ?", projectID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+// Version returns one version with its scenes; 0 is the latest
+func (s *VersionService) Version(ctx context.Context, projectID uint, number int) (*models.ProjectVersion, error) {
+	query := s.db.WithContext(ctx).Where("project_id = ?", projectID)
+	if number != 0 {
+		query = query.Where("version = ?", number)
+	}
+	var version models.ProjectVersion
+	err := query.Order("version DESC").First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVersionNotFound
+	}
+	return &version, err
+}
+
+// Diff compares two versions scene by scene. to defaults to the latest
+// version and from to the one before to.
+func (s *VersionService) Diff(ctx context.Context, projectID uint, from, to int) (*VersionDiff, error) {
+	newer, err := s.Version(ctx, projectID, to)
+	if err != nil {
+		return nil, err
+	}
+	if from == 0 {
+		from = newer.Version - 1
+	}
+	older, err := s.Version(ctx, projectID, from)
+	if err != nil {
+		return nil, err
+	}
+	if from == 0 || older.Version != from {
+		return nil, ErrVersionNotFound
+	}
+
+	diff := &VersionDiff{
+		From: older.Version,
+		To:   newer.Version,
+		Project: fieldChanges(
+			[3]string{"title", older.Title, newer.Title},
+			[3]string{"description", older.Description, newer.Description},
+			[3]string{"prompt", older.Prompt, newer.Prompt},
+			[3]string{"genre", older.Genre, newer.Genre},
+			[3]string{"style", older.Style, newer.Style},
+			[3]string{"target_duration", strconv.Itoa(older.TargetDuration), strconv.Itoa(newer.TargetDuration)},
+		),
+		Scenes: []SceneChange{},
+	}
+
+	before := make(map[uint]models.SceneSnapshot, len(older.Scenes))
+	for _, scene := range older.Scenes {
+		before[scene.ID] = scene
+	}
+	for _, scene := range newer.Scenes {
+		old, ok := before[scene.ID]
+		if !ok {
+			diff.Scenes = append(diff.Scenes, SceneChange{SceneID: scene.ID, Change: SceneAdded, ToNumber: scene.SceneNumber, Title: scene.Title})
+			continue
+		}
+		delete(before, scene.ID)
+		change := SceneChange{
+			SceneID:    scene.ID,
+			FromNumber: old.SceneNumber,
+			ToNumber:   scene.SceneNumber,
+			Title:      scene.Title,
+			Fields: fieldChanges(
+				[3]string{"title", old.Title, scene.Title},
+				[3]string{"description", old.Description, scene.Description},
+				[3]string{"location", old.Location, scene.Location},
+				[3]string{"characters", castLine(old.Characters), castLine(scene.Characters)},
+				[3]string{"dialogue", old.Dialogue, scene.Dialogue},
+				[3]string{"shot_type", old.ShotType, scene.ShotType},
+				[3]string{"duration", strconv.Itoa(old.Duration), strconv.Itoa(scene.Duration)},
+			),
+		}
+		switch {
+		case len(change.Fields) > 0:
+			change.Change = SceneChanged
+		case old.SceneNumber != scene.SceneNumber:
+			change.Change = SceneMoved
+		default:
+			continue
+		}
+		diff.Scenes = append(diff.Scenes, change)
+	}
+	for _, scene := range older.Scenes {
+		if _, ok := before[scene.ID]; ok {
+			diff.Scenes = append(diff.Scenes, SceneChange{SceneID: scene.ID, Change: SceneRemoved, FromNumber: scene.SceneNumber, Title: scene.Title})
+		}
+	}
+	return diff, nil
+}
+
+// newVersion snapshots the project and its scenes, with the checksum of the
+// script
+func newVersion(project *models.Project, scenes []models.Scene) *models.ProjectVersion {
+	version := &models.ProjectVersion{
+		ProjectID:      project.ID,
+		Title:          project.Title,
+		Description:    project.Description,
+		Prompt:         project.Prompt,
+		Genre:          project.Genre,
+		Style:          project.Style,
+		TargetDuration: project.TargetDuration,
+		SceneCount:     len(scenes),
+		Scenes:         make(models.SceneSnapshots, 0, len(scenes)),
+	}
+	for _, scene := range scenes {
+		version.Scenes = append(version.Scenes, models.SceneSnapshot{
+			ID:             scene.ID,
+			SceneNumber:    scene.SceneNumber,
+			Title:          scene.Title,
+			Description:    scene.Description,
+			Location:       scene.Location,
+			Characters:     scene.Characters,
+			Dialogue:       scene.Dialogue,
+			ShotType:       scene.ShotType,
+			Duration:       scene.Duration,
+			MusicCue:       scene.MusicCue,
+			MusicTrackID:   scene.MusicTrackID,
+			MediaType:      scene.MediaType,
+			MediaURL:       scene.MediaURL,
+			MediaAssetID:   scene.MediaAssetID,
+			PromptForImage: scene.PromptForImage,
+			PromptForVideo: scene.PromptForVideo,
+			Status:         scene.Status,
+		})
+	}
+	version.Checksum = scriptChecksum(version)
+	return version
+}
+
+// scriptChecksum hashes the script-level fields of a version; media and
+// statuses are left out
+func scriptChecksum(version *models.ProjectVersion) string {
+	type sceneScript struct {
+		ID          uint
+		SceneNumber int
+		Title       string
+		Description string
+		Location    string
+		Characters  models.CharacterArray
+		Dialogue    string
+		ShotType    string
+		Duration    int
+	}
+	script := struct {
+		Title, Description, Prompt, Genre, Style string
+		TargetDuration                           int
+		Scenes                                   []sceneScript
+	}{
+		Title:          version.Title,
+		Description:    version.Description,
+		Prompt:         version.Prompt,
+		Genre:          version.Genre,
+		Style:          version.Style,
+		TargetDuration: version.TargetDuration,
+	}
+	for _, scene := range version.Scenes {
+		script.Scenes = append(script.Scenes, sceneScript{
+			ID:          scene.ID,
+			SceneNumber: scene.SceneNumber,
+			Title:       scene.Title,
+			Description: scene.Description,
+			Location:    scene.Location,
+			Characters:  scene.Characters,
+			Dialogue:    scene.Dialogue,
+			ShotType:    scene.ShotType,
+			Duration:    scene.Duration,
+		})
+	}
+	data, _ := json.Marshal(script)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fieldChanges keeps the {field, from, to} triples whose values differ
+func fieldChanges(fields ...[3]string) []FieldChange {
+	changes := []FieldChange{}
+	for _, f := range fields {
+		if f[1] != f[2] {
+			changes = append(changes, FieldChange{Field: f[0], From: f[1], To: f[2]})
+		}
+	}
+	return changes
+}
+
+// castLine writes a scene's characters as "林晓(愤怒)、阿强"
+func castLine(characters models.CharacterArray) string {
+	parts := make([]string, 0, len(characters))
+	for _, c := range characters {
+		if c.Emotion != "" {
+			parts = append(parts, c.Name+"("+c.Emotion+")")
+		} else {
+			parts = append(parts, c.Name)
+		}
+	}
+	return strings.Join(parts, "、")
+}
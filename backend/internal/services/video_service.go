@@ -4,13 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/richard9219/3kstory/internal/config"
+	"github.com/richard9219/3kstory/internal/events"
+	"github.com/richard9219/3kstory/internal/health"
+	"github.com/richard9219/3kstory/internal/models"
+	"gorm.io/gorm"
 )
 
 // VideoProvider defines which video generation service to use
@@ -22,13 +30,38 @@ const (
 	ProviderLocal  VideoProvider = "local"
 )
 
+// ErrProviderUnavailable is returned when a provider's circuit breaker is open
+var ErrProviderUnavailable = errors.New("provider unavailable, circuit open")
+
+var (
+	// ErrVideoTaskNotFound is returned for video tasks outside the project
+	ErrVideoTaskNotFound = errors.New("video task not found")
+	// ErrVideoTaskFinished is returned when cancelling a task that already ended
+	ErrVideoTaskFinished = errors.New("video task already finished")
+)
+
 // VideoService handles video generation via third-party APIs
 type VideoService struct {
-	cfg *config.Config
+	cfg      *config.Config
+	db       *gorm.DB
+	projects *ProjectService
+	events   *events.Broker
+	health   *health.Monitor
+	assets   *AssetService
+	reviews  *ReviewService
 }
 
-func NewVideoService(cfg *config.Config) *VideoService {
-	return &VideoService{cfg: cfg}
+func NewVideoService(cfg *config.Config, db *gorm.DB, projects *ProjectService, broker *events.Broker, monitor *health.Monitor, assets *AssetService, reviews *ReviewService) *VideoService {
+	s := &VideoService{cfg: cfg, db: db, projects: projects, events: broker, health: monitor, assets: assets, reviews: reviews}
+	if monitor != nil {
+		// Runway and Pika have no free status endpoint, so they are tracked passively
+		monitor.Register(string(ProviderRunway), health.KindVideo, nil)
+		monitor.Register(string(ProviderPika), health.KindVideo, nil)
+		if cfg.AI.VideoServiceURL != "" {
+			monitor.Register(string(ProviderLocal), health.KindVideo, s.probeLocalService)
+		}
+	}
+	return s
 }
 
 // VideoGenerationRequest represents a video generation request
@@ -40,6 +73,9 @@ type VideoGenerationRequest struct {
 	ImageURL    string // for image-to-video
 	Duration    int    // seconds (1-60)
 	AspectRatio string // "16:9" or "9:16"
+	Motion      string // Ken Burns preset for image scenes, local renderer only
+	Dialogue    string // shown as subtitles by the local renderer
+	Speakers    []string
 }
 
 // VideoGenerationResult represents the result of video generation
@@ -50,6 +86,8 @@ type VideoGenerationResult struct {
 	Status      string // "pending", "processing", "completed", "failed"
 	Duration    int
 	Resolution  string
+	Progress    int    // percent, when the provider reports it
+	Error       string // provider failure reason
 	CreatedAt   time.Time
 	CompletedAt *time.Time
 }
@@ -74,6 +112,8 @@ func (s *VideoService) generateWithLocalService(ctx context.Context, req *VideoG
 	}
 
 	endpoint := s.cfg.AI.VideoServiceURL
+	// Clips carry dialogue as a soft track only; the episode render decides
+	// how subtitles are shown
 	requestBody := map[string]interface{}{
 		"prompt":       req.Prompt,
 		"image_url":    req.ImageURL,
@@ -81,6 +121,10 @@ func (s *VideoService) generateWithLocalService(ctx context.Context, req *VideoG
 		"aspect_ratio": req.AspectRatio,
 		"scene_id":     req.SceneID,
 		"project_id":   req.ProjectID,
+		"motion":       req.Motion,
+		"dialogue":     req.Dialogue,
+		"speakers":     req.Speakers,
+		"subtitles":    "soft",
 	}
 
 	jsonData, _ := json.Marshal(requestBody)
@@ -324,9 +368,13 @@ func (s *VideoService) PollVideoStatus(ctx context.Context, videoID string, prov
 
 	var statusResp struct {
 		ID       string   `json:"id"`
+		VideoID  string   `json:"video_id"`
 		Status   string   `json:"status"`
 		Output   []string `json:"output"`
 		VideoURL string   `json:"video_url"`
+		Progress float64  `json:"progress"`
+		Message  string   `json:"message"`
+		Error    string   `json:"error"`
 	}
 
 	if err := json.Unmarshal(body, &statusResp); err != nil {
@@ -337,8 +385,16 @@ func (s *VideoService) PollVideoStatus(ctx context.Context, videoID string, prov
 		VideoID:   statusResp.ID,
 		Provider:  provider,
 		Status:    statusResp.Status,
+		Progress:  normalizeProgress(statusResp.Progress),
+		Error:     statusResp.Error,
 		CreatedAt: time.Now(),
 	}
+	if result.VideoID == "" {
+		result.VideoID = statusResp.VideoID
+	}
+	if result.Error == "" {
+		result.Error = statusResp.Message
+	}
 
 	// Get video URL from response
 	if statusResp.VideoURL != "" {
@@ -350,25 +406,27 @@ func (s *VideoService) PollVideoStatus(ctx context.Context, videoID string, prov
 	return result, nil
 }
 
-// FailoverGenerate attempts to generate video with primary provider, falls back to secondary
+// FailoverGenerate attempts to generate video with primary provider, falls back to secondary.
+// Providers whose circuit is open are skipped without being called.
 func (s *VideoService) FailoverGenerate(ctx context.Context, req *VideoGenerationRequest) (*VideoGenerationResult, error) {
+	primary := req.Provider
+	fallback := ProviderRunway
+	if primary == ProviderRunway {
+		fallback = ProviderPika
+	}
+
 	// Try primary provider
-	result, err := s.GenerateVideo(ctx, req)
+	result, err := s.generateGuarded(ctx, req)
 	if err == nil {
 		return result, nil
 	}
 
 	// Log the error and attempt fallback
-	fmt.Printf("Primary provider %s failed: %v, attempting fallback\n", req.Provider, err)
+	log.Printf("primary provider %s failed: %v, attempting fallback", primary, err)
 
 	// Switch to fallback provider
-	if req.Provider == ProviderRunway {
-		req.Provider = ProviderPika
-	} else {
-		req.Provider = ProviderRunway
-	}
-
-	result, fallbackErr := s.GenerateVideo(ctx, req)
+	req.Provider = fallback
+	result, fallbackErr := s.generateGuarded(ctx, req)
 	if fallbackErr != nil {
 		return nil, fmt.Errorf("both providers failed. Primary: %w, Fallback: %w", err, fallbackErr)
 	}
@@ -376,36 +434,495 @@ func (s *VideoService) FailoverGenerate(ctx context.Context, req *VideoGeneratio
 	return result, nil
 }
 
-// GenerateVideoTask represents an async video generation task
-type GenerateVideoTask struct {
-	ID          uint
-	ProjectID   uint
-	SceneID     uint
-	Status      string // pending, processing, completed, failed
-	VideoURL    string
-	ErrorMsg    string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	CompletedAt *time.Time
+// generateGuarded calls GenerateVideo through the provider's circuit breaker
+func (s *VideoService) generateGuarded(ctx context.Context, req *VideoGenerationRequest) (*VideoGenerationResult, error) {
+	name := string(req.Provider)
+	if s.health != nil && !s.health.Allow(name) {
+		return nil, fmt.Errorf("%s: %w", name, ErrProviderUnavailable)
+	}
+
+	start := time.Now()
+	result, err := s.GenerateVideo(ctx, req)
+	if s.health != nil {
+		s.health.Record(name, err, time.Since(start))
+	}
+	return result, err
 }
 
-// SaveVideoTask persists a video generation task
-func (s *VideoService) SaveVideoTask(ctx context.Context, task *GenerateVideoTask) error {
-	// This would be implemented with database persistence
-	// For now, placeholder
+// probeLocalService checks the /health endpoint next to AI_VIDEO_SERVICE_URL
+func (s *VideoService) probeLocalService(ctx context.Context) error {
+	u, err := url.Parse(s.cfg.AI.VideoServiceURL)
+	if err != nil {
+		return fmt.Errorf("invalid AI_VIDEO_SERVICE_URL: %w", err)
+	}
+	u.Path, u.RawQuery = "/health", ""
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("local video service health check returned status %d", resp.StatusCode)
+	}
 	return nil
 }
 
+// VideoTaskFilter narrows ListVideoTasks results
+type VideoTaskFilter struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+// SaveVideoTask persists a video generation task, creating it when it has no ID yet
+func (s *VideoService) SaveVideoTask(ctx context.Context, task *models.VideoTask) error {
+	if task.IsTerminal() && task.CompletedAt == nil {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+	if task.ID == 0 {
+		return s.db.WithContext(ctx).Create(task).Error
+	}
+	return s.db.WithContext(ctx).Save(task).Error
+}
+
+// saveUnfinished writes a task back only while the stored row is still
+// unfinished, so a late provider answer can't revive a task that was
+// cancelled or failed meanwhile. It reports whether the row was updated.
+func (s *VideoService) saveUnfinished(ctx context.Context, task *models.VideoTask) (bool, error) {
+	if task.IsTerminal() && task.CompletedAt == nil {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+	res := s.db.WithContext(ctx).Model(task).
+		Where("status NOT IN ?", []string{models.VideoTaskCompleted, models.VideoTaskFailed, models.VideoTaskCancelled}).
+		Select("*").Omit("id", "created_at").
+		Updates(task)
+	return res.RowsAffected == 1, res.Error
+}
+
 // GetVideoTask retrieves a video generation task by ID
-func (s *VideoService) GetVideoTask(ctx context.Context, taskID uint) (*GenerateVideoTask, error) {
-	// This would be implemented with database retrieval
-	// For now, placeholder
-	return nil, nil
+func (s *VideoService) GetVideoTask(ctx context.Context, taskID uint) (*models.VideoTask, error) {
+	var task models.VideoTask
+	if err := s.db.WithContext(ctx).First(&task, taskID).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// CancelVideoTask stops tracking a queued or running video task and puts its
+// scene back on the media it had before. A job already sent to a provider
+// runs on there, but its result is no longer applied.
+func (s *VideoService) CancelVideoTask(ctx context.Context, projectID, taskID uint) (*models.VideoTask, error) {
+	var task models.VideoTask
+	err := s.db.WithContext(ctx).Where("id = ? AND project_id = ?", taskID, projectID).First(&task).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVideoTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if task.IsTerminal() {
+		return nil, fmt.Errorf("%w: %s", ErrVideoTaskFinished, task.Status)
+	}
+
+	task.Status = models.VideoTaskCancelled
+	saved, err := s.saveUnfinished(ctx, &task)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrVideoTaskFinished
+	}
+	if err := s.syncScene(ctx, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// FindVideoTaskByProviderID retrieves the task a provider job belongs to; an empty provider matches any
+func (s *VideoService) FindVideoTaskByProviderID(ctx context.Context, projectID uint, provider VideoProvider, videoID string) (*models.VideoTask, error) {
+	query := s.db.WithContext(ctx).Where("project_id = ? AND provider_video_id = ?", projectID, videoID)
+	if provider != "" {
+		query = query.Where("provider = ?", string(provider))
+	}
+
+	var task models.VideoTask
+	if err := query.First(&task).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListVideoTasks retrieves the video tasks for a project, newest first, along with the unpaginated total
+func (s *VideoService) ListVideoTasks(ctx context.Context, projectID uint, filter VideoTaskFilter) ([]*models.VideoTask, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.VideoTask{}).Where("project_id = ?", projectID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var tasks []*models.VideoTask
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Order("created_at DESC").Offset(filter.Offset).Find(&tasks).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return tasks, total, nil
+}
+
+// ApplyVideoResult copies a provider result onto a task, persists the status
+// transition and mirrors it onto the task's scene. A task that has already
+// ended is left alone and reloaded into task.
+func (s *VideoService) ApplyVideoResult(ctx context.Context, task *models.VideoTask, result *VideoGenerationResult) error {
+	current, err := s.GetVideoTask(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	if current.IsTerminal() {
+		*task = *current
+		return nil
+	}
+
+	if result.Provider != "" {
+		task.Provider = string(result.Provider)
+	}
+	if result.VideoID != "" {
+		task.ProviderVideoID = result.VideoID
+	}
+	if result.VideoURL != "" && task.AssetID == nil {
+		task.VideoURL = result.VideoURL
+	}
+	task.Status = normalizeVideoStatus(result.Status)
+	if task.Status == models.VideoTaskCompleted && task.AssetID == nil {
+		if err := s.storeVideo(ctx, task); err != nil {
+			// Leave the task unfinished so the poller retries the download
+			log.Printf("video task %d: %v", task.ID, err)
+			task.Status = models.VideoTaskProcessing
+			task.ErrorMessage = err.Error()
+		}
+	}
+	switch task.Status {
+	case models.VideoTaskCompleted:
+		task.ErrorMessage = ""
+	case models.VideoTaskFailed:
+		if result.Error != "" {
+			task.ErrorMessage = result.Error
+		}
+	}
+	s.schedulePoll(task)
+
+	saved, err := s.saveUnfinished(ctx, task)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		// Cancelled or failed while we were downloading
+		if task.AssetID != nil {
+			s.dropVideo(ctx, *task.AssetID)
+		}
+		current, err := s.GetVideoTask(ctx, task.ID)
+		if err != nil {
+			return err
+		}
+		*task = *current
+		return nil
+	}
+
+	eventType := events.VideoProgress
+	progress := result.Progress
+	if task.Status == models.VideoTaskCompleted {
+		eventType = events.VideoReady
+		progress = 100
+	}
+	s.events.Emit(ctx, task.ProjectID, eventType, map[string]interface{}{
+		"video_task_id": task.ID,
+		"scene_id":      task.SceneID,
+		"status":        task.Status,
+		"progress":      progress,
+		"video_url":     task.VideoURL,
+	})
+
+	return s.syncScene(ctx, task)
+}
+
+// storeVideo moves a finished clip from the provider into our storage
+func (s *VideoService) storeVideo(ctx context.Context, task *models.VideoTask) error {
+	if task.VideoURL == "" {
+		return fmt.Errorf("provider reported completion without a video URL")
+	}
+	asset, err := s.assets.Import(ctx, models.AssetVideo, AssetOwner{ProjectID: task.ProjectID, SceneID: task.SceneID}, task.VideoURL)
+	if err != nil {
+		return fmt.Errorf("failed to store video: %w", err)
+	}
+	task.VideoURL = asset.URL
+	task.AssetID = &asset.ID
+	return nil
+}
+
+// dropVideo removes a clip stored for a task that ended before it was saved
+func (s *VideoService) dropVideo(ctx context.Context, assetID uint) {
+	var asset models.Asset
+	if err := s.db.WithContext(ctx).First(&asset, assetID).Error; err != nil {
+		log.Printf("failed to find orphaned video asset %d: %v", assetID, err)
+		return
+	}
+	if err := s.assets.Delete(ctx, &asset); err != nil {
+		log.Printf("failed to delete orphaned video asset %d: %v", assetID, err)
+	}
+}
+
+// ClaimDueVideoTasks returns non-terminal tasks whose next poll is due. Each
+// task is claimed by pushing its next poll time forward with a conditional
+// update, so several pollers can share the table without double polling.
+func (s *VideoService) ClaimDueVideoTasks(ctx context.Context, limit int) ([]*models.VideoTask, error) {
+	now := time.Now()
+	var due []*models.VideoTask
+	err := s.db.WithContext(ctx).
+		Where("status IN ? AND provider_video_id <> '' AND next_poll_at <= ?",
+			[]string{models.VideoTaskPending, models.VideoTaskProcessing}, now).
+		Order("next_poll_at ASC").
+		Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]*models.VideoTask, 0, len(due))
+	for _, task := range due {
+		next := now.Add(s.pollInterval(VideoProvider(task.Provider)))
+		res := s.db.WithContext(ctx).Model(&models.VideoTask{}).
+			Where("id = ? AND next_poll_at = ?", task.ID, task.NextPollAt).
+			Update("next_poll_at", next)
+		if res.Error != nil {
+			return claimed, res.Error
+		}
+		if res.RowsAffected == 1 {
+			task.NextPollAt = &next
+			claimed = append(claimed, task)
+		}
+	}
+	return claimed, nil
+}
+
+// PollVideoTask asks the provider for the task's status once and records the
+// result. Tasks still unfinished after the poll deadline are failed.
+func (s *VideoService) PollVideoTask(ctx context.Context, task *models.VideoTask) (*VideoGenerationResult, error) {
+	task.PollCount++
+	result, err := s.PollVideoStatus(ctx, task.ProviderVideoID, VideoProvider(task.Provider))
+	if err == nil {
+		if result.VideoID == "" {
+			result.VideoID = task.ProviderVideoID
+		}
+		if err := s.ApplyVideoResult(ctx, task, result); err != nil {
+			return nil, err
+		}
+	}
+
+	if !task.IsTerminal() && s.pastDeadline(task) {
+		cause := fmt.Errorf("video not ready after %ds", s.cfg.Poller.Deadline)
+		if err != nil {
+			cause = fmt.Errorf("%w: last poll error: %v", cause, err)
+		}
+		task.Status = models.VideoTaskFailed
+		task.ErrorMessage = cause.Error()
+		saved, saveErr := s.saveUnfinished(ctx, task)
+		if saveErr != nil || !saved {
+			return result, saveErr
+		}
+		return result, s.syncScene(ctx, task)
+	}
+
+	if err != nil {
+		// Transient: keep the claimed next poll time and try again later
+		s.db.WithContext(ctx).Model(task).Update("poll_count", task.PollCount)
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *VideoService) pastDeadline(task *models.VideoTask) bool {
+	if s.cfg.Poller.Deadline <= 0 {
+		return false
+	}
+	return time.Since(task.CreatedAt) > time.Duration(s.cfg.Poller.Deadline)*time.Second
+}
+
+// schedulePoll sets when the poller should next look at an unfinished task
+func (s *VideoService) schedulePoll(task *models.VideoTask) {
+	if task.IsTerminal() || task.ProviderVideoID == "" {
+		task.NextPollAt = nil
+		return
+	}
+	next := time.Now().Add(s.pollInterval(VideoProvider(task.Provider)))
+	task.NextPollAt = &next
+}
+
+// pollInterval returns the provider's polling interval with random jitter
+func (s *VideoService) pollInterval(provider VideoProvider) time.Duration {
+	seconds := s.cfg.Poller.LocalInterval
+	switch provider {
+	case ProviderRunway:
+		seconds = s.cfg.Poller.RunwayInterval
+	case ProviderPika:
+		seconds = s.cfg.Poller.PikaInterval
+	}
+	if seconds <= 0 {
+		seconds = 5
+	}
+	interval := time.Duration(seconds) * time.Second
+	if s.cfg.Poller.JitterPercent > 0 {
+		spread := int64(interval) * int64(s.cfg.Poller.JitterPercent) / 100
+		interval += time.Duration(rand.Int63n(2*spread+1) - spread)
+	}
+	return interval
+}
+
+// syncScene writes a video task's state back to its scene and lets the
+// project complete once every scene is done. A finished clip is put through
+// content review first.
+func (s *VideoService) syncScene(ctx context.Context, task *models.VideoTask) error {
+	if task.SceneID == 0 {
+		return nil
+	}
+	var scene models.Scene
+	if err := s.db.WithContext(ctx).Where("id = ? AND project_id = ?", task.SceneID, task.ProjectID).First(&scene).Error; err != nil {
+		return nil
+	}
+
+	switch task.Status {
+	case models.VideoTaskCompleted:
+		scene.MediaURL = task.VideoURL
+		scene.MediaAssetID = task.AssetID
+		scene.MediaType = "video"
+		scene.Status = "completed"
+		if review := s.reviews.ReviewVideo(ctx, &scene); review != nil && review.Held() {
+			scene.Status = heldStatus(review)
+		}
+	case models.VideoTaskFailed:
+		scene.Status = "failed"
+	case models.VideoTaskCancelled:
+		// Fall back to whatever media the scene had before
+		if scene.MediaURL != "" {
+			scene.Status = "completed"
+		} else {
+			scene.Status = "failed"
+		}
+	default:
+		scene.Status = "processing"
+	}
+	if err := s.db.WithContext(ctx).Model(&scene).Select("media_url", "media_asset_id", "media_type", "status").Updates(&scene).Error; err != nil {
+		return err
+	}
+
+	if scene.Status == "processing" {
+		return s.projects.MarkProjectProcessing(ctx, task.ProjectID)
+	}
+	return s.projects.RefreshProjectStatus(ctx, task.ProjectID)
+}
+
+func normalizeProgress(p float64) int {
+	// Providers report either a 0-1 fraction or a 0-100 percentage
+	if p > 0 && p <= 1 {
+		p *= 100
+	}
+	if p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return int(p)
 }
 
-// ListVideoTasks retrieves all video tasks for a project
-func (s *VideoService) ListVideoTasks(ctx context.Context, projectID uint) ([]*GenerateVideoTask, error) {
-	// This would be implemented with database query
-	// For now, placeholder
-	return nil, nil
+// RunVideoTask submits a persisted video task to its provider (with failover)
+// and records the provider job on the task row
+func (s *VideoService) RunVideoTask(ctx context.Context, taskID uint) error {
+	task, err := s.GetVideoTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if task.IsTerminal() || task.ProviderVideoID != "" {
+		return nil
+	}
+
+	task.Status = models.VideoTaskProcessing
+	saved, err := s.saveUnfinished(ctx, task)
+	if err != nil || !saved {
+		return err
+	}
+
+	var scene models.Scene
+	if err := s.db.WithContext(ctx).First(&scene, task.SceneID).Error; err != nil {
+		return err
+	}
+	speakers := make([]string, 0, len(scene.Characters))
+	for _, c := range scene.Characters {
+		speakers = append(speakers, c.Name)
+	}
+
+	result, err := s.FailoverGenerate(ctx, &VideoGenerationRequest{
+		ProjectID:   task.ProjectID,
+		SceneID:     task.SceneID,
+		Prompt:      task.Prompt,
+		Provider:    VideoProvider(task.Provider),
+		ImageURL:    s.assets.ShareURL(ctx, task.ImageURL),
+		Duration:    task.Duration,
+		AspectRatio: task.AspectRatio,
+		Motion:      task.Motion,
+		Dialogue:    scene.Dialogue,
+		Speakers:    speakers,
+	})
+	if err != nil {
+		// Leaves a task cancelled while the provider call ran alone
+		task.ErrorMessage = err.Error()
+		if _, saveErr := s.saveUnfinished(ctx, task); saveErr != nil {
+			return fmt.Errorf("%w (and failed to record it: %v)", err, saveErr)
+		}
+		return err
+	}
+
+	return s.ApplyVideoResult(ctx, task, result)
+}
+
+// MarkVideoTaskFailed records that a video task could not be submitted
+func (s *VideoService) MarkVideoTaskFailed(ctx context.Context, taskID uint, cause error) error {
+	task, err := s.GetVideoTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	task.Status = models.VideoTaskFailed
+	task.ErrorMessage = cause.Error()
+	saved, err := s.saveUnfinished(ctx, task)
+	if err != nil || !saved {
+		return err
+	}
+	return s.syncScene(ctx, task)
+}
+
+// normalizeVideoStatus maps the provider-specific status vocabulary onto ours
+func normalizeVideoStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "completed", "succeeded", "success", "finished", "done":
+		return models.VideoTaskCompleted
+	case "failed", "error", "failure":
+		return models.VideoTaskFailed
+	case "cancelled", "canceled":
+		return models.VideoTaskCancelled
+	case "pending", "queued", "":
+		return models.VideoTaskPending
+	default:
+		return models.VideoTaskProcessing
+	}
 }
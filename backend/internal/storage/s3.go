@@ -0,0 +1,350 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3-compatible bucket (Aliyun OSS, MinIO, AWS S3)
+type S3Config struct {
+	Endpoint        string // e.g. oss-cn-hangzhou.aliyuncs.com or http://localhost:9000
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// BaseURL is the public URL of the bucket, e.g. a CDN; empty uses the endpoint
+	BaseURL string
+	// PathStyle addresses the bucket as endpoint/bucket/key, as MinIO expects;
+	// otherwise bucket.endpoint/key is used, as OSS expects
+	PathStyle bool
+}
+
+// S3Store talks to the S3 REST API directly, signing requests with AWS
+// Signature Version 4
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 storage needs OSS_ENDPOINT and OSS_BUCKET_NAME")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 storage needs OSS_ACCESS_KEY_ID and OSS_ACCESS_KEY_SECRET")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	raw := cfg.Endpoint
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	endpoint, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OSS_ENDPOINT: %w", err)
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &S3Store{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+// objectURL addresses a key path-style or virtual-hosted-style
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	// S3 needs the length up front and SigV4 signs the payload hash, so
+	// streams are spooled to a temp file first
+	body, size, payloadHash, cleanup, err := spool(r)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), body)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req, payloadHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return s.URL(key), nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	if err := validKey(key); err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, objectFromHeader(key, resp.Header), nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (*Object, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectFromHeader(key, resp.Header), nil
+}
+
+// PresignedURL signs a GET in the query string; S3 caps expiry at 7 days
+func (s *S3Store) PresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if expiry <= 0 || expiry > 7*24*time.Hour {
+		return "", fmt.Errorf("presigned URL expiry must be between 1s and 7 days, got %s", expiry)
+	}
+	u := s.objectURL(key)
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := credentialScope(now, s.cfg.Region)
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.cfg.AccessKeyID+"/"+scope)
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	q.Set("X-Amz-Signature", s.signature(now, amzDate, scope, canonical))
+	u.RawQuery = canonicalQuery(q)
+	return u.String(), nil
+}
+
+func (s *S3Store) URL(key string) string {
+	if s.cfg.BaseURL != "" {
+		return s.cfg.BaseURL + "/" + key
+	}
+	if key == "" {
+		return strings.TrimSuffix(s.objectURL("").String(), "/") + "/"
+	}
+	return s.objectURL(key).String()
+}
+
+// do signs and sends a request, mapping error statuses to errors
+func (s *S3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("s3 API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds a SigV4 Authorization header covering host and the x-amz-* headers
+func (s *S3Store) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := credentialScope(now, s.cfg.Region)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, s.signature(now, amzDate, scope, canonical)))
+}
+
+func (s *S3Store) signature(now time.Time, amzDate, scope, canonicalRequest string) string {
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func credentialScope(t time.Time, region string) string {
+	return t.Format("20060102") + "/" + region + "/s3/aws4_request"
+}
+
+// canonicalQuery sorts parameters and encodes them the way SigV4 expects
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters; slashes
+// are kept in paths
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func objectFromHeader(key string, h http.Header) *Object {
+	obj := &Object{Key: key, ContentType: h.Get("Content-Type")}
+	obj.Size, _ = strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	obj.ModTime, _ = http.ParseTime(h.Get("Last-Modified"))
+	return obj
+}
+
+// spool copies r to a temp file, returning it rewound with its size and hash
+func spool(r io.Reader) (io.Reader, int64, string, func(), error) {
+	f, err := os.CreateTemp("", "3kstory-upload-*")
+	if err != nil {
+		return nil, 0, "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), r)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, "", nil, fmt.Errorf("failed to buffer upload: %w", err)
+	}
+	return f, size, hex.EncodeToString(hash.Sum(nil)), cleanup, nil
+}
@@ -0,0 +1,130 @@
+// Package storage keeps generated media under our own control, so scenes
+// never point at URLs that belong to an AI provider
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/config"
+)
+
+// ErrNotFound is returned for keys that hold no object
+var ErrNotFound = errors.New("storage object not found")
+
+// Backend names for STORAGE_DRIVER
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// Object describes a stored object
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Store saves objects under a key and serves them from a URL
+type Store interface {
+	// Put writes the object and returns the URL it is served from
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	// Get opens an object; the caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete removes an object; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*Object, error)
+	// PresignedURL grants temporary read access to an object
+	PresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// URL returns the public URL of a key
+	URL(key string) string
+}
+
+// NewFromConfig builds the backend selected by STORAGE_DRIVER
+func NewFromConfig(cfg *config.Config) (Store, error) {
+	switch cfg.Storage.Driver {
+	case "", DriverLocal:
+		return NewLocalStore(cfg.Storage.LocalRoot, cfg.Storage.BaseURL), nil
+	case DriverS3:
+		return NewS3Store(S3Config{
+			Endpoint:        cfg.OSS.Endpoint,
+			Region:          cfg.OSS.Region,
+			Bucket:          cfg.OSS.BucketName,
+			AccessKeyID:     cfg.OSS.AccessKeyID,
+			SecretAccessKey: cfg.OSS.AccessKeySecret,
+			BaseURL:         cfg.OSS.BaseURL,
+			PathStyle:       cfg.OSS.PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q (want local or s3)", cfg.Storage.Driver)
+	}
+}
+
+// KeyForURL maps a URL handed out by the store back to its key, so media
+// that is already ours is not downloaded and stored a second time
+func KeyForURL(s Store, url string) (string, bool) {
+	prefix := s.URL("")
+	if prefix == "" || !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	key, _, _ := strings.Cut(strings.TrimPrefix(url, prefix), "?")
+	return key, key != ""
+}
+
+// ContentKey names an object by the hash of its content, so identical media
+// is stored once, e.g. images/3f2a....png
+func ContentKey(prefix string, data []byte, contentType string) string {
+	sum := sha256.Sum256(data)
+	return HashKey(prefix, hex.EncodeToString(sum[:]), contentType)
+}
+
+// HashKey is ContentKey for a hash computed elsewhere
+func HashKey(prefix, hash, contentType string) string {
+	return fmt.Sprintf("%s/%s%s", strings.Trim(prefix, "/"), hash, Extension(contentType))
+}
+
+// Extension picks a file extension for the media types we produce
+func Extension(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(strings.ToLower(mediaType)) {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	case "video/mp4":
+		return ".mp4"
+	case "video/webm":
+		return ".webm"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return ".wav"
+	case "audio/mp4", "audio/aac":
+		return ".m4a"
+	case "audio/ogg", "application/ogg":
+		return ".ogg"
+	case "text/plain":
+		return ".txt"
+	default:
+		return ".bin"
+	}
+}
+
+// validKey rejects keys that could escape a prefix or root directory
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "..") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
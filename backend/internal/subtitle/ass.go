@@ -0,0 +1,68 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Style is how burned-in subtitles look. Font is a family name looked up by
+// libass, which falls back to any installed font that covers the text.
+type Style struct {
+	Font   string
+	Width  int
+	Height int
+}
+
+// DefaultFont covers Chinese and is packaged by most Linux distributions
+const DefaultFont = "Noto Sans CJK SC"
+
+// ASS renders cues as an Advanced SubStation file sized for the video:
+// white text with a dark outline at the bottom, narration in italics
+func ASS(cues []Cue, st Style) []byte {
+	if st.Font == "" {
+		st.Font = DefaultFont
+	}
+	if st.Width <= 0 || st.Height <= 0 {
+		st.Width, st.Height = 1280, 720
+	}
+	size := min(st.Width, st.Height) / 18
+	margin := st.Width / 20
+	// CJK glyphs are about as wide as the font size, i.e. two cells
+	width := (st.Width - 2*margin) * 2 / size
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Script Info]\nScriptType: v4.00+\nPlayResX: %d\nPlayResY: %d\nWrapStyle: 2\nScaledBorderAndShadow: yes\n\n", st.Width, st.Height)
+	b.WriteString("[V4+ Styles]\n")
+	b.WriteString("Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	for _, style := range []struct {
+		name, colour string
+		italic       int
+	}{{"Default", "&H00FFFFFF", 0}, {"Narration", "&H00D7F0FF", -1}} {
+		fmt.Fprintf(&b, "Style: %s,%s,%d,%s,&H000000FF,&H00000000,&H96000000,0,%d,0,0,100,100,0,0,1,%d,1,2,%d,%d,%d,1\n",
+			style.name, st.Font, size, style.colour, style.italic, max(size/16, 2), margin, margin, st.Height/16)
+	}
+	b.WriteString("\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	for _, c := range cues {
+		style := "Default"
+		if c.Narration() {
+			style = "Narration"
+		}
+		lines := Wrap(c.Text, width)
+		for i := range lines {
+			lines[i] = assEscape(lines[i])
+		}
+		fmt.Fprintf(&b, "Dialogue: 0,%s,%s,%s,%s,0,0,0,,%s\n",
+			assTime(c.Start), assTime(c.End), style, strings.ReplaceAll(c.Speaker, ",", "，"), strings.Join(lines, `\N`))
+	}
+	return []byte(b.String())
+}
+
+// assTime formats seconds as H:MM:SS.cc
+func assTime(seconds float64) string {
+	cs := int64(math.Round(math.Max(seconds, 0) * 100))
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// assEscape keeps dialogue from being read as override tags
+var assEscape = strings.NewReplacer(`\`, `＼`, "{", "｛", "}", "｝").Replace
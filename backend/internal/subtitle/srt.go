@@ -0,0 +1,27 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// srtWidth is how many cells an SRT line holds, about 20 CJK characters
+const srtWidth = 40
+
+// SRT renders cues as a SubRip file
+func SRT(cues []Cue) []byte {
+	var b strings.Builder
+	for i, c := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", i+1, srtTime(c.Start), srtTime(c.End))
+		b.WriteString(strings.Join(Wrap(c.Text, srtWidth), "\n"))
+		b.WriteString("\n\n")
+	}
+	return []byte(b.String())
+}
+
+// srtTime formats seconds as HH:MM:SS,mmm
+func srtTime(seconds float64) string {
+	ms := int64(math.Round(math.Max(seconds, 0) * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
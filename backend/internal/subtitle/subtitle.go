@@ -0,0 +1,166 @@
+// Package subtitle builds SRT and ASS subtitles from scene dialogue
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/richard9219/3kstory/internal/tts"
+)
+
+// Mode is how subtitles end up in a rendered video
+type Mode string
+
+const (
+	// ModeBurn draws the subtitles into the picture
+	ModeBurn Mode = "burn"
+	// ModeSoft adds a subtitle track players can switch off
+	ModeSoft Mode = "soft"
+	ModeNone Mode = "none"
+)
+
+// Modes lists the values a render request may ask for
+var Modes = []Mode{ModeBurn, ModeSoft, ModeNone}
+
+// ParseMode validates a requested mode; an empty value means none
+func ParseMode(s string) (Mode, error) {
+	m := Mode(strings.ToLower(strings.TrimSpace(s)))
+	if m == "" {
+		return ModeNone, nil
+	}
+	for _, known := range Modes {
+		if m == known {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown subtitle mode %q", s)
+}
+
+// Cue is one subtitle on screen, in seconds from the start of the video
+type Cue struct {
+	Start   float64
+	End     float64
+	Speaker string
+	Text    string
+}
+
+// Narration reports whether the cue is spoken by nobody in the scene
+func (c Cue) Narration() bool {
+	return c.Speaker == tts.Narrator
+}
+
+// Spacing of estimated cues, in seconds
+const (
+	cueLead = 0.3
+	cueGap  = 0.2
+)
+
+// FromDialogue spreads a scene's dialogue over seconds when there is no
+// speech to time it by. Each line gets time in proportion to its length.
+func FromDialogue(dialogue string, known []string, seconds float64) []Cue {
+	lines := tts.SplitDialogue(dialogue, known)
+	if len(lines) == 0 || seconds <= 0 {
+		return nil
+	}
+
+	lead, gap := cueLead, cueGap
+	usable := seconds - 2*lead - gap*float64(len(lines)-1)
+	// Short scenes show their lines back to back
+	if usable < 0.5*float64(len(lines)) {
+		lead, gap, usable = 0, 0, seconds
+	}
+
+	weights := make([]float64, len(lines))
+	total := 0.0
+	for i, line := range lines {
+		// Every line needs a moment to be read, however short
+		weights[i] = float64(utf8.RuneCountInString(line.Text) + 6)
+		total += weights[i]
+	}
+
+	cues := make([]Cue, 0, len(lines))
+	cursor := lead
+	for i, line := range lines {
+		length := usable * weights[i] / total
+		cues = append(cues, Cue{Start: cursor, End: cursor + length, Speaker: line.Speaker, Text: line.Text})
+		cursor += length + gap
+	}
+	return cues
+}
+
+// Shift moves cues by offset seconds and drops the part of them past limit,
+// e.g. where the next scene takes over; limit <= 0 keeps everything
+func Shift(cues []Cue, offset, limit float64) []Cue {
+	out := make([]Cue, 0, len(cues))
+	for _, c := range cues {
+		c.Start += offset
+		c.End += offset
+		if limit > 0 && c.End > limit {
+			c.End = limit
+		}
+		if c.End <= c.Start {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// closingPunct may not start a line
+const closingPunct = "，。、；：？！…）》」』”’,.;:?!)"
+
+// Wrap breaks text into lines of at most width cells; CJK characters take
+// two cells and everything else one. Latin text breaks at spaces where it
+// can, and closing punctuation stays with the line before it.
+func Wrap(text string, width int) []string {
+	text = strings.Join(strings.Fields(text), " ")
+	if width <= 0 || cells(text) <= width {
+		return []string{text}
+	}
+
+	var lines []string
+	var line []rune
+	used, lastSpace := 0, -1
+	for _, r := range text {
+		w := runeCells(r)
+		if used+w > width && len(line) > 0 && !strings.ContainsRune(closingPunct, r) {
+			cut := len(line)
+			if lastSpace > 0 && r != ' ' {
+				cut = lastSpace
+			}
+			lines = append(lines, strings.TrimSpace(string(line[:cut])))
+			line = []rune(strings.TrimLeft(string(line[cut:]), " "))
+			used, lastSpace = cells(string(line)), -1
+			if r == ' ' {
+				continue
+			}
+		}
+		if r == ' ' {
+			lastSpace = len(line)
+		}
+		line = append(line, r)
+		used += w
+	}
+	if rest := strings.TrimSpace(string(line)); rest != "" {
+		lines = append(lines, rest)
+	}
+	return lines
+}
+
+func cells(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeCells(r)
+	}
+	return n
+}
+
+func runeCells(r rune) int {
+	if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) || (r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef) {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,162 @@
+package subtitle
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/richard9219/3kstory/internal/tts"
+)
+
+func sameCues(a, b []Cue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i].Start-b[i].Start) > 1e-9 || math.Abs(a[i].End-b[i].End) > 1e-9 ||
+			a[i].Speaker != b[i].Speaker || a[i].Text != b[i].Text {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFromDialogue(t *testing.T) {
+	known := []string{"林夏", "周屿"}
+	tests := []struct {
+		name     string
+		dialogue string
+		seconds  float64
+		want     []Cue
+	}{
+		{
+			name:     "spaced cues",
+			dialogue: "林夏：你好\n周屿：再见",
+			seconds:  10,
+			want:     []Cue{{Start: 0.3, End: 4.9, Speaker: "林夏", Text: "你好"}, {Start: 5.1, End: 9.7, Speaker: "周屿", Text: "再见"}},
+		},
+		{
+			name:     "time follows length",
+			dialogue: "林夏：好\n旁白：他们沉默了很久很久很久",
+			seconds:  5.6,
+			want:     []Cue{{Start: 0.3, End: 1.7, Speaker: "林夏", Text: "好"}, {Start: 1.9, End: 5.3, Speaker: tts.Narrator, Text: "他们沉默了很久很久很久"}},
+		},
+		{
+			name:     "short scene back to back",
+			dialogue: "林夏：你好\n周屿：再见",
+			seconds:  0.8,
+			want:     []Cue{{Start: 0, End: 0.4, Speaker: "林夏", Text: "你好"}, {Start: 0.4, End: 0.8, Speaker: "周屿", Text: "再见"}},
+		},
+		{name: "no dialogue", dialogue: " \n", seconds: 5},
+		{name: "no time", dialogue: "林夏：你好", seconds: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromDialogue(tt.dialogue, known, tt.seconds); !sameCues(got, tt.want) {
+				t.Fatalf("FromDialogue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShift(t *testing.T) {
+	cues := []Cue{{Start: 0, End: 1, Text: "a"}, {Start: 1.5, End: 3, Text: "b"}, {Start: 3.5, End: 4, Text: "c"}}
+	got := Shift(cues, 10, 12.5)
+	want := []Cue{{Start: 10, End: 11, Text: "a"}, {Start: 11.5, End: 12.5, Text: "b"}}
+	if !sameCues(got, want) {
+		t.Fatalf("Shift() = %+v, want %+v", got, want)
+	}
+	if cues[0].Start != 0 {
+		t.Fatalf("Shift() changed its input: %+v", cues)
+	}
+	if got := Shift(cues, 2, 0); len(got) != 3 || got[2].End != 6 {
+		t.Fatalf("Shift() without limit = %+v", got)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{name: "fits", text: "你好", width: 10, want: []string{"你好"}},
+		{name: "no width", text: "  a   b ", width: 0, want: []string{"a b"}},
+		{name: "cjk takes two cells", text: "一二三四五六", width: 4, want: []string{"一二", "三四", "五六"}},
+		{name: "closing punctuation stays", text: "一二，三四。", width: 4, want: []string{"一二，", "三四。"}},
+		{name: "latin at spaces", text: "hello brave new world", width: 11, want: []string{"hello brave", "new world"}},
+		{name: "latin mid word", text: "hello brave", width: 8, want: []string{"hello", "brave"}},
+		{name: "long word", text: "abcdefgh", width: 4, want: []string{"abcd", "efgh"}},
+		{name: "mixed", text: "他说OK走了", width: 7, want: []string{"他说OK", "走了"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Wrap(tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Wrap(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		srt     string
+		ass     string
+	}{
+		{seconds: 0, srt: "00:00:00,000", ass: "0:00:00.00"},
+		{seconds: 1, srt: "00:00:01,000", ass: "0:00:01.00"},
+		{seconds: 2.5, srt: "00:00:02,500", ass: "0:00:02.50"},
+		{seconds: 59.999, srt: "00:00:59,999", ass: "0:01:00.00"},
+		{seconds: 3723.04, srt: "01:02:03,040", ass: "1:02:03.04"},
+		{seconds: -1, srt: "00:00:00,000", ass: "0:00:00.00"},
+	}
+	for _, tt := range tests {
+		if got := srtTime(tt.seconds); got != tt.srt {
+			t.Errorf("srtTime(%v) = %q, want %q", tt.seconds, got, tt.srt)
+		}
+		if got := assTime(tt.seconds); got != tt.ass {
+			t.Errorf("assTime(%v) = %q, want %q", tt.seconds, got, tt.ass)
+		}
+	}
+}
+
+func TestSRT(t *testing.T) {
+	cues := []Cue{
+		{Start: 1, End: 2.5, Speaker: "林夏", Text: "你好"},
+		{Start: 3, End: 6, Speaker: tts.Narrator, Text: strings.Repeat("雨", 25)},
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,500\n你好\n\n" +
+		"2\n00:00:03,000 --> 00:00:06,000\n" + strings.Repeat("雨", 20) + "\n" + strings.Repeat("雨", 5) + "\n\n"
+	if got := string(SRT(cues)); got != want {
+		t.Fatalf("SRT() = %q, want %q", got, want)
+	}
+}
+
+func TestASS(t *testing.T) {
+	cues := []Cue{
+		{Start: 1, End: 2.5, Speaker: "林夏", Text: "你好{\\b1}"},
+		{Start: 3, End: 6, Speaker: tts.Narrator, Text: strings.Repeat("雨", 30)},
+		{Start: 6, End: 7, Speaker: "A,B", Text: "hi"},
+	}
+	got := string(ASS(cues, Style{}))
+	for _, want := range []string{
+		"PlayResX: 1280\nPlayResY: 720\n",
+		"Style: Default,Noto Sans CJK SC,40,&H00FFFFFF,",
+		"Style: Narration,Noto Sans CJK SC,40,&H00D7F0FF,&H000000FF,&H00000000,&H96000000,0,-1,",
+		"Dialogue: 0,0:00:01.00,0:00:02.50,Default,林夏,0,0,0,,你好｛＼b1｝\n",
+		"Dialogue: 0,0:00:03.00,0:00:06.00,Narration," + tts.Narrator + ",0,0,0,," + strings.Repeat("雨", 28) + `\N` + "雨雨\n",
+		"Dialogue: 0,0:00:06.00,0:00:07.00,Default,A，B,0,0,0,,hi\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ASS() is missing %q:\n%s", want, got)
+		}
+	}
+
+	got = string(ASS(nil, Style{Font: "Serif", Width: 1080, Height: 1920}))
+	if !strings.Contains(got, "PlayResX: 1080\nPlayResY: 1920\n") || !strings.Contains(got, "Style: Default,Serif,60,") {
+		t.Errorf("ASS() ignored the style:\n%s", got)
+	}
+}
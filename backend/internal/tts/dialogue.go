@@ -0,0 +1,136 @@
+package tts
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Narrator is the speaker of lines that name nobody
+const Narrator = "旁白"
+
+// Line is one speaker's turn in a scene's dialogue
+type Line struct {
+	Speaker string
+	Text    string
+}
+
+// maxSpeakerRunes keeps "speaker: text" from matching ordinary sentences that
+// happen to contain a colon
+const maxSpeakerRunes = 16
+
+var (
+	// stage directions such as （冷笑） or (whispering) are not spoken
+	directionPattern = regexp.MustCompile(`（[^）]*）|\([^)]*\)|【[^】]*】|\[[^\]]*\]`)
+	narratorNames    = map[string]bool{"旁白": true, "画外音": true, "narrator": true, "vo": true, "v.o.": true}
+)
+
+// SplitDialogue breaks a scene's dialogue into lines by speaker. Lines look
+// like "林夏：你来了。" or "Lin (quietly): You came."; several turns on one
+// line are split when the speakers are among known. Anything without a
+// speaker is narration.
+func SplitDialogue(dialogue string, known []string) []Line {
+	var lines []Line
+	for _, raw := range strings.Split(strings.ReplaceAll(dialogue, "\r\n", "\n"), "\n") {
+		for _, turn := range splitTurns(raw, known) {
+			line := parseLine(turn)
+			if line.Text == "" {
+				continue
+			}
+			// Merge consecutive narration so it is read in one breath
+			if n := len(lines); n > 0 && line.Speaker == Narrator && lines[n-1].Speaker == Narrator {
+				lines[n-1].Text += " " + line.Text
+				continue
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Turns breaks a scene's dialogue into its raw turns, as SplitDialogue does,
+// but keeps stage directions and narration as written, e.g. to divide a scene
+// in two
+func Turns(dialogue string, known []string) []string {
+	var turns []string
+	for _, raw := range strings.Split(strings.ReplaceAll(dialogue, "\r\n", "\n"), "\n") {
+		for _, turn := range splitTurns(raw, known) {
+			if turn = strings.TrimSpace(turn); turn != "" {
+				turns = append(turns, turn)
+			}
+		}
+	}
+	return turns
+}
+
+// splitTurns cuts a line before every "Name：" of a known speaker
+func splitTurns(raw string, known []string) []string {
+	cuts := []int{0}
+	for _, name := range known {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		for _, sep := range []string{"：", ":"} {
+			marker := name + sep
+			for from := 1; from < len(raw); {
+				i := strings.Index(raw[from:], marker)
+				if i < 0 {
+					break
+				}
+				cuts = append(cuts, from+i)
+				from += i + len(marker)
+			}
+		}
+	}
+	if len(cuts) == 1 {
+		return []string{raw}
+	}
+	sort.Ints(cuts)
+	var turns []string
+	for i, start := range cuts {
+		end := len(raw)
+		if i+1 < len(cuts) {
+			end = cuts[i+1]
+		}
+		if start < end {
+			turns = append(turns, raw[start:end])
+		}
+	}
+	return turns
+}
+
+func parseLine(raw string) Line {
+	s := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(raw), "-*•·>"))
+	speaker := Narrator
+	if i := indexSeparator(s); i > 0 {
+		name := strings.TrimSpace(directionPattern.ReplaceAllString(s[:i], ""))
+		if name != "" && utf8.RuneCountInString(name) <= maxSpeakerRunes && !strings.ContainsAny(name, "。！？!?，,") {
+			speaker = name
+			if narratorNames[strings.ToLower(name)] {
+				speaker = Narrator
+			}
+			s = s[i+separatorLen(s, i):]
+		}
+	}
+	text := strings.TrimSpace(directionPattern.ReplaceAllString(s, ""))
+	text = strings.Trim(text, "\"“”「」『』 ")
+	return Line{Speaker: speaker, Text: strings.TrimSpace(text)}
+}
+
+// indexSeparator finds the first full- or half-width colon
+func indexSeparator(s string) int {
+	i := strings.Index(s, "：")
+	if j := strings.Index(s, ":"); j >= 0 && (i < 0 || j < i) {
+		i = j
+	}
+	return i
+}
+
+func separatorLen(s string, i int) int {
+	if strings.HasPrefix(s[i:], "：") {
+		return len("：")
+	}
+	return 1
+}
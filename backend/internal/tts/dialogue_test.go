@@ -0,0 +1,102 @@
+package tts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitDialogue(t *testing.T) {
+	known := []string{"林夏", "Lin"}
+	tests := []struct {
+		name     string
+		dialogue string
+		want     []Line
+	}{
+		{
+			name:     "full-width colon",
+			dialogue: "林夏：你来了。",
+			want:     []Line{{Speaker: "林夏", Text: "你来了。"}},
+		},
+		{
+			name:     "ascii colon with direction",
+			dialogue: "Lin (quietly): \"You came.\"",
+			want:     []Line{{Speaker: "Lin", Text: "You came."}},
+		},
+		{
+			name:     "direction removed from text",
+			dialogue: "林夏：（冷笑）「随你。」",
+			want:     []Line{{Speaker: "林夏", Text: "随你。"}},
+		},
+		{
+			name:     "unknown speaker",
+			dialogue: "路人甲：让一让！",
+			want:     []Line{{Speaker: "路人甲", Text: "让一让！"}},
+		},
+		{
+			name:     "narration merged",
+			dialogue: "雨一直下。\n街上没有人。\n旁白：她终于回来了。",
+			want:     []Line{{Speaker: Narrator, Text: "雨一直下。 街上没有人。 她终于回来了。"}},
+		},
+		{
+			name:     "colon in a sentence",
+			dialogue: "她说了一句话，声音很轻：再见。",
+			want:     []Line{{Speaker: Narrator, Text: "她说了一句话，声音很轻：再见。"}},
+		},
+		{
+			name:     "blank lines and directions only",
+			dialogue: "\r\n林夏：你好\r\n\n   \n（沉默）\n路人甲:嗯",
+			want:     []Line{{Speaker: "林夏", Text: "你好"}, {Speaker: "路人甲", Text: "嗯"}},
+		},
+		{
+			name:     "known speakers on one line",
+			dialogue: "林夏：走吧。Lin: OK.",
+			want:     []Line{{Speaker: "林夏", Text: "走吧。"}, {Speaker: "Lin", Text: "OK."}},
+		},
+		{
+			name:     "unknown speakers stay on one line",
+			dialogue: "林夏：走吧。路人甲：好。",
+			want:     []Line{{Speaker: "林夏", Text: "走吧。路人甲：好。"}},
+		},
+		{name: "empty", dialogue: "\n \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitDialogue(tt.dialogue, known); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SplitDialogue(%q) = %+v, want %+v", tt.dialogue, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTurns(t *testing.T) {
+	known := []string{"林夏", "周屿"}
+	tests := []struct {
+		name     string
+		dialogue string
+		want     []string
+	}{
+		{
+			name:     "one turn per line",
+			dialogue: "林夏：（冷笑）你来了。\n周屿: 嗯。",
+			want:     []string{"林夏：（冷笑）你来了。", "周屿: 嗯。"},
+		},
+		{
+			name:     "known speakers split",
+			dialogue: "林夏：走吧。 周屿：好。",
+			want:     []string{"林夏：走吧。", "周屿：好。"},
+		},
+		{
+			name:     "narration and unknown speakers kept",
+			dialogue: "雨一直下。\r\n\n路人甲：让一让！",
+			want:     []string{"雨一直下。", "路人甲：让一让！"},
+		},
+		{name: "blank", dialogue: " \n\t\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Turns(tt.dialogue, known); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Turns(%q) = %q, want %q", tt.dialogue, got, tt.want)
+			}
+		})
+	}
+}
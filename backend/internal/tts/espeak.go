@@ -0,0 +1,72 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// EspeakProvider speaks through a local espeak-ng, for offline development.
+// Voices are espeak voice names with an optional variant, e.g. "cmn" or
+// "cmn+f3"; the robotic result is enough to time subtitles and mixes.
+type EspeakProvider struct {
+	binary       string
+	defaultVoice string
+}
+
+// NewEspeakProvider uses voice when a request names none
+func NewEspeakProvider(voice string) *EspeakProvider {
+	if voice == "" {
+		voice = "cmn"
+	}
+	binary := "espeak-ng"
+	if _, err := exec.LookPath(binary); err != nil {
+		if _, err := exec.LookPath("espeak"); err == nil {
+			binary = "espeak"
+		}
+	}
+	return &EspeakProvider{binary: binary, defaultVoice: voice}
+}
+
+func (p *EspeakProvider) Name() string {
+	return ProviderEspeak
+}
+
+// Synthesize writes WAV to stdout; espeak's default rate is 175 words per minute
+func (p *EspeakProvider) Synthesize(ctx context.Context, req Request) (*Result, error) {
+	if strings.TrimSpace(req.Text) == "" {
+		return nil, errors.New("nothing to say")
+	}
+	voice := req.Voice
+	if voice == "" {
+		voice = p.defaultVoice
+	}
+	speed := req.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	cmd := exec.CommandContext(ctx, p.binary, "-v", voice, "-s", strconv.Itoa(int(175*speed)), "--stdout", req.Text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", p.binary, err, truncate(strings.TrimSpace(stderr.String()), 512))
+	}
+	data := stdout.Bytes()
+	mimeType := audioType(data, "audio/wav")
+	if err := checkAudio(p.Name(), data, mimeType); err != nil {
+		return nil, err
+	}
+	return &Result{Data: data, MIMEType: mimeType, Provider: p.Name()}, nil
+}
+
+func (p *EspeakProvider) Health(ctx context.Context) error {
+	if _, err := exec.LookPath(p.binary); err != nil {
+		return fmt.Errorf("%s not found on PATH", p.binary)
+	}
+	return nil
+}
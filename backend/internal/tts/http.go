@@ -0,0 +1,157 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPProvider calls a self-hosted speech service at AI_TTS_SERVICE_URL, such
+// as a CosyVoice, Edge-TTS or Piper wrapper. The service may answer with the
+// audio itself, or with JSON carrying it as base64 or as a URL, which is
+// downloaded right away.
+type HTTPProvider struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewHTTPProvider(endpoint string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{endpoint: endpoint, client: newClient(timeout)}
+}
+
+func (p *HTTPProvider) Name() string {
+	return ProviderHTTP
+}
+
+type httpSpeechResponse struct {
+	AudioBase64 string `json:"audio_base64"`
+	Audio       string `json:"audio"`
+	AudioURL    string `json:"audio_url"`
+	URL         string `json:"url"`
+	Error       string `json:"error"`
+}
+
+func (p *HTTPProvider) Synthesize(ctx context.Context, req Request) (*Result, error) {
+	speed := req.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"text":     req.Text,
+		"voice":    req.Voice,
+		"speed":    speed,
+		"language": req.Language,
+		"format":   "wav",
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	raw, contentType, err := fetch(p.client, p.Name(), httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Provider: p.Name()}
+	if mimeType := audioType(raw, contentType); checkAudio(p.Name(), raw, mimeType) == nil {
+		result.Data, result.MIMEType = raw, mimeType
+		return result, nil
+	}
+
+	var resp httpSpeechResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode speech service response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("speech service error: %s", resp.Error)
+	}
+
+	switch ref := firstNonEmpty(resp.AudioURL, resp.URL); {
+	case ref != "":
+		dl, err := http.NewRequestWithContext(ctx, http.MethodGet, p.resolve(ref), nil)
+		if err != nil {
+			return nil, err
+		}
+		data, contentType, err := fetch(p.client, p.Name(), dl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download audio: %w", err)
+		}
+		result.Data, result.MIMEType = data, audioType(data, contentType)
+	case firstNonEmpty(resp.AudioBase64, resp.Audio) != "":
+		data, err := decodeBase64Audio(firstNonEmpty(resp.AudioBase64, resp.Audio))
+		if err != nil {
+			return nil, fmt.Errorf("speech service: %w", err)
+		}
+		result.Data, result.MIMEType = data, audioType(data, "")
+	default:
+		return nil, errors.New("speech service returned no audio")
+	}
+	if err := checkAudio(p.Name(), result.Data, result.MIMEType); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Health checks the /health endpoint next to AI_TTS_SERVICE_URL
+func (p *HTTPProvider) Health(ctx context.Context) error {
+	u, err := url.Parse(p.endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid AI_TTS_SERVICE_URL: %w", err)
+	}
+	u.Path, u.RawQuery = "/health", ""
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	_, _, err = fetch(p.client, p.Name(), req)
+	return err
+}
+
+// resolve makes a path like /outputs/1.wav absolute against the service URL
+func (p *HTTPProvider) resolve(ref string) string {
+	base, err := url.Parse(p.endpoint)
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// decodeBase64Audio accepts plain base64 and data: URLs
+func decodeBase64Audio(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "data:") {
+		if _, payload, ok := strings.Cut(s, ","); ok {
+			s = payload
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 audio: %w", err)
+	}
+	return data, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
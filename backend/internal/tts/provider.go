@@ -0,0 +1,112 @@
+// Package tts synthesizes dialogue audio. Every backend returns the audio
+// bytes, never a provider URL, so callers can store the result themselves.
+package tts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider names
+const (
+	ProviderHTTP   = "tts_service"
+	ProviderEspeak = "espeak"
+)
+
+// Request is a provider-neutral text-to-speech request
+type Request struct {
+	Text  string
+	Voice string  // backend-specific voice ID; empty uses the backend default
+	Speed float64 // 1 is normal speed; 0 means 1
+	// Language hint for backends that pick a voice per language, e.g. "zh"
+	Language string
+}
+
+// Result is synthesized speech
+type Result struct {
+	Data     []byte
+	MIMEType string
+	Provider string
+}
+
+// Provider is implemented by every speech backend
+type Provider interface {
+	Name() string
+	Synthesize(ctx context.Context, req Request) (*Result, error)
+	Health(ctx context.Context) error
+}
+
+// StatusError is returned when a backend answers with a non-2xx status
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// maxAudioBytes bounds downloads from speech backends
+const maxAudioBytes = 32 << 20
+
+// fetch performs the request and returns the body of a 2xx response
+func fetch(client *http.Client, provider string, req *http.Request) ([]byte, string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAudioBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s response: %w", provider, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", &StatusError{Provider: provider, StatusCode: resp.StatusCode, Body: truncate(string(body), 512)}
+	}
+	if len(body) > maxAudioBytes {
+		return nil, "", fmt.Errorf("%s response exceeds %d bytes", provider, maxAudioBytes)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// audioType trusts the bytes over the Content-Type header where it can;
+// http.DetectContentType knows WAV, MP3 with an ID3 tag, Ogg and FLAC
+func audioType(data []byte, header string) string {
+	if sniffed := http.DetectContentType(data); strings.HasPrefix(sniffed, "audio/") || sniffed == "application/ogg" {
+		return sniffed
+	}
+	if len(data) > 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0 {
+		return "audio/mpeg" // MP3 frame without an ID3 tag
+	}
+	return header
+}
+
+func checkAudio(provider string, data []byte, mimeType string) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%s returned empty audio", provider)
+	}
+	if !strings.HasPrefix(mimeType, "audio/") && mimeType != "application/ogg" {
+		return fmt.Errorf("%s returned %s instead of audio", provider, mimeType)
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func newClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
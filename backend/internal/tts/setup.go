@@ -0,0 +1,32 @@
+package tts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/config"
+)
+
+// NewFromConfig builds the speech provider selected by AI_TTS_PROVIDER. When
+// unset, AI_TTS_SERVICE_URL selects the HTTP service, otherwise espeak-ng.
+func NewFromConfig(cfg config.AIConfig) (Provider, error) {
+	name := cfg.TTSProvider
+	if name == "" {
+		name = "espeak"
+		if cfg.TTSServiceURL != "" {
+			name = "http"
+		}
+	}
+
+	switch name {
+	case "http":
+		if cfg.TTSServiceURL == "" {
+			return nil, fmt.Errorf("AI_TTS_PROVIDER=http needs AI_TTS_SERVICE_URL")
+		}
+		return NewHTTPProvider(cfg.TTSServiceURL, time.Duration(cfg.TTSTimeout)*time.Second), nil
+	case "espeak":
+		return NewEspeakProvider(firstVoice(cfg.TTSVoices)), nil
+	default:
+		return nil, fmt.Errorf("unknown AI_TTS_PROVIDER %q (want http or espeak)", name)
+	}
+}
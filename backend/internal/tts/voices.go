@@ -0,0 +1,27 @@
+package tts
+
+import "strings"
+
+// espeakVoices is the voice pool when TTS_VOICES is unset and espeak speaks:
+// Mandarin with different male and female variants
+var espeakVoices = []string{"cmn", "cmn+m3", "cmn+f3", "cmn+m5", "cmn+f4", "cmn+m7", "cmn+f2"}
+
+// VoicePool parses TTS_VOICES, a comma separated list of voice IDs. The first
+// voice reads narration; characters are given the others in turn.
+func VoicePool(provider, list string) []string {
+	var pool []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			pool = append(pool, v)
+		}
+	}
+	if len(pool) == 0 && provider == ProviderEspeak {
+		pool = append(pool, espeakVoices...)
+	}
+	return pool
+}
+
+func firstVoice(list string) string {
+	first, _, _ := strings.Cut(list, ",")
+	return strings.TrimSpace(first)
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/services"
+)
+
+// New builds a pool with the handlers for every AI task type registered
+func New(svc *services.Services, concurrency int) *Pool {
+	p := NewPool(svc.Task, concurrency)
+
+	p.Handle(services.TaskTypeScript,
+		func(ctx context.Context, task *models.AITask) error {
+			if task.ProjectID == nil {
+				return errors.New("script task has no project")
+			}
+			return svc.Project.GenerateScenes(ctx, *task.ProjectID, task)
+		},
+		func(ctx context.Context, task *models.AITask, cause error) {
+			if task.ProjectID != nil {
+				logFailure(svc.Project.MarkProjectFailed(ctx, *task.ProjectID, cause))
+			}
+		},
+	)
+
+	// A rewrite that gives up leaves the scene as it was
+	p.Handle(services.TaskTypeRewrite,
+		func(ctx context.Context, task *models.AITask) error {
+			if task.SceneID == nil {
+				return errors.New("rewrite task has no scene")
+			}
+			return svc.Scenes.RewriteScene(ctx, *task.SceneID, task)
+		},
+		func(ctx context.Context, task *models.AITask, cause error) {
+			if task.SceneID != nil {
+				logFailure(svc.Scenes.MarkRewriteFailed(ctx, *task.SceneID, task.InputData))
+			}
+		},
+	)
+
+	p.Handle(services.TaskTypeImage,
+		func(ctx context.Context, task *models.AITask) error {
+			if task.SceneID == nil {
+				return errors.New("image task has no scene")
+			}
+			return svc.Project.GenerateSceneImage(ctx, *task.SceneID, task.InputData)
+		},
+		func(ctx context.Context, task *models.AITask, cause error) {
+			if task.SceneID != nil {
+				logFailure(svc.Project.MarkSceneFailed(ctx, *task.SceneID))
+			}
+		},
+	)
+
+	p.Handle(services.TaskTypeVideo,
+		func(ctx context.Context, task *models.AITask) error {
+			videoTaskID, err := inputUint(task, "video_task_id")
+			if err != nil {
+				return err
+			}
+			return svc.Video.RunVideoTask(ctx, videoTaskID)
+		},
+		func(ctx context.Context, task *models.AITask, cause error) {
+			if videoTaskID, err := inputUint(task, "video_task_id"); err == nil {
+				logFailure(svc.Video.MarkVideoTaskFailed(ctx, videoTaskID, cause))
+			}
+		},
+	)
+
+	// A scene whose speech gives up keeps its media and stays silent
+	p.Handle(services.TaskTypeSpeech,
+		func(ctx context.Context, task *models.AITask) error {
+			if task.SceneID == nil {
+				return errors.New("speech task has no scene")
+			}
+			return svc.Speech.SynthesizeScene(ctx, *task.SceneID)
+		},
+		nil,
+	)
+
+	// A season that gives up keeps the episodes written before the failing one
+	p.Handle(services.TaskTypeSeason,
+		func(ctx context.Context, task *models.AITask) error {
+			if task.SeriesID == nil {
+				return errors.New("season task has no series")
+			}
+			return svc.Series.GenerateSeason(ctx, task)
+		},
+		func(ctx context.Context, task *models.AITask, cause error) {
+			logFailure(svc.Series.MarkSeasonFailed(ctx, task, cause))
+		},
+	)
+
+	p.Handle(services.TaskTypeRender,
+		func(ctx context.Context, task *models.AITask) error {
+			if task.ProjectID == nil {
+				return errors.New("render task has no project")
+			}
+			return svc.Render.RenderProject(ctx, *task.ProjectID, task)
+		},
+		func(ctx context.Context, task *models.AITask, cause error) {
+			if task.ProjectID != nil {
+				svc.Render.MarkRenderFailed(ctx, *task.ProjectID, task, cause)
+			}
+		},
+	)
+
+	return p
+}
+
+// inputUint reads a numeric ID from the task input; JSON numbers decode as float64
+func inputUint(task *models.AITask, key string) (uint, error) {
+	switch v := task.InputData[key].(type) {
+	case float64:
+		return uint(v), nil
+	case int:
+		return uint(v), nil
+	case uint:
+		return v, nil
+	}
+	return 0, fmt.Errorf("%s task %d has no %s", task.TaskType, task.ID, key)
+}
+
+func logFailure(err error) {
+	if err != nil {
+		log.Printf("worker: failed to record terminal failure: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/models"
+	"github.com/richard9219/3kstory/internal/queue"
+	"github.com/richard9219/3kstory/internal/services"
+)
+
+// HandlerFunc runs one attempt of an AI task. Returning an error schedules a retry.
+type HandlerFunc func(ctx context.Context, task *models.AITask) error
+
+// FailureFunc is called once a task has used all of its attempts
+type FailureFunc func(ctx context.Context, task *models.AITask, cause error)
+
+type handler struct {
+	run      HandlerFunc
+	onFailed FailureFunc
+}
+
+// Pool leases jobs from the queue and runs them on a fixed number of goroutines
+type Pool struct {
+	tasks        *services.TaskService
+	queue        *queue.Queue
+	concurrency  int
+	pollInterval time.Duration
+	handlers     map[string]handler
+}
+
+func NewPool(tasks *services.TaskService, concurrency int) *Pool {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Pool{
+		tasks:        tasks,
+		queue:        tasks.Queue(),
+		concurrency:  concurrency,
+		pollInterval: time.Second,
+		handlers:     map[string]handler{},
+	}
+}
+
+// Handle registers the handler for a task type
+func (p *Pool) Handle(taskType string, run HandlerFunc, onFailed FailureFunc) {
+	p.handlers[taskType] = handler{run: run, onFailed: onFailed}
+}
+
+// Run processes jobs until ctx is cancelled
+func (p *Pool) Run(ctx context.Context) {
+	if n, err := p.tasks.Recover(ctx); err != nil {
+		log.Printf("worker: failed to recover tasks: %v", err)
+	} else if n > 0 {
+		log.Printf("worker: recovered %d unfinished tasks", n)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.reap(ctx)
+	}()
+	for i := 0; i < p.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.loop(ctx)
+		}()
+	}
+
+	log.Printf("worker: pool started with %d workers", p.concurrency)
+	wg.Wait()
+	log.Println("worker: pool stopped")
+}
+
+func (p *Pool) loop(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := p.queue.Lease(ctx)
+		if err != nil {
+			log.Printf("worker: lease failed: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.pollInterval):
+			}
+			continue
+		}
+
+		p.process(ctx, job)
+	}
+}
+
+// reap periodically returns expired leases to the ready set
+func (p *Pool) reap(ctx context.Context) {
+	ticker := time.NewTicker(p.queue.VisibilityTimeout() / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := p.queue.RequeueExpired(ctx); err != nil {
+				log.Printf("worker: requeue expired failed: %v", err)
+			} else if n > 0 {
+				log.Printf("worker: redelivering %d expired jobs", n)
+			}
+		}
+	}
+}
+
+func (p *Pool) process(ctx context.Context, job *queue.Job) {
+	task, err := p.tasks.GetTask(ctx, job.TaskID)
+	if err != nil {
+		log.Printf("worker: task %d for job %s not found: %v", job.TaskID, job.ID, err)
+		p.queue.Bury(ctx, job)
+		return
+	}
+	if task.Status == services.TaskCompleted || task.Status == services.TaskFailed {
+		p.queue.Ack(ctx, job)
+		return
+	}
+
+	h, ok := p.handlers[task.TaskType]
+	if !ok {
+		p.fail(ctx, job, task, h, fmt.Errorf("no handler for task type %q", task.TaskType), true)
+		return
+	}
+
+	// A job redelivered after a crash may already be past its last attempt
+	if job.Attempts > job.MaxAttempts {
+		p.fail(ctx, job, task, h, errors.New("exceeded max attempts"), true)
+		return
+	}
+
+	if err := p.tasks.MarkStarted(ctx, task, job.Attempts); err != nil {
+		log.Printf("worker: failed to mark task %d started: %v", task.ID, err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	go p.heartbeat(runCtx, job)
+	err = p.run(runCtx, h, task)
+	cancel()
+
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: hand the job back for the next worker instead of
+		// leaving it leased until the visibility timeout
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := p.queue.Release(releaseCtx, job); err != nil {
+			log.Printf("worker: failed to release job %s: %v", job.ID, err)
+		}
+		return
+	}
+	if err != nil {
+		p.fail(ctx, job, task, h, err, false)
+		return
+	}
+
+	if err := p.tasks.MarkCompleted(ctx, task); err != nil {
+		log.Printf("worker: failed to mark task %d completed: %v", task.ID, err)
+	}
+	if err := p.queue.Ack(ctx, job); err != nil {
+		log.Printf("worker: failed to ack job %s: %v", job.ID, err)
+	}
+}
+
+// run calls the handler, turning a panic into a retryable error
+func (p *Pool) run(ctx context.Context, h handler, task *models.AITask) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.run(ctx, task)
+}
+
+func (p *Pool) fail(ctx context.Context, job *queue.Job, task *models.AITask, h handler, cause error, bury bool) {
+	log.Printf("worker: %s task %d attempt %d failed: %v", task.TaskType, task.ID, job.Attempts, cause)
+
+	dead := bury
+	if bury {
+		if err := p.queue.Bury(ctx, job); err != nil {
+			log.Printf("worker: failed to bury job %s: %v", job.ID, err)
+		}
+	} else {
+		var err error
+		dead, err = p.queue.Retry(ctx, job, cause)
+		if err != nil {
+			log.Printf("worker: failed to retry job %s: %v", job.ID, err)
+		}
+	}
+
+	if err := p.tasks.MarkAttemptFailed(ctx, task, cause, dead); err != nil {
+		log.Printf("worker: failed to record failure of task %d: %v", task.ID, err)
+	}
+	if dead && h.onFailed != nil {
+		h.onFailed(ctx, task, cause)
+	}
+}
+
+func (p *Pool) heartbeat(ctx context.Context, job *queue.Job) {
+	ticker := time.NewTicker(p.queue.VisibilityTimeout() / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.queue.Extend(ctx, job); err != nil {
+				log.Printf("worker: failed to extend lease of job %s: %v", job.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/richard9219/3kstory/internal/services"
+)
+
+// VideoPoller drives provider video jobs to a terminal state so clients no
+// longer have to call the video-status endpoint themselves
+type VideoPoller struct {
+	videos    *services.VideoService
+	tick      time.Duration
+	batchSize int
+}
+
+func NewVideoPoller(videos *services.VideoService, tick time.Duration) *VideoPoller {
+	if tick <= 0 {
+		tick = 2 * time.Second
+	}
+	return &VideoPoller{videos: videos, tick: tick, batchSize: 50}
+}
+
+// Run polls due tasks every tick until ctx is cancelled
+func (p *VideoPoller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.tick)
+	defer ticker.Stop()
+
+	log.Printf("video poller: started (tick %s)", p.tick)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("video poller: stopped")
+			return
+		case <-ticker.C:
+			p.pollDue(ctx)
+		}
+	}
+}
+
+func (p *VideoPoller) pollDue(ctx context.Context) {
+	tasks, err := p.videos.ClaimDueVideoTasks(ctx, p.batchSize)
+	if err != nil {
+		log.Printf("video poller: failed to load due tasks: %v", err)
+		return
+	}
+
+	for _, task := range tasks {
+		previous := task.Status
+		if _, err := p.videos.PollVideoTask(ctx, task); err != nil {
+			log.Printf("video poller: task %d (%s %s) poll failed: %v", task.ID, task.Provider, task.ProviderVideoID, err)
+			continue
+		}
+		if task.Status != previous {
+			log.Printf("video poller: task %d %s -> %s", task.ID, previous, task.Status)
+		}
+	}
+}
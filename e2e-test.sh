@@ -248,7 +248,7 @@ echo "  视频生成服务:   $VIDEO_SERVICE"
 echo ""
 echo -e "${YELLOW}下一步操作：${NC}"
 echo "  1. 在浏览器中打开视频 URL 进行预览"
-echo "  2. 检查 .local/videos 目录查看生成的视频文件"
+echo "  2. 检查 .local/media/videos 目录查看生成的视频文件"
 echo "  3. 在前端应用中测试完整的用户界面"
 echo ""
 success "E2E 测试验证完成！🎉"
@@ -102,6 +102,6 @@ export const videoAPI = {
   }) =>
     apiClient.get(`/projects/${projectId}/videos`, { params }),
     
-  cancel: (projectId: number, videoId: string) =>
-    apiClient.delete(`/projects/${projectId}/video/${videoId}`),
+  cancel: (projectId: number, taskId: number) =>
+    apiClient.delete(`/projects/${projectId}/video/${taskId}`),
 };
@@ -94,8 +94,8 @@ BACKEND_PID=$!
 # 启动本地视频生成服务
 echo -e "${YELLOW}启动本地视频生成服务...${NC}"
 export LOCAL_VIDEO_PORT=8003
-export LOCAL_VIDEO_OUTPUT_DIR=".local/videos"
-go run ./cmd/local-video-service/entry.go &
+export MEDIA_ROOT=".local/media"
+go run ./cmd/local-video-service &
 VIDEO_SERVICE_PID=$!
 
 # 等待服务启动